@@ -2,19 +2,39 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/luikyv/go-open-finance/internal/account"
 	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/autopayment"
+	"github.com/luikyv/go-open-finance/internal/bankfixedincome"
+	"github.com/luikyv/go-open-finance/internal/brcode"
 	"github.com/luikyv/go-open-finance/internal/consent"
 	"github.com/luikyv/go-open-finance/internal/creditcard"
 	"github.com/luikyv/go-open-finance/internal/customer"
+	"github.com/luikyv/go-open-finance/internal/dict"
+	"github.com/luikyv/go-open-finance/internal/enrollment"
+	"github.com/luikyv/go-open-finance/internal/financing"
+	"github.com/luikyv/go-open-finance/internal/idempotency"
+	"github.com/luikyv/go-open-finance/internal/invoicefinancing"
+	"github.com/luikyv/go-open-finance/internal/loan"
+	"github.com/luikyv/go-open-finance/internal/mock"
+	"github.com/luikyv/go-open-finance/internal/oidc"
+	"github.com/luikyv/go-open-finance/internal/overdraft"
+	"github.com/luikyv/go-open-finance/internal/payment"
 	"github.com/luikyv/go-open-finance/internal/resource"
+	"github.com/luikyv/go-open-finance/internal/risk"
+	"github.com/luikyv/go-open-finance/internal/spi"
+	"github.com/luikyv/go-open-finance/internal/timex"
 	"github.com/luikyv/go-open-finance/internal/user"
+	"github.com/luikyv/go-open-finance/internal/webhook"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -27,24 +47,83 @@ var (
 	dbSchema       = getEnv("MOCKBANK_DB_SCHEMA", "mockbank")
 	dbStringCon    = getEnv("MOCKBANK_DB_CONNECTION", "mongodb://localhost:27017/mockbank")
 	pathPrefixOIDC = "/auth"
+	// holidays is a comma separated list of dates, e.g. "2025-01-25,2025-07-09",
+	// considered holidays in addition to the national ones.
+	holidays = getEnv("MOCKBANK_HOLIDAYS", "")
+	timezone = getEnv("MOCKBANK_TIMEZONE", timex.DefaultLocation)
+	// pspHost is where the PSP stub which serves the payloads of dynamic QR
+	// codes is reachable.
+	pspHost = getEnv("MOCKBANK_PSP_HOST", host)
+	// spiReceiverURL is where the pacs.008 messages of the payments are sent.
+	// It defaults to the fake receiving institution served by MockBank.
+	spiReceiverURL = getEnv("MOCKBANK_SPI_RECEIVER_URL", "http://localhost:"+port+spi.PathReceiver)
+	// spiSettlementDelay is how long payments stay in ACPD before being
+	// settled or rejected, e.g. "5s".
+	spiSettlementDelay = getEnv("MOCKBANK_SPI_SETTLEMENT_DELAY", "0s")
+	// spiRejections is a comma separated list of amounts and the ISO 20022
+	// codes the fake receiving institution rejects them with, e.g.
+	// "10444.00:DS04,10466.00:AB03".
+	spiRejections = getEnv("MOCKBANK_SPI_REJECTIONS", "")
+	spiTimeout    = 5 * time.Second
 )
 
 func main() {
 	// Logging.
 	slog.SetDefault(logger())
 
+	// Calendar.
+	if err := timex.SetLocation(timezone); err != nil {
+		log.Fatal(err)
+	}
+	if err := loadHolidays(); err != nil {
+		log.Fatal(err)
+	}
+
+	// SPI.
+	settlementDelay, err := time.ParseDuration(spiSettlementDelay)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rejections, err := loadSPIRejections()
+	if err != nil {
+		log.Fatal(err)
+	}
+	spiClient := spi.NewClient(&http.Client{Timeout: spiTimeout}, spiReceiverURL, settlementDelay)
+
 	// Database.
 	db, err := dbConnection()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// Keys.
+	serverJWKS := privateJWKS(filepath.Join(keysDir(), "server.jwks"))
+	jwtSigner, err := api.NewJWTSigner(mock.MockBankOrgID, serverJWKS)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Storage.
 	userStorage := user.NewStorage()
 	consentStorage := consent.NewStorage(db)
 	customerStorage := customer.NewStorage()
 	accountStorage := account.NewStorage()
 	creditCardStorage := creditcard.NewStorage()
+	loanStorage := loan.NewStorage()
+	financingStorage := financing.NewStorage()
+	overdraftStorage := overdraft.NewStorage()
+	invoiceFinancingStorage := invoicefinancing.NewStorage()
+	bankFixedIncomeStorage := bankfixedincome.NewStorage()
+	dictStorage := dict.NewStorage()
+	riskStorage := risk.NewStorage()
+	paymentStorage := payment.NewStorage(db)
+	autoPaymentStorage := autopayment.NewStorage(db)
+	enrollmentStorage := enrollment.NewStorage(db)
+	webhookStorage := webhook.NewStorage(db)
+	idempotencyStorage := idempotency.NewStorage(db)
+	if err := idempotencyStorage.CreateIndexes(context.Background()); err != nil {
+		log.Fatal(err)
+	}
 
 	// Services.
 	userService := user.NewService(userStorage)
@@ -53,19 +132,43 @@ func main() {
 	customerService := customer.NewService(customerStorage)
 	accountService := account.NewService(accountStorage, consentService)
 	creditCardService := creditcard.NewService(creditCardStorage, consentService)
+	loanService := loan.NewService(loanStorage, consentService)
+	financingService := financing.NewService(financingStorage, consentService)
+	overdraftService := overdraft.NewService(overdraftStorage, consentService, accountService)
+	invoiceFinancingService := invoicefinancing.NewService(invoiceFinancingStorage, consentService)
+	bankFixedIncomeService := bankfixedincome.NewService(bankFixedIncomeStorage, consentService)
+	dictService := dict.NewService(dictStorage)
+	riskService := risk.NewService(riskStorage, accountService)
+	webhookService := webhook.NewService(webhookStorage, oidc.NewClientManager(db), jwtSigner, mtlsHTTPClient())
+	paymentService := payment.NewService(paymentStorage, brcode.NewClient(httpClient()), dictService, webhookService,
+		spiClient, accountService, riskService)
+	autoPaymentService := autopayment.NewService(autoPaymentStorage, spiClient, accountService, riskService)
+	enrollmentService := enrollment.NewService(enrollmentStorage, paymentService)
 
 	// OpenID Provider.
-	op, err := openidProvider(db, userService, consentService)
+	op, err := openidProvider(db, userService, consentService, paymentService, autoPaymentService, enrollmentService, serverJWKS)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	// API Routers.
-	consentAPIRouterV3 := consent.NewAPIRouterV3(mtlsHost, consentService, op)
+	consentAPIRouterV3 := consent.NewAPIRouterV3(mtlsHost, consentService, op, idempotencyStorage)
 	resourceAPIRouterV3 := resource.NewAPIRouterV3(mtlsHost, resourceService, consentService, op)
 	customerAPIRouterV2 := customer.NewAPIRouterV2(mtlsHost, customerService, consentService, op)
 	accountAPIRouterV2 := account.NewAPIRouterV2(mtlsHost, accountService, consentService, op)
 	creditCardAPIRouterV2 := creditcard.NewAPIRouterV2(mtlsHost, creditCardService, consentService, op)
+	loanAPIRouterV2 := loan.NewAPIRouterV2(mtlsHost, loanService, consentService, op)
+	financingAPIRouterV2 := financing.NewAPIRouterV2(mtlsHost, financingService, consentService, op)
+	overdraftAPIRouterV2 := overdraft.NewAPIRouterV2(mtlsHost, overdraftService, consentService, op)
+	invoiceFinancingAPIRouterV2 := invoicefinancing.NewAPIRouterV2(mtlsHost, invoiceFinancingService, consentService, op)
+	bankFixedIncomeAPIRouterV1 := bankfixedincome.NewAPIRouterV1(mtlsHost, bankFixedIncomeService, consentService, op)
+	paymentAPIRouterV4 := payment.NewAPIRouterV4(mtlsHost, paymentService, op, jwtSigner, httpClient(), idempotencyStorage)
+	autoPaymentAPIRouterV1 := autopayment.NewAPIRouterV1(mtlsHost, autoPaymentService, op, jwtSigner, httpClient(), idempotencyStorage)
+	enrollmentAPIRouterV2 := enrollment.NewAPIRouterV2(mtlsHost, enrollmentService, op, jwtSigner, httpClient(), idempotencyStorage)
+	pspRouter := brcode.NewPSPRouter(pspHost, serverJWKS)
+	dictAPIRouter := dict.NewAPIRouter(dictService)
+	webhookAPIRouter := webhook.NewAPIRouter(webhookService, op)
+	spiReceiverRouter := spi.NewReceiverRouter(rejections)
 
 	// Server.
 	mux := http.NewServeMux()
@@ -76,9 +179,23 @@ func main() {
 	customerAPIRouterV2.Register(mux)
 	accountAPIRouterV2.Register(mux)
 	creditCardAPIRouterV2.Register(mux)
+	loanAPIRouterV2.Register(mux)
+	financingAPIRouterV2.Register(mux)
+	overdraftAPIRouterV2.Register(mux)
+	invoiceFinancingAPIRouterV2.Register(mux)
+	bankFixedIncomeAPIRouterV1.Register(mux)
+	paymentAPIRouterV4.Register(mux)
+	autoPaymentAPIRouterV1.Register(mux)
+	enrollmentAPIRouterV2.Register(mux)
+	pspRouter.Register(mux)
+	dictAPIRouter.Register(mux)
+	webhookAPIRouter.Register(mux)
+	spiReceiverRouter.Register(mux)
 
 	// Run.
-	_ = loadMocks(userService, customerService, accountService, creditCardService)
+	go payment.NewScheduler(paymentService).Run(context.Background())
+	go webhook.NewDispatcher(webhookService).Run(context.Background())
+	_ = loadMocks(userService, customerService, accountService, creditCardService, loanService, financingService, overdraftService, invoiceFinancingService, bankFixedIncomeService, dictService, riskService)
 	if err := http.ListenAndServe(":"+port, mux); err != nil {
 		log.Fatal(err)
 	}
@@ -106,6 +223,39 @@ func dbConnection() (*mongo.Database, error) {
 	return conn.Database(dbSchema), nil
 }
 
+func loadHolidays() error {
+	if holidays == "" {
+		return nil
+	}
+
+	var dates []timex.Date
+	for _, s := range strings.Split(holidays, ",") {
+		d, err := timex.ParseDate(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("invalid holiday %q: %w", s, err)
+		}
+		dates = append(dates, d)
+	}
+	timex.AddHolidays(dates...)
+	return nil
+}
+
+func loadSPIRejections() (map[string]spi.ReasonCode, error) {
+	if spiRejections == "" {
+		return spi.DefaultRejections, nil
+	}
+
+	rejections := map[string]spi.ReasonCode{}
+	for _, s := range strings.Split(spiRejections, ",") {
+		amount, code, ok := strings.Cut(strings.TrimSpace(s), ":")
+		if !ok || amount == "" || code == "" {
+			return nil, fmt.Errorf("invalid spi rejection %q", s)
+		}
+		rejections[amount] = spi.ReasonCode(code)
+	}
+	return rejections, nil
+}
+
 // getEnv retrieves an environment variable or returns a fallback value if not found
 func getEnv(key, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -5,12 +5,22 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/rand"
+	"slices"
 	"time"
 
 	"github.com/luikyv/go-open-finance/internal/account"
+	"github.com/luikyv/go-open-finance/internal/bankfixedincome"
+	"github.com/luikyv/go-open-finance/internal/credit"
 	"github.com/luikyv/go-open-finance/internal/creditcard"
 	"github.com/luikyv/go-open-finance/internal/customer"
+	"github.com/luikyv/go-open-finance/internal/dict"
+	"github.com/luikyv/go-open-finance/internal/financing"
+	"github.com/luikyv/go-open-finance/internal/invoicefinancing"
+	"github.com/luikyv/go-open-finance/internal/loan"
 	"github.com/luikyv/go-open-finance/internal/mock"
+	"github.com/luikyv/go-open-finance/internal/money"
+	"github.com/luikyv/go-open-finance/internal/overdraft"
+	"github.com/luikyv/go-open-finance/internal/risk"
 	"github.com/luikyv/go-open-finance/internal/timex"
 	"github.com/luikyv/go-open-finance/internal/user"
 )
@@ -24,14 +34,29 @@ func loadMocks(
 	customerService customer.Service,
 	accountService account.Service,
 	creditCardService creditcard.Service,
+	loanService loan.Service,
+	financingService financing.Service,
+	overdraftService overdraft.Service,
+	invoiceFinancingService invoicefinancing.Service,
+	bankFixedIncomeService bankfixedincome.Service,
+	dictService dict.Service,
+	riskService risk.Service,
 ) error {
 	ctx := context.Background()
 
-	if err := loadUserBob(ctx, userService, customerService, accountService, creditCardService); err != nil {
+	if err := loadUserBob(ctx, userService, customerService, accountService, creditCardService, loanService, financingService, overdraftService, invoiceFinancingService, bankFixedIncomeService, dictService); err != nil {
 		return err
 	}
 
-	if err := loadUserAlice(ctx, userService, customerService, accountService); err != nil {
+	if err := loadUserAlice(ctx, userService, customerService, accountService, dictService); err != nil {
+		return err
+	}
+
+	if err := loadUserCarol(ctx, userService, accountService, dictService, riskService); err != nil {
+		return err
+	}
+
+	if err := loadExternalPixKeys(ctx, dictService); err != nil {
 		return err
 	}
 
@@ -44,6 +69,12 @@ func loadUserBob(
 	customerService customer.Service,
 	accountService account.Service,
 	creditCardService creditcard.Service,
+	loanService loan.Service,
+	financingService financing.Service,
+	overdraftService overdraft.Service,
+	invoiceFinancingService invoicefinancing.Service,
+	bankFixedIncomeService bankfixedincome.Service,
+	dictService dict.Service,
 ) error {
 
 	var u = user.User{
@@ -51,6 +82,8 @@ func loadUserBob(
 		Email:    "bob@mail.com",
 		CPF:      "78628584099",
 		Name:     "Mr. Bob",
+		// Bob owns a company.
+		CompanyCNPJs: []string{"47312985000154"},
 	}
 
 	customerService.AddPersonalIdentification(ctx, u.CPF, customer.PersonalIdentification{
@@ -123,6 +156,9 @@ func loadUserBob(
 			BlockedAmount:               "0.00",
 			AutomaticallyInvestedAmount: "0.00",
 		},
+		OverdraftLimit: account.OverdraftLimit{
+			Unarranged: "650.00",
+		},
 	}
 
 	for i := 0; i < 30; i++ {
@@ -151,20 +187,147 @@ func loadUserBob(
 
 	accountService.Set(u.CPF, acc)
 
+	// ========================= Pix Keys =========================
+	pixAccount := dict.Account{
+		Participant: mock.MockBankISPB,
+		Branch:      account.DefaultBranch,
+		Number:      acc.Number,
+		Type:        dict.AccountTypeCACC,
+	}
+	pixOwner := dict.Owner{
+		Type:        dict.OwnerTypeNaturalPerson,
+		TaxIDNumber: u.CPF,
+		Name:        u.Name,
+	}
+	for _, key := range []dict.Key{
+		{Value: u.CPF, Type: dict.KeyTypeCPF},
+		{Value: u.Email, Type: dict.KeyTypeEmail},
+		{Value: "+5511999999999", Type: dict.KeyTypePhone},
+		{Value: "3f1c9a52-7b4e-4d08-9e6a-51c2d8f0b7a4", Type: dict.KeyTypeEVP},
+	} {
+		key.Account = pixAccount
+		key.Owner = pixOwner
+		key.CreationDateTime = timex.NewDateTime(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+		if err := dictService.Add(ctx, key); err != nil {
+			return err
+		}
+	}
+	// The CNPJ keys of Bob's companies point to his account as well.
+	for _, cnpj := range u.CompanyCNPJs {
+		if err := dictService.Add(ctx, dict.Key{
+			Value:   cnpj,
+			Type:    dict.KeyTypeCNPJ,
+			Account: pixAccount,
+			Owner: dict.Owner{
+				Type:        dict.OwnerTypeLegalPerson,
+				TaxIDNumber: cnpj,
+				Name:        "Bob Company LTDA",
+			},
+			CreationDateTime: timex.NewDateTime(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)),
+		}); err != nil {
+			return err
+		}
+	}
+
 	// ========================= Credit Cards =========================
 	creditCardID := uuid()
 	u.CreditAccountID = creditCardID
+	mainCard := creditcard.Card{
+		Number: "4539148803436467",
+	}
+	additionalCard := creditcard.Card{
+		Number: "4539578763621486",
+	}
+	creditCardTrs, err := creditCardTransactions(mainCard, additionalCard)
+	if err != nil {
+		return err
+	}
 	card := creditcard.Account{
-		ID:      creditCardID,
-		Name:    "Black card",
-		Type:    creditcard.TypeBlack,
-		Network: creditcard.NetworkVisa,
-		MainCard: creditcard.Card{
-			Number: "4539148803436467",
+		ID:              creditCardID,
+		Name:            "Black card",
+		Type:            creditcard.TypeBlack,
+		Network:         creditcard.NetworkVisa,
+		MainCard:        mainCard,
+		AdditionalCards: []creditcard.Card{additionalCard},
+		Limits: []creditcard.Limit{
+			{
+				Type:              creditcard.LimitTypeTotal,
+				ConsolidationType: creditcard.ConsolidationTypeConsolidated,
+				Card:              mainCard,
+				LimitAmount:       "10000.00",
+				UsedAmount:        "1500.00",
+			},
+			{
+				Type:              creditcard.LimitTypeBusiness,
+				ConsolidationType: creditcard.ConsolidationTypeConsolidated,
+				Card:              mainCard,
+				LineName:          creditcard.LineNameCashCredit,
+				LimitAmount:       "10000.00",
+				UsedAmount:        "1000.00",
+			},
+			{
+				Type:              creditcard.LimitTypeBusiness,
+				ConsolidationType: creditcard.ConsolidationTypeConsolidated,
+				Card:              mainCard,
+				LineName:          creditcard.LineNameInstalmentCredit,
+				LimitAmount:       "8000.00",
+				UsedAmount:        "500.00",
+			},
+			{
+				Type:              creditcard.LimitTypeBusiness,
+				ConsolidationType: creditcard.ConsolidationTypeConsolidated,
+				Card:              mainCard,
+				LineName:          creditcard.LineNameDomesticWithdrawal,
+				LimitAmount:       "2000.00",
+				UsedAmount:        "0.00",
+			},
+			{
+				Type:                   creditcard.LimitTypeBusiness,
+				ConsolidationType:      creditcard.ConsolidationTypeConsolidated,
+				Card:                   mainCard,
+				LineName:               creditcard.LineNameOthers,
+				LineNameAdditionalInfo: "Limite emergencial",
+				IsFlexible:             true,
+				UsedAmount:             "0.00",
+			},
 		},
+		ClosingDay:   5,
+		Transactions: creditCardTrs,
 	}
 	creditCardService.Add(u.CPF, card)
 
+	// ========================= Loans =========================
+	for _, contract := range loanContracts() {
+		u.LoanIDs = append(u.LoanIDs, contract.ID)
+		loanService.Add(u.CPF, contract)
+	}
+
+	// ========================= Financings =========================
+	for _, contract := range financingContracts() {
+		u.FinancingIDs = append(u.FinancingIDs, contract.ID)
+		financingService.Add(u.CPF, contract)
+	}
+
+	// ========================= Unarranged Accounts Overdraft =========================
+	overdraftContract := unarrangedOverdraftContract(acc.Number)
+	u.UnarrangedOverdraftIDs = append(u.UnarrangedOverdraftIDs, overdraftContract.ID)
+	overdraftService.Add(u.CPF, overdraftContract)
+
+	// ========================= Invoice Financings =========================
+	u.InvoiceFinancingIDs = map[string][]string{}
+	for _, cnpj := range u.CompanyCNPJs {
+		for _, contract := range invoiceFinancingContracts() {
+			u.InvoiceFinancingIDs[cnpj] = append(u.InvoiceFinancingIDs[cnpj], contract.ID)
+			invoiceFinancingService.Add(cnpj, contract)
+		}
+	}
+
+	// ========================= Bank Fixed Incomes =========================
+	for _, inv := range bankFixedIncomeInvestments() {
+		u.BankFixedIncomeIDs = append(u.BankFixedIncomeIDs, inv.ID)
+		bankFixedIncomeService.Add(u.CPF, inv)
+	}
+
 	userService.Create(ctx, u)
 	return nil
 }
@@ -174,6 +337,7 @@ func loadUserAlice(
 	userService user.Service,
 	_ customer.Service,
 	accountService account.Service,
+	dictService dict.Service,
 ) error {
 	var u = user.User{
 		UserName:  "alice@mail.com",
@@ -207,9 +371,711 @@ func loadUserAlice(
 		},
 	})
 
+	for _, key := range []dict.Key{
+		{Value: u.CPF, Type: dict.KeyTypeCPF},
+		{Value: u.Email, Type: dict.KeyTypeEmail},
+	} {
+		key.Account = dict.Account{
+			Participant: mock.MockBankISPB,
+			Branch:      account.DefaultBranch,
+			Number:      "75690055",
+			Type:        dict.AccountTypeCACC,
+		}
+		key.Owner = dict.Owner{
+			Type:        dict.OwnerTypeNaturalPerson,
+			TaxIDNumber: u.CPF,
+			Name:        u.Name,
+		}
+		key.CreationDateTime = timex.NewDateTime(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+		if err := dictService.Add(ctx, key); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// loadUserCarol loads a user with restrictive limits and fraud rules, so each
+// of the rejections by the risk rules can be reproduced.
+func loadUserCarol(
+	ctx context.Context,
+	userService user.Service,
+	accountService account.Service,
+	dictService dict.Service,
+	riskService risk.Service,
+) error {
+	var u = user.User{
+		UserName:  "carol@mail.com",
+		Email:     "carol@mail.com",
+		CPF:       "53810274607",
+		Name:      "Ms. Carol",
+		AccountID: uuid(),
+	}
+	userService.Create(ctx, u)
+
+	accountService.Set(u.CPF, account.Account{
+		ID:      u.AccountID,
+		Number:  "41820937",
+		Type:    account.TypeCheckingAccount,
+		SubType: account.SubTypeIndividual,
+		Balance: account.Balance{
+			AvailableAmount:             "2000.00",
+			BlockedAmount:               "0.00",
+			AutomaticallyInvestedAmount: "0.00",
+		},
+	})
+
+	riskService.SetRules(u.CPF, risk.Rules{
+		TransactionLimit: "3000.00",
+		DailyLimit:       "2500.00",
+		NightlyLimit:     risk.DefaultNightlyLimit,
+		CheckBalance:     true,
+		BlockedCreditors: []string{"12345678909"},
+	})
+
+	return dictService.Add(ctx, dict.Key{
+		Value: u.CPF,
+		Type:  dict.KeyTypeCPF,
+		Account: dict.Account{
+			Participant: mock.MockBankISPB,
+			Branch:      account.DefaultBranch,
+			Number:      "41820937",
+			Type:        dict.AccountTypeCACC,
+		},
+		Owner: dict.Owner{
+			Type:        dict.OwnerTypeNaturalPerson,
+			TaxIDNumber: u.CPF,
+			Name:        u.Name,
+		},
+		CreationDateTime: timex.NewDateTime(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)),
+	})
+}
+
+// loadExternalPixKeys registers keys held by other institutions, so they can
+// be used as creditors of payments.
+func loadExternalPixKeys(ctx context.Context, dictService dict.Service) error {
+	// Key of the Pix homologation environment used by default as creditor by
+	// the conformance suite.
+	return dictService.Add(ctx, dict.Key{
+		Value: "cliente-a00001@pix.bcb.gov.br",
+		Type:  dict.KeyTypeEmail,
+		Account: dict.Account{
+			Participant: "99999004",
+			Branch:      "0001",
+			Number:      "12345678",
+			Type:        dict.AccountTypeCACC,
+		},
+		Owner: dict.Owner{
+			Type:        dict.OwnerTypeNaturalPerson,
+			TaxIDNumber: "99991111140",
+			Name:        "Joao Silva",
+		},
+		CreationDateTime: timex.NewDateTime(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)),
+	})
+}
+
+// creditCardTransactions generates the transactions of the last months for
+// the cards. Every month has the same purchases, which are posted to the bill
+// closing on the following month and paid around its due date. Three months
+// ago, an international purchase was made and a purchase was split into
+// instalments posted to the following bills. The bill closed two months ago
+// is paid in instalments.
+func creditCardTransactions(mainCard, additionalCard creditcard.Card) ([]creditcard.Transaction, error) {
+	const months = 5
+	now := timex.Now().In(timex.Location())
+	at := func(monthsAgo, day int) timex.DateTime {
+		return timex.NewDateTime(time.Date(now.Year(), now.Month()-time.Month(monthsAgo), day, 12, 0, 0, 0, timex.Location()))
+	}
+
+	var trs []creditcard.Transaction
+	for i := range months {
+		trs = append(trs,
+			creditcard.Transaction{
+				Card:            mainCard,
+				Name:            "Anuidade",
+				CreditDebitType: creditcard.CreditDebitTypeDebit,
+				Type:            creditcard.TransactionTypeFee,
+				FeeType:         creditcard.FeeTypeAnnual,
+				Amount:          "40.00",
+				DateTime:        at(i, 25),
+			},
+			creditcard.Transaction{
+				Card:            additionalCard,
+				Name:            "Restaurante Sabor",
+				CreditDebitType: creditcard.CreditDebitTypeDebit,
+				Type:            creditcard.TransactionTypeOthers,
+				PaymentType:     creditcard.TransactionPaymentTypeCash,
+				Amount:          "120.50",
+				DateTime:        at(i, 18),
+				PayeeMCC:        5812,
+			},
+			creditcard.Transaction{
+				Card:            mainCard,
+				Name:            "Supermercado Dia a Dia",
+				CreditDebitType: creditcard.CreditDebitTypeDebit,
+				Type:            creditcard.TransactionTypeOthers,
+				PaymentType:     creditcard.TransactionPaymentTypeCash,
+				Amount:          "350.00",
+				DateTime:        at(i, 8),
+				PayeeMCC:        5411,
+			},
+		)
+	}
+
+	internationalTr, err := creditcard.NewInternationalTransaction(creditcard.Transaction{
+		Card:            mainCard,
+		Name:            "Online Bookstore",
+		CreditDebitType: creditcard.CreditDebitTypeDebit,
+		Type:            creditcard.TransactionTypeOthers,
+		PaymentType:     creditcard.TransactionPaymentTypeCash,
+		DateTime:        at(3, 10),
+		PayeeMCC:        5942,
+	}, "USD", "50.00", "5.4500")
+	if err != nil {
+		return nil, err
+	}
+	trs = append(trs, internationalTr, creditcard.Transaction{
+		Card:              mainCard,
+		Name:              "IOF",
+		CreditDebitType:   creditcard.CreditDebitTypeDebit,
+		Type:              creditcard.TransactionTypeOthers,
+		FinanceChargeType: creditcard.FinanceChargeTypeIOF,
+		AdditionalInfo:    "IOF Online Bookstore",
+		Amount:            "9.21",
+		DateTime:          at(3, 10),
+	})
+
+	instalments, err := creditcard.Transaction{
+		Card:            mainCard,
+		Name:            "Loja de Eletronicos",
+		CreditDebitType: creditcard.CreditDebitTypeDebit,
+		Type:            creditcard.TransactionTypeOthers,
+		Amount:          "1200.00",
+		DateTime:        at(3, 12),
+		PayeeMCC:        5732,
+	}.Instalments(6)
+	if err != nil {
+		return nil, err
+	}
+	trs = append(trs, instalments...)
+
+	for i := range trs {
+		if trs[i].PostDate.IsZero() {
+			trs[i].PostDate = trs[i].DateTime.ToDate()
+		}
+	}
+
+	// The purchases posted on the previous month are paid on the current one.
+	for i := range months - 1 {
+		from, to := at(i+1, 1).ToDate(), at(i, 1).ToDate()
+		var total int64
+		for _, tr := range trs {
+			if tr.PostDate.Before(from.Time) || !tr.PostDate.Before(to.Time) {
+				continue
+			}
+			amount, _ := money.Parse(tr.Amount)
+			total += amount
+		}
+
+		payment := creditcard.Transaction{
+			Card:            mainCard,
+			Name:            "Pagamento de fatura",
+			CreditDebitType: creditcard.CreditDebitTypeCredit,
+			Type:            creditcard.TransactionTypePayment,
+			PaymentType:     creditcard.TransactionPaymentTypeCash,
+			PaymentMode:     creditcard.PaymentModeDebitCurrentAccount,
+			Amount:          money.Format(total),
+			DateTime:        at(i, 15),
+		}
+		payment.PostDate = payment.DateTime.ToDate()
+		if i == 2 {
+			payment.PaymentType = creditcard.TransactionPaymentTypeInstalment
+		}
+		trs = append(trs, payment)
+	}
+
+	// Transactions are kept from the most recent to the oldest.
+	slices.SortStableFunc(trs, func(tr1, tr2 creditcard.Transaction) int {
+		return tr2.DateTime.Compare(tr1.DateTime.Time)
+	})
+	var pastTrs []creditcard.Transaction
+	for _, tr := range trs {
+		if tr.DateTime.After(now) {
+			continue
+		}
+		tr.ID = uuid()
+		pastTrs = append(pastTrs, tr)
+	}
+
+	return pastTrs, nil
+}
+
+// loanContracts generates a personal loan amortized with the PRICE table and
+// a home equity loan amortized with SAC, both with their instalments paid up
+// to date.
+func loanContracts() []loan.Contract {
+	today := timex.DateNow()
+	date := func(months, days int) timex.Date {
+		return timex.NewDate(today.AddDate(0, months, days))
+	}
+
+	personalLoanDate := date(-6, -5)
+	personalLoan := loan.Contract{
+		Contract: credit.Contract{
+			ID:             uuid(),
+			Number:         "1000234567",
+			IPOCCode:       "58540569CPSC1000234567",
+			ProductName:    "Crédito Pessoal",
+			ProductType:    loan.ProductTypeLoan,
+			ProductSubType: loan.ProductSubTypePersonalLoan,
+			Terms: credit.Terms{
+				Amount:                 "10000.00",
+				Date:                   personalLoanDate,
+				InstalmentNumber:       24,
+				InstalmentPeriodicity:  credit.PeriodicityMonthly,
+				FirstInstalmentDueDate: timex.NewDate(personalLoanDate.AddDate(0, 1, 0)),
+				AmortizationSchedule:   credit.AmortizationSchedulePRICE,
+				InterestRate:           preFixedRate(credit.TaxPeriodicityYearly, "0.2668"),
+				Fees: []credit.Fee{
+					{
+						Name:       "Tarifa de cadastro",
+						Code:       "CADASTRO",
+						ChargeType: credit.FeeChargeTypeSingle,
+						Charge:     credit.FeeChargeFixed,
+						Amount:     "50.00",
+					},
+				},
+			},
+			DisbursementDates: []timex.Date{personalLoanDate},
+			FinanceCharges: []credit.FinanceCharge{
+				{Type: credit.FinanceChargeTypeLateFine, Rate: "0.0200"},
+				{Type: credit.FinanceChargeTypeDefault, Rate: "0.1268"},
+				{Type: credit.FinanceChargeTypeIOF, Rate: "0.0038"},
+			},
+		},
+	}
+	personalLoan.Payments = instalmentPayments(personalLoan.Terms, today)
+
+	homeEquityDate := date(-3, -5)
+	homeEquity := loan.Contract{
+		Contract: credit.Contract{
+			ID:             uuid(),
+			Number:         "1000345678",
+			IPOCCode:       "58540569HOME1000345678",
+			ProductName:    "Crédito com Garantia de Imóvel",
+			ProductType:    loan.ProductTypeLoan,
+			ProductSubType: loan.ProductSubTypeHomeEquity,
+			Terms: credit.Terms{
+				Amount:                 "150000.00",
+				Date:                   homeEquityDate,
+				InstalmentNumber:       120,
+				InstalmentPeriodicity:  credit.PeriodicityMonthly,
+				FirstInstalmentDueDate: timex.NewDate(homeEquityDate.AddDate(0, 1, 0)),
+				AmortizationSchedule:   credit.AmortizationScheduleSAC,
+				InterestRate:           preFixedRate(credit.TaxPeriodicityYearly, "0.1403"),
+				Fees: []credit.Fee{
+					{
+						Name:       "Tarifa de avaliação de bem recebido em garantia",
+						Code:       "AVALIACAO_GARANTIA",
+						ChargeType: credit.FeeChargeTypeSingle,
+						Charge:     credit.FeeChargeFixed,
+						Amount:     "3100.00",
+					},
+				},
+			},
+			DisbursementDates: []timex.Date{homeEquityDate},
+			FinanceCharges: []credit.FinanceCharge{
+				{Type: credit.FinanceChargeTypeLateFine, Rate: "0.0200"},
+				{Type: credit.FinanceChargeTypeDefault, Rate: "0.1268"},
+			},
+			Warranties: []credit.Warranty{
+				{
+					Type:    credit.WarrantyTypeFiduciaryAlienation,
+					SubType: credit.WarrantySubTypeResidentialProperty,
+					Amount:  "400000.00",
+				},
+			},
+		},
+	}
+	homeEquity.Payments = instalmentPayments(homeEquity.Terms, today)
+
+	return []loan.Contract{personalLoan, homeEquity}
+}
+
+// financingContracts generates a vehicle financing whose third instalment was
+// paid late, with the late charges paid apart from the instalment, and a
+// solar panels financing amortized with SAM whose last instalment is past due.
+func financingContracts() []financing.Contract {
+	today := timex.DateNow()
+	date := func(months, days int) timex.Date {
+		return timex.NewDate(today.AddDate(0, months, days))
+	}
+
+	vehicleDate := date(-5, -10)
+	vehicle := financing.Contract{
+		Contract: credit.Contract{
+			ID:             uuid(),
+			Number:         "2000456789",
+			IPOCCode:       "58540569VEIC2000456789",
+			ProductName:    "Financiamento de Veículo",
+			ProductType:    financing.ProductTypeFinancing,
+			ProductSubType: financing.ProductSubTypeVehicles,
+			Terms: credit.Terms{
+				Amount:                 "60000.00",
+				Date:                   vehicleDate,
+				InstalmentNumber:       48,
+				InstalmentPeriodicity:  credit.PeriodicityMonthly,
+				FirstInstalmentDueDate: timex.NewDate(vehicleDate.AddDate(0, 1, 0)),
+				AmortizationSchedule:   credit.AmortizationSchedulePRICE,
+				InterestRate:           preFixedRate(credit.TaxPeriodicityYearly, "0.1956"),
+				Fees: []credit.Fee{
+					{
+						Name:       "Tarifa de avaliação de bem recebido em garantia",
+						Code:       "AVALIACAO_GARANTIA",
+						ChargeType: credit.FeeChargeTypeSingle,
+						Charge:     credit.FeeChargeFixed,
+						Amount:     "450.00",
+					},
+				},
+			},
+			DisbursementDates: []timex.Date{vehicleDate},
+			FinanceCharges: []credit.FinanceCharge{
+				{Type: credit.FinanceChargeTypeLateFine, Rate: "0.0200"},
+				{Type: credit.FinanceChargeTypeDefault, Rate: "0.1268"},
+				{Type: credit.FinanceChargeTypeIOF, Rate: "0.0038"},
+			},
+			Warranties: []credit.Warranty{
+				{
+					Type:    credit.WarrantyTypeFiduciaryAlienation,
+					SubType: credit.WarrantySubTypeVehicles,
+					Amount:  "75000.00",
+				},
+			},
+		},
+	}
+	for _, p := range instalmentPayments(vehicle.Terms, today) {
+		if p.InstalmentID != "3" {
+			vehicle.Payments = append(vehicle.Payments, p)
+			continue
+		}
+
+		// The third instalment was paid six days late.
+		p.Date = timex.NewDate(p.Date.AddDate(0, 0, 6))
+		vehicle.Payments = append(vehicle.Payments, p)
+
+		charges := credit.LateCharges(vehicle.Terms.Schedule()[2], p.Date, vehicle.FinanceCharges)
+		var chargesAmount int64
+		for _, charge := range charges {
+			amount, _ := money.Parse(charge.Amount)
+			chargesAmount += amount
+		}
+		vehicle.Payments = append(vehicle.Payments, credit.Payment{
+			ID:           uuid(),
+			IsOverParcel: true,
+			Date:         p.Date,
+			Amount:       money.Format(chargesAmount),
+			OverParcel: &credit.OverParcel{
+				Charges: charges,
+			},
+		})
+	}
+
+	solarPanelsDate := date(-3, -20)
+	solarPanels := financing.Contract{
+		Contract: credit.Contract{
+			ID:             uuid(),
+			Number:         "2000567890",
+			IPOCCode:       "58540569BENS2000567890",
+			ProductName:    "Financiamento de Energia Solar",
+			ProductType:    financing.ProductTypeFinancing,
+			ProductSubType: financing.ProductSubTypeOtherGoods,
+			Terms: credit.Terms{
+				Amount:                 "18000.00",
+				Date:                   solarPanelsDate,
+				InstalmentNumber:       24,
+				InstalmentPeriodicity:  credit.PeriodicityMonthly,
+				FirstInstalmentDueDate: timex.NewDate(solarPanelsDate.AddDate(0, 1, 0)),
+				AmortizationSchedule:   credit.AmortizationScheduleSAM,
+				InterestRate:           preFixedRate(credit.TaxPeriodicityYearly, "0.2190"),
+				Fees: []credit.Fee{
+					{
+						Name:       "Tarifa de cadastro",
+						Code:       "CADASTRO",
+						ChargeType: credit.FeeChargeTypeSingle,
+						Charge:     credit.FeeChargeFixed,
+						Amount:     "50.00",
+					},
+				},
+			},
+			DisbursementDates: []timex.Date{solarPanelsDate},
+			FinanceCharges: []credit.FinanceCharge{
+				{Type: credit.FinanceChargeTypeLateFine, Rate: "0.0200"},
+				{Type: credit.FinanceChargeTypeDefault, Rate: "0.1268"},
+				{Type: credit.FinanceChargeTypeIOF, Rate: "0.0038"},
+			},
+		},
+	}
+	// The instalment due in the last month wasn't paid.
+	solarPanels.Payments = instalmentPayments(solarPanels.Terms, timex.NewDate(today.AddDate(0, -1, 0)))
+
+	return []financing.Contract{vehicle, solarPanels}
+}
+
+// invoiceFinancingContracts generates the discount of trade bills to be paid at
+// once at its due date and a settled credit card invoice advance.
+func invoiceFinancingContracts() []invoicefinancing.Contract {
+	today := timex.DateNow()
+	date := func(months, days int) timex.Date {
+		return timex.NewDate(today.AddDate(0, months, days))
+	}
+
+	tradeBillsDate := date(0, -15)
+	tradeBills := invoicefinancing.Contract{
+		Contract: credit.Contract{
+			ID:             uuid(),
+			Number:         "4000789012",
+			IPOCCode:       "58540569DUPL4000789012",
+			ProductName:    "Desconto de Duplicatas",
+			ProductType:    invoicefinancing.ProductTypeDiscountedCreditRights,
+			ProductSubType: invoicefinancing.ProductSubTypeTradeBillsDiscount,
+			Terms: credit.Terms{
+				Amount:                 "50000.00",
+				Date:                   tradeBillsDate,
+				InstalmentNumber:       1,
+				InstalmentPeriodicity:  credit.PeriodicityIrregular,
+				FirstInstalmentDueDate: timex.NewDate(tradeBillsDate.AddDate(0, 0, 60)),
+				AmortizationSchedule:   credit.AmortizationScheduleNone,
+				InterestRate:           preFixedRate(credit.TaxPeriodicityMonthly, "0.0250"),
+				Fees: []credit.Fee{
+					{
+						Name:       "Tarifa de cadastro",
+						Code:       "CADASTRO",
+						ChargeType: credit.FeeChargeTypeSingle,
+						Charge:     credit.FeeChargeFixed,
+						Amount:     "150.00",
+					},
+				},
+			},
+			DisbursementDates: []timex.Date{tradeBillsDate},
+			FinanceCharges: []credit.FinanceCharge{
+				{Type: credit.FinanceChargeTypeLateFine, Rate: "0.0200"},
+				{Type: credit.FinanceChargeTypeDefault, Rate: "0.1268"},
+				{Type: credit.FinanceChargeTypeIOF, Rate: "0.0038"},
+			},
+			Warranties: []credit.Warranty{
+				{
+					Type:    credit.WarrantyTypeCreditRightsAssignment,
+					SubType: credit.WarrantySubTypeTradeBills,
+					Amount:  "50000.00",
+				},
+			},
+		},
+	}
+
+	cardInvoiceDate := date(-4, 0)
+	cardInvoice := invoicefinancing.Contract{
+		Contract: credit.Contract{
+			ID:             uuid(),
+			Number:         "4000890123",
+			IPOCCode:       "58540569ANTC4000890123",
+			ProductName:    "Antecipação de Recebíveis de Cartão",
+			ProductType:    invoicefinancing.ProductTypeDiscountedCreditRights,
+			ProductSubType: invoicefinancing.ProductSubTypeCreditCardInvoiceAdvance,
+			Terms: credit.Terms{
+				Amount:                 "12000.00",
+				Date:                   cardInvoiceDate,
+				InstalmentNumber:       1,
+				InstalmentPeriodicity:  credit.PeriodicityIrregular,
+				FirstInstalmentDueDate: date(-1, 0),
+				AmortizationSchedule:   credit.AmortizationScheduleNone,
+				InterestRate:           preFixedRate(credit.TaxPeriodicityMonthly, "0.0210"),
+			},
+			DisbursementDates: []timex.Date{cardInvoiceDate},
+			FinanceCharges: []credit.FinanceCharge{
+				{Type: credit.FinanceChargeTypeNone},
+			},
+		},
+	}
+	cardInvoice.Payments = instalmentPayments(cardInvoice.Terms, today)
+
+	return []invoicefinancing.Contract{tradeBills, cardInvoice}
+}
+
+// unarrangedOverdraftContract generates the unarranged overdraft of the
+// account identified by accNumber, partially paid back with two payments.
+// The amount still owed is the unarranged overdraft amount of the account.
+func unarrangedOverdraftContract(accNumber string) overdraft.Contract {
+	contractDate := timex.NewDate(timex.DateNow().AddDate(0, -2, 0))
+	return overdraft.Contract{
+		Contract: credit.Contract{
+			ID:             uuid(),
+			Number:         "3000678901",
+			IPOCCode:       "58540569ADEP3000678901",
+			ProductName:    "Adiantamento a Depositantes",
+			ProductType:    overdraft.ProductTypeDepositorAdvance,
+			ProductSubType: overdraft.ProductSubTypeDepositorAdvance,
+			Terms: credit.Terms{
+				Date:                  contractDate,
+				InstalmentPeriodicity: credit.PeriodicityIrregular,
+				AmortizationSchedule:  credit.AmortizationScheduleNone,
+				InterestRate:          preFixedRate(credit.TaxPeriodicityMonthly, "0.0800"),
+				Fees: []credit.Fee{
+					{
+						Name:       "Concessão de adiantamento a depositante",
+						Code:       "ADIANT_DEPOSITANTE",
+						ChargeType: credit.FeeChargeTypeSingle,
+						Charge:     credit.FeeChargeFixed,
+						Amount:     "50.00",
+					},
+				},
+			},
+			DisbursementDates: []timex.Date{contractDate},
+			DueDate:           timex.NewDate(contractDate.AddDate(0, 3, 0)),
+			FinanceCharges: []credit.FinanceCharge{
+				{Type: credit.FinanceChargeTypeLateFine, Rate: "0.0200"},
+				{Type: credit.FinanceChargeTypeDefault, Rate: "0.1268"},
+			},
+			Payments: []credit.Payment{
+				{
+					ID:           uuid(),
+					IsOverParcel: true,
+					Date:         timex.NewDate(contractDate.AddDate(0, 0, 20)),
+					Amount:       "200.00",
+				},
+				{
+					ID:           uuid(),
+					IsOverParcel: true,
+					Date:         timex.NewDate(contractDate.AddDate(0, 1, 10)),
+					Amount:       "150.00",
+				},
+			},
+		},
+		AccountNumber: accNumber,
+	}
+}
+
+// preFixedRate returns an effective compound pre-fixed interest rate.
+func preFixedRate(periodicity credit.TaxPeriodicity, rate string) credit.InterestRate {
+	return credit.InterestRate{
+		TaxType:                       credit.TaxTypeEffective,
+		InterestRateType:              credit.InterestRateTypeCompound,
+		TaxPeriodicity:                periodicity,
+		Calculation:                   credit.Calculation30Over360,
+		ReferentialRateIndexerType:    credit.IndexerTypePreFixed,
+		ReferentialRateIndexerSubType: credit.IndexerSubTypePre,
+		PreFixedRate:                  rate,
+		PostFixedRate:                 "0.0000",
+	}
+}
+
+// bankFixedIncomeInvestments generates a CDB paying a percentage of the CDI
+// which was partially redeemed, an LCI indexed to the IPCA and a pre fixed LCA
+// still in its grace period.
+func bankFixedIncomeInvestments() []bankfixedincome.Investment {
+	today := timex.DateNow()
+	date := func(months, days int) timex.Date {
+		return timex.NewDate(today.AddDate(0, months, days))
+	}
+
+	cdbDate := date(-8, 0)
+	cdb := bankfixedincome.Investment{
+		ID:           uuid(),
+		Type:         bankfixedincome.TypeCDB,
+		IssuerCNPJ:   mock.MockBankCNPJ,
+		ISINCode:     "BRMOCKCDB0A1",
+		ClearingCode: "CDB0245A1B2",
+		Remuneration: bankfixedincome.Remuneration{
+			PostFixedIndexerPercentage: "1.1000",
+			RateType:                   bankfixedincome.RateTypeExponential,
+			RatePeriodicity:            bankfixedincome.RatePeriodicityYearly,
+			Calculation:                bankfixedincome.CalculationBusinessDays,
+			Indexer:                    bankfixedincome.IndexerCDI,
+		},
+		IssueUnitPrice:  "1000.00",
+		IssueDate:       cdbDate,
+		PurchaseDate:    cdbDate,
+		DueDate:         timex.NewDate(cdbDate.AddDate(2, 0, 0)),
+		GracePeriodDate: cdbDate,
+	}
+	cdb.Transactions = []bankfixedincome.Transaction{
+		cdb.Application(20),
+		cdb.Redemption(date(-2, 0), 5),
+	}
+
+	lciDate := date(-14, 0)
+	lci := bankfixedincome.Investment{
+		ID:           uuid(),
+		Type:         bankfixedincome.TypeLCI,
+		IssuerCNPJ:   mock.MockBankCNPJ,
+		ISINCode:     "BRMOCKLCI0B2",
+		ClearingCode: "LCI0245C3D4",
+		Remuneration: bankfixedincome.Remuneration{
+			PreFixedRate:    "0.0550",
+			RateType:        bankfixedincome.RateTypeExponential,
+			RatePeriodicity: bankfixedincome.RatePeriodicityYearly,
+			Calculation:     bankfixedincome.CalculationCalendarDays,
+			Indexer:         bankfixedincome.IndexerIPCA,
+		},
+		IssueUnitPrice:  "1000.00",
+		IssueDate:       lciDate,
+		PurchaseDate:    lciDate,
+		DueDate:         timex.NewDate(lciDate.AddDate(3, 0, 0)),
+		GracePeriodDate: timex.NewDate(lciDate.AddDate(1, 0, 0)),
+	}
+	lci.Transactions = []bankfixedincome.Transaction{lci.Application(30)}
+
+	// The LCA was issued before Bob bought it, so he paid the price updated
+	// since the issue.
+	lcaDate := date(-2, 0)
+	lca := bankfixedincome.Investment{
+		ID:           uuid(),
+		Type:         bankfixedincome.TypeLCA,
+		IssuerCNPJ:   mock.MockBankCNPJ,
+		ISINCode:     "BRMOCKLCA0C3",
+		ClearingCode: "LCA0245E5F6",
+		Remuneration: bankfixedincome.Remuneration{
+			PreFixedRate:    "0.1150",
+			RateType:        bankfixedincome.RateTypeExponential,
+			RatePeriodicity: bankfixedincome.RatePeriodicityYearly,
+			Calculation:     bankfixedincome.CalculationBusinessDays,
+			Indexer:         bankfixedincome.IndexerPreFixed,
+		},
+		IssueUnitPrice:  "1000.00",
+		IssueDate:       timex.NewDate(lcaDate.AddDate(0, 0, -10)),
+		PurchaseDate:    lcaDate,
+		DueDate:         timex.NewDate(lcaDate.AddDate(1, 0, 0)),
+		GracePeriodDate: timex.NewDate(lcaDate.AddDate(1, 0, 0)),
+	}
+	lca.Transactions = []bankfixedincome.Transaction{lca.Application(15)}
+
+	for _, inv := range []*bankfixedincome.Investment{&cdb, &lci, &lca} {
+		for i := range inv.Transactions {
+			inv.Transactions[i].ID = uuid()
+		}
+	}
+
+	return []bankfixedincome.Investment{cdb, lci, lca}
+}
+
+// instalmentPayments pays the instalments of the contract due until the date
+// informed on their due dates.
+func instalmentPayments(terms credit.Terms, until timex.Date) []credit.Payment {
+	var payments []credit.Payment
+	for _, inst := range terms.Schedule() {
+		if inst.DueDate.After(until.Time) {
+			break
+		}
+
+		payments = append(payments, credit.Payment{
+			ID:           uuid(),
+			InstalmentID: inst.ID,
+			Date:         inst.DueDate,
+			Amount:       inst.Amount,
+		})
+	}
+	return payments
+}
+
 // uuid generates a UUID-like string using a seeded random generator.
 func uuid() string {
 	b := make([]byte, 16)
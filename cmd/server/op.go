@@ -11,14 +11,23 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/luikyv/go-oidc/pkg/goidc"
 	"github.com/luikyv/go-oidc/pkg/provider"
 	"github.com/luikyv/go-open-finance/internal/account"
+	"github.com/luikyv/go-open-finance/internal/autopayment"
+	"github.com/luikyv/go-open-finance/internal/bankfixedincome"
 	"github.com/luikyv/go-open-finance/internal/consent"
 	"github.com/luikyv/go-open-finance/internal/creditcard"
 	"github.com/luikyv/go-open-finance/internal/customer"
+	"github.com/luikyv/go-open-finance/internal/enrollment"
+	"github.com/luikyv/go-open-finance/internal/financing"
+	"github.com/luikyv/go-open-finance/internal/invoicefinancing"
+	"github.com/luikyv/go-open-finance/internal/loan"
 	"github.com/luikyv/go-open-finance/internal/oidc"
+	"github.com/luikyv/go-open-finance/internal/overdraft"
+	"github.com/luikyv/go-open-finance/internal/payment"
 	"github.com/luikyv/go-open-finance/internal/resource"
 	"github.com/luikyv/go-open-finance/internal/user"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -31,11 +40,16 @@ var Scopes = []goidc.Scope{
 	customer.Scope,
 	account.Scope,
 	creditcard.Scope,
-	// ScopeLoans,
-	// ScopeFinancings,
-	// ScopeUnarrangedAccountsOverdraft,
-	// ScopeInvoiceFinancings,
-	// ScopeBankFixedIncomes,
+	payment.Scope,
+	autopayment.ScopeConsentID,
+	autopayment.Scope,
+	enrollment.ScopeID,
+	enrollment.Scope,
+	loan.Scope,
+	financing.Scope,
+	overdraft.Scope,
+	invoicefinancing.Scope,
+	bankfixedincome.Scope,
 	// ScopeCreditFixedIncomes,
 	// ScopeVariableIncomes,
 	// ScopeTreasureTitles,
@@ -46,11 +60,6 @@ var Scopes = []goidc.Scope{
 
 // var (
 // 	ScopeOpenID                      = goidc.ScopeOpenID
-// 	ScopeLoans                       = goidc.NewScope("loans")
-// 	ScopeFinancings                  = goidc.NewScope("financings")
-// 	ScopeUnarrangedAccountsOverdraft = goidc.NewScope("unarranged-accounts-overdraft")
-// 	ScopeInvoiceFinancings           = goidc.NewScope("invoice-financings")
-// 	ScopeBankFixedIncomes            = goidc.NewScope("bank-fixed-incomes")
 // 	ScopeCreditFixedIncomes          = goidc.NewScope("credit-fixed-incomes")
 // 	ScopeVariableIncomes             = goidc.NewScope("variable-incomes")
 // 	ScopeTreasureTitles              = goidc.NewScope("treasure-titles")
@@ -62,19 +71,17 @@ func openidProvider(
 	db *mongo.Database,
 	userService user.Service,
 	consentService consent.Service,
+	paymentService payment.Service,
+	autoPaymentService autopayment.Service,
+	enrollmentService enrollment.Service,
+	serverJWKS goidc.JSONWebKeySet,
 ) (
 	*provider.Provider,
 	error,
 ) {
 
-	// Get the file path of the source file.
-	_, filename, _, _ := runtime.Caller(0)
-	sourceDir := filepath.Dir(filename)
-
-	templatesDirPath := filepath.Join(sourceDir, "../../templates")
-	// TODO: This will cause problems for the docker file.
-	keysDir := filepath.Join(sourceDir, "../../keys")
-	serverJWKS := privateJWKS(filepath.Join(keysDir, "server.jwks"))
+	templatesDirPath := filepath.Join(sourceDir(), "../../templates")
+	keysDir := keysDir()
 
 	return provider.New(
 		goidc.ProfileFAPI1,
@@ -110,8 +117,8 @@ func openidProvider(
 		provider.WithIDTokenEncryption(goidc.RSA_OAEP),
 		provider.WithStaticClient(client("client_one", keysDir)),
 		provider.WithStaticClient(client("client_two", keysDir)),
-		provider.WithHandleGrantFunc(oidc.HandleGrantFunc(consentService)),
-		provider.WithPolicy(oidc.Policy(templatesDirPath, host+pathPrefixOIDC, userService, consentService)),
+		provider.WithHandleGrantFunc(oidc.HandleGrantFunc(consentService, paymentService, autoPaymentService, enrollmentService)),
+		provider.WithPolicy(oidc.Policy(templatesDirPath, host+pathPrefixOIDC, userService, consentService, paymentService, autoPaymentService, enrollmentService)),
 		provider.WithNotifyErrorFunc(oidc.LogErrorFunc()),
 		provider.WithDCR(oidc.DCRFunc(Scopes), func(r *http.Request, s string) error {
 			return nil
@@ -120,6 +127,17 @@ func openidProvider(
 	)
 }
 
+// sourceDir returns the directory of this source file.
+func sourceDir() string {
+	_, filename, _, _ := runtime.Caller(0)
+	return filepath.Dir(filename)
+}
+
+// TODO: This will cause problems for the docker file.
+func keysDir() string {
+	return filepath.Join(sourceDir(), "../../keys")
+}
+
 func client(clientID string, keysDir string) *goidc.Client {
 	var scopes []string
 	for _, scope := range Scopes {
@@ -174,16 +192,39 @@ func privateJWKS(filePath string) goidc.JSONWebKeySet {
 }
 
 func httpClientFunc() goidc.HTTPClientFunc {
-	client := &http.Client{
+	client := httpClient()
+	return func(ctx context.Context) *http.Client {
+		return client
+	}
+}
+
+// mtlsHTTPClient authenticates with the transport certificate of MockBank. It
+// is used to call the APIs of the clients, e.g. their webhooks.
+func mtlsHTTPClient() *http.Client {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(keysDir(), "server.crt"), filepath.Join(keysDir(), "server.key"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{
+				Certificates:       []tls.Certificate{cert},
 				Renegotiation:      tls.RenegotiateOnceAsClient,
 				InsecureSkipVerify: true,
 			},
 		},
 	}
+}
 
-	return func(ctx context.Context) *http.Client {
-		return client
+func httpClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				Renegotiation:      tls.RenegotiateOnceAsClient,
+				InsecureSkipVerify: true,
+			},
+		},
 	}
 }
@@ -3,13 +3,13 @@ module github.com/luikyv/go-open-finance
 go 1.22.4
 
 require (
+	github.com/go-jose/go-jose/v4 v4.0.4
 	github.com/google/uuid v1.6.0
 	github.com/luikyv/go-oidc v0.8.0
 	go.mongodb.org/mongo-driver v1.17.2
 )
 
 require (
-	github.com/go-jose/go-jose/v4 v4.0.4 // indirect
 	github.com/golang/snappy v0.0.4 // indirect
 	github.com/klauspost/compress v1.16.7 // indirect
 	github.com/montanaflynn/stats v0.7.1 // indirect
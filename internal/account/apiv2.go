@@ -332,7 +332,7 @@ func toAccountTransactionsResponseV2(trs page.Page[Transaction], reqURL string)
 				Amount:   tr.Amount,
 				Currency: DefaultCurrency,
 			},
-			DateTime: tr.DateTime.Format(dateTimeMillisFormat),
+			DateTime: tr.DateTime.UTC().Format(dateTimeMillisFormat),
 		})
 	}
 
@@ -385,57 +385,15 @@ type amountResponseV2 struct {
 }
 
 func newTransactionFilter(r *http.Request, current bool) (transactionFilter, error) {
-	now := timex.DateNow()
-	filter := transactionFilter{
-		from: now,
-		to:   now,
+	dates, err := api.NewTransactionDateRange(r, "fromBookingDate", "toBookingDate", current)
+	if err != nil {
+		return transactionFilter{}, err
 	}
 
-	from := r.URL.Query().Get("fromBookingDate")
-	to := r.URL.Query().Get("toBookingDate")
-
-	if from != "" {
-		if to == "" {
-			return transactionFilter{}, api.NewError("INVALID_PARAMETER",
-				http.StatusUnprocessableEntity, "toBookingDate is required if fromBookingDate is informed")
-		}
-
-		fromDate, err := timex.ParseDate(from)
-		if err != nil {
-			return transactionFilter{}, api.NewError("INVALID_PARAMETER",
-				http.StatusUnprocessableEntity, "invalid fromBookingDate")
-		}
-		filter.from = fromDate
-	}
-
-	if to != "" {
-		if from == "" {
-			return transactionFilter{}, api.NewError("INVALID_PARAMETER",
-				http.StatusUnprocessableEntity, "fromBookingDate is required if toBookingDate is informed")
-		}
-
-		toDate, err := timex.ParseDate(to)
-		if err != nil {
-			return transactionFilter{}, api.NewError("INVALID_PARAMETER",
-				http.StatusUnprocessableEntity, "invalid toBookingDate")
-		}
-		filter.to = toDate
-	}
-
-	if current {
-		nowMinus7Days := now.AddDate(0, 0, -7)
-		if filter.from.Before(nowMinus7Days) {
-			return transactionFilter{}, api.NewError("INVALID_PARAMETER",
-				http.StatusUnprocessableEntity, "fromBookingDate too far in the past")
-		}
-
-		if filter.to.Before(nowMinus7Days) {
-			return transactionFilter{}, api.NewError("INVALID_PARAMETER",
-				http.StatusUnprocessableEntity, "toBookingDate too far in the past")
-		}
-	}
-
-	return filter, nil
+	return transactionFilter{
+		from: dates.From,
+		to:   dates.To,
+	}, nil
 }
 
 func writeErrorV2(w http.ResponseWriter, err error, pagination bool) {
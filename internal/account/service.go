@@ -3,13 +3,20 @@ package account
 import (
 	"context"
 	"errors"
+	"log/slog"
 
+	"github.com/google/uuid"
 	"github.com/luikyv/go-open-finance/internal/consent"
 	"github.com/luikyv/go-open-finance/internal/mock"
+	"github.com/luikyv/go-open-finance/internal/money"
 	"github.com/luikyv/go-open-finance/internal/page"
+	"github.com/luikyv/go-open-finance/internal/timex"
 )
 
 var (
+	ErrAccountNotFound     = errors.New("the account was not found")
+	ErrInsufficientBalance = errors.New("the account does not have enough balance")
+
 	errAccountNotAllowed                = errors.New("the account was not consented")
 	errJointAccountPendingAuthorization = errors.New("the account was not authorized by all users")
 )
@@ -31,6 +38,86 @@ func (s Service) Set(userID string, acc Account) {
 	s.storage.save(acc)
 }
 
+// Accounts returns the accounts of the user sorted by ID.
+func (s Service) Accounts(userID string) []Account {
+	return s.storage.userAccounts(userID)
+}
+
+// Debit withdraws amount from the account of the user identified by number and
+// books a completed transaction for it. If number is empty, the first account
+// of the user is debited.
+// Overdraft is not allowed, so [ErrInsufficientBalance] is returned and the
+// account is left unchanged if amount is above the available amount.
+func (s Service) Debit(ctx context.Context, userID, number, amount string, trType TransactionType, name string) error {
+	return s.book(ctx, userID, number, amount, MovementTypeDebit, trType, name)
+}
+
+// Credit deposits amount in the account of the user identified by number and
+// books a completed transaction for it, e.g. to refund a debit. If number is
+// empty, the first account of the user is credited.
+func (s Service) Credit(ctx context.Context, userID, number, amount string, trType TransactionType, name string) error {
+	return s.book(ctx, userID, number, amount, MovementTypeCredit, trType, name)
+}
+
+func (s Service) book(
+	ctx context.Context,
+	userID, number, amount string,
+	movementType MovementType,
+	trType TransactionType,
+	name string,
+) error {
+	cents, err := money.Parse(amount)
+	if err != nil {
+		return err
+	}
+
+	acc, err := s.UserAccount(userID, number)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "booking transaction", slog.String("account_id", acc.ID), slog.Any("type", movementType),
+		slog.String("amount", amount))
+	return s.storage.update(acc.ID, func(acc *Account) error {
+		available, err := money.Parse(acc.Balance.AvailableAmount)
+		if err != nil {
+			return err
+		}
+
+		delta := cents
+		if movementType == MovementTypeDebit {
+			if cents > available {
+				return ErrInsufficientBalance
+			}
+			delta = -cents
+		}
+		acc.Balance.AvailableAmount = money.Format(available + delta)
+
+		// Transactions are kept from the most recent to the oldest.
+		acc.Transactions = append([]Transaction{{
+			ID:           uuid.NewString(),
+			Status:       TransactionStatusCompleted,
+			MovementType: movementType,
+			Name:         name,
+			Type:         trType,
+			Amount:       amount,
+			DateTime:     timex.DateTimeNow(),
+		}}, acc.Transactions...)
+		return nil
+	})
+}
+
+// UserAccount returns the account of the user identified by number. If number
+// is empty, the first account of the user is returned.
+func (s Service) UserAccount(userID, number string) (Account, error) {
+	for _, acc := range s.Accounts(userID) {
+		if number == "" || acc.Number == number {
+			return acc, nil
+		}
+	}
+	return Account{}, ErrAccountNotFound
+}
+
 func (s Service) accounts(ctx context.Context, consentID string, pag page.Pagination) (page.Page[Account], error) {
 	c, err := s.consentService.Consent(ctx, consentID)
 	if err != nil {
@@ -1,10 +1,16 @@
 package account
 
 import (
+	"slices"
+	"strings"
+	"sync"
+
 	"github.com/luikyv/go-open-finance/internal/page"
 )
 
 type Storage struct {
+	// mu guards the accounts, which are updated when payments are settled.
+	mu          sync.RWMutex
 	accountsMap map[string]Account
 }
 
@@ -15,13 +21,47 @@ func NewStorage() *Storage {
 }
 
 func (s *Storage) save(acc Account) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.accountsMap[acc.ID] = acc
 }
 
 func (s *Storage) account(id string) Account {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.accountsMap[id]
 }
 
+// userAccounts returns the accounts of the user sorted by ID.
+func (s *Storage) userAccounts(userID string) []Account {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var accs []Account
+	for _, acc := range s.accountsMap {
+		if acc.UserID == userID {
+			accs = append(accs, acc)
+		}
+	}
+	slices.SortFunc(accs, func(a, b Account) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return accs
+}
+
+// update applies fn to the account atomically.
+func (s *Storage) update(id string, fn func(acc *Account) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc := s.accountsMap[id]
+	if err := fn(&acc); err != nil {
+		return err
+	}
+	s.accountsMap[id] = acc
+	return nil
+}
+
 func (s *Storage) transactions(accID string, pag page.Pagination, filter transactionFilter) page.Page[Transaction] {
 	acc := s.account(accID)
 	var trs []Transaction
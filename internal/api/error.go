@@ -33,10 +33,20 @@ func NewError(code string, status int, description string) Error {
 }
 
 func WriteError(w http.ResponseWriter, err error) {
+	errResp, status := toErrorResponse(err)
+	WriteJSON(w, errResp, status)
+}
+
+// WriteJWTError is the signed counterpart of [WriteError].
+func WriteJWTError(w http.ResponseWriter, err error, signer JWTSigner, audience string) {
+	errResp, status := toErrorResponse(err)
+	WriteJWT(w, errResp, status, signer, audience)
+}
+
+func toErrorResponse(err error) (response, int) {
 	var apiErr Error
 	if !errors.As(err, &apiErr) {
-		WriteError(w, Error{"INTERNAL_ERROR", http.StatusInternalServerError, "internal error", false})
-		return
+		apiErr = Error{"INTERNAL_ERROR", http.StatusInternalServerError, "internal error", false}
 	}
 
 	errResp := response{
@@ -57,7 +67,7 @@ func WriteError(w http.ResponseWriter, err error) {
 		errResp.Meta = NewSingleRecordMeta()
 	}
 
-	WriteJSON(w, errResp, apiErr.statusCode)
+	return errResp, apiErr.statusCode
 }
 
 type response struct {
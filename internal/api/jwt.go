@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+// JWTSigner signs the JWT bodies exchanged by the APIs which require
+// application/jwt requests and responses.
+type JWTSigner struct {
+	issuer string
+	jwk    goidc.JSONWebKey
+}
+
+// NewJWTSigner creates a signer which issues JWTs with issuer using the PS256
+// key available in jwks.
+func NewJWTSigner(issuer string, jwks goidc.JSONWebKeySet) (JWTSigner, error) {
+	jwk, err := jwks.KeyByAlg(string(goidc.PS256))
+	if err != nil {
+		return JWTSigner{}, err
+	}
+
+	return JWTSigner{
+		issuer: issuer,
+		jwk:    jwk,
+	}, nil
+}
+
+// Issuer is the value of the "iss" claim of the JWTs signed by s.
+func (s JWTSigner) Issuer() string {
+	return s.issuer
+}
+
+// Sign adds the "iss", "aud", "iat" and "jti" claims to claims and signs the
+// result.
+func (s JWTSigner) Sign(claims map[string]any, audience string) (string, error) {
+	claims["iss"] = s.issuer
+	claims["aud"] = audience
+	claims["iat"] = timex.Now().Unix()
+	claims["jti"] = uuid.NewString()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.PS256, Key: s.jwk},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return jws.CompactSerialize()
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-oidc/pkg/provider"
+	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+const (
+	jwtMaxAgeSecs   = 60
+	jwtClockSkewSec = 5
+)
+
+var (
+	errInvalidSignature = api.NewError("BAD_SIGNATURE", http.StatusBadRequest, "the request signature is invalid")
+	errInvalidClaims    = api.NewError("PARAMETRO_INVALIDO", http.StatusBadRequest, "the request jwt claims are invalid")
+
+	usedJTIs = jtiCache{jtis: map[string]int64{}}
+)
+
+// JWS verifies the JWT sent as the request body and signs the response
+// returned by next.
+// The request body is replaced by the JWT claims, so next can handle it as
+// JSON. The claims "iss", "aud", "iat" and "jti" are not forwarded.
+// This middleware must run after the client was identified by [AuthScopes].
+func JWS(next http.Handler, op *provider.Provider, signer api.JWTSigner, httpClient *http.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		clientID := ctx.Value(api.CtxKeyClientID).(string)
+		client, err := op.Client(ctx, clientID)
+		if err != nil {
+			slog.ErrorContext(ctx, "could not load the client", slog.String("error", err.Error()))
+			api.WriteJWTError(w, err, signer, clientID)
+			return
+		}
+		orgID := api.ClientOrgID(client)
+
+		if r.ContentLength != 0 && r.Body != nil && r.Body != http.NoBody {
+			claims, err := verifyJWS(r, client, orgID, signer.Issuer(), httpClient)
+			if err != nil {
+				slog.DebugContext(ctx, "invalid request jws", slog.String("error", err.Error()))
+				api.WriteJWTError(w, err, signer, orgID)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(claims))
+			r.Header.Set("Content-Type", api.ContentTypeJSON)
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.body.Len() == 0 {
+			w.WriteHeader(rec.status)
+			return
+		}
+
+		var data map[string]any
+		if err := json.Unmarshal(rec.body.Bytes(), &data); err != nil {
+			api.WriteJWTError(w, err, signer, orgID)
+			return
+		}
+		api.WriteJWT(w, data, rec.status, signer, orgID)
+	})
+}
+
+// verifyJWS validates the request JWT and returns its claims without the ones
+// used to secure it.
+func verifyJWS(r *http.Request, client *goidc.Client, orgID, audience string, httpClient *http.Client) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), api.ContentTypeJWT) {
+		return nil, api.NewError("PARAMETRO_INVALIDO", http.StatusUnsupportedMediaType, "the content type must be application/jwt")
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errInvalidSignature
+	}
+
+	jws, err := jose.ParseSignedCompact(string(body), []jose.SignatureAlgorithm{jose.PS256})
+	if err != nil || len(jws.Signatures) != 1 {
+		return nil, errInvalidSignature
+	}
+
+	jwks, err := client.FetchPublicJWKS(httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	jwk, err := jwks.Key(jws.Signatures[0].Header.KeyID)
+	if err != nil {
+		return nil, errInvalidSignature
+	}
+
+	payload, err := jws.Verify(jwk.Key)
+	if err != nil {
+		return nil, errInvalidSignature
+	}
+
+	var claims struct {
+		Issuer   string `json:"iss"`
+		Audience string `json:"aud"`
+		IssuedAt int64  `json:"iat"`
+		JWTID    string `json:"jti"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errInvalidClaims
+	}
+
+	if claims.Issuer != orgID || claims.Audience != audience {
+		return nil, errInvalidClaims
+	}
+
+	now := timex.Now().Unix()
+	if claims.IssuedAt > now+jwtClockSkewSec || claims.IssuedAt < now-jwtMaxAgeSecs {
+		return nil, errInvalidClaims
+	}
+
+	if claims.JWTID == "" || !usedJTIs.add(claims.JWTID, claims.IssuedAt) {
+		return nil, errInvalidClaims
+	}
+
+	// Only the request data is forwarded, this way retries signed with new
+	// claims are seen as the same request.
+	var data map[string]any
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, errInvalidClaims
+	}
+	for _, claim := range []string{"iss", "aud", "iat", "jti"} {
+		delete(data, claim)
+	}
+
+	return json.Marshal(data)
+}
+
+// responseRecorder holds the response written by a handler, so it can be
+// signed before being sent.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	return rec.body.Write(b)
+}
+
+// jtiCache remembers the jtis of the JWTs received to prevent replays.
+// A jti only needs to be remembered while its JWT is accepted, i.e. until it
+// is older than the max age, so the cache holds at most the JWTs issued in
+// that window.
+type jtiCache struct {
+	mu sync.Mutex
+	// jtis maps each jti to the last second its JWT is accepted.
+	jtis map[string]int64
+}
+
+// add registers jti of a JWT issued at iat and returns false if it was already
+// used.
+func (c *jtiCache) add(jti string, iat int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := timex.Now().Unix()
+	for id, exp := range c.jtis {
+		if now > exp {
+			delete(c.jtis, id)
+		}
+	}
+
+	if _, ok := c.jtis[jti]; ok {
+		return false
+	}
+
+	c.jtis[jti] = iat + jwtMaxAgeSecs
+	return true
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -56,6 +57,70 @@ func NewPagination(r *http.Request) (page.Pagination, error) {
 	return page.NewPagination(pageNumber, pageSize), nil
 }
 
+// DateRange is an inclusive range of dates.
+type DateRange struct {
+	From timex.Date
+	To   timex.Date
+}
+
+// NewTransactionDateRange parses the range of dates transactions are filtered
+// by from the query parameters fromParam and toParam, which must be informed
+// together. If current is true, only the transactions of the last 7 days can
+// be requested.
+func NewTransactionDateRange(r *http.Request, fromParam, toParam string, current bool) (DateRange, error) {
+	now := timex.DateNow()
+	// By default, the transactions since the last business day are returned,
+	// so the ones made during weekends and holidays are included.
+	dates := DateRange{
+		From: timex.AddBusinessDays(now, -1),
+		To:   now,
+	}
+
+	from := r.URL.Query().Get(fromParam)
+	to := r.URL.Query().Get(toParam)
+
+	if from != "" {
+		if to == "" {
+			return DateRange{}, NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity,
+				fmt.Sprintf("%s is required if %s is informed", toParam, fromParam))
+		}
+
+		fromDate, err := timex.ParseDate(from)
+		if err != nil {
+			return DateRange{}, NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, "invalid "+fromParam)
+		}
+		dates.From = fromDate
+	}
+
+	if to != "" {
+		if from == "" {
+			return DateRange{}, NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity,
+				fmt.Sprintf("%s is required if %s is informed", fromParam, toParam))
+		}
+
+		toDate, err := timex.ParseDate(to)
+		if err != nil {
+			return DateRange{}, NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, "invalid "+toParam)
+		}
+		dates.To = toDate
+	}
+
+	if current {
+		nowMinus7Days := now.AddDate(0, 0, -7)
+		if dates.From.Before(nowMinus7Days) {
+			return DateRange{}, NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity,
+				fromParam+" too far in the past")
+		}
+
+		if dates.To.Before(nowMinus7Days) {
+			return DateRange{}, NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity,
+				toParam+" too far in the past")
+		}
+	}
+
+	return dates, nil
+}
+
 type Links struct {
 	First string `json:"first,omitempty"`
 	Last  string `json:"last,omitempty"`
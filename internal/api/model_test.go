@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+func TestNewTransactionDateRange(t *testing.T) {
+	today := timex.DateNow()
+	testCases := []struct {
+		name     string
+		query    string
+		current  bool
+		wantFrom timex.Date
+		wantTo   timex.Date
+		wantErr  bool
+	}{
+		{
+			// Without filters, the transactions since the last business day
+			// are returned, so a request made on Monday still gets the ones
+			// made over the weekend.
+			name:     "default",
+			wantFrom: timex.AddBusinessDays(today, -1),
+			wantTo:   today,
+		},
+		{
+			name:     "informed",
+			query:    "fromBookingDate=2024-01-01&toBookingDate=2024-01-31",
+			wantFrom: date(t, "2024-01-01"),
+			wantTo:   date(t, "2024-01-31"),
+		},
+		{
+			name:    "only from",
+			query:   "fromBookingDate=2024-01-01",
+			wantErr: true,
+		},
+		{
+			name:    "only to",
+			query:   "toBookingDate=2024-01-31",
+			wantErr: true,
+		},
+		{
+			name:    "invalid date",
+			query:   "fromBookingDate=2024-01-32&toBookingDate=2024-02-01",
+			wantErr: true,
+		},
+		{
+			name:     "default for current transactions",
+			current:  true,
+			wantFrom: timex.AddBusinessDays(today, -1),
+			wantTo:   today,
+		},
+		{
+			name: "current transactions of the last week",
+			query: "fromBookingDate=" + today.AddDate(0, 0, -7).Format("2006-01-02") +
+				"&toBookingDate=" + today.String(),
+			current:  true,
+			wantFrom: timex.NewDate(today.AddDate(0, 0, -7)),
+			wantTo:   today,
+		},
+		{
+			name: "current transactions older than a week",
+			query: "fromBookingDate=" + today.AddDate(0, 0, -8).Format("2006-01-02") +
+				"&toBookingDate=" + today.String(),
+			current: true,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/transactions?"+tc.query, nil)
+			dates, err := NewTransactionDateRange(r, "fromBookingDate", "toBookingDate", tc.current)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !dates.From.Equal(tc.wantFrom.Time) || !dates.To.Equal(tc.wantTo.Time) {
+				t.Errorf("got %s to %s, want %s to %s", dates.From, dates.To, tc.wantFrom, tc.wantTo)
+			}
+		})
+	}
+}
+
+func date(t *testing.T, s string) timex.Date {
+	t.Helper()
+	d, err := timex.ParseDate(s)
+	if err != nil {
+		t.Fatalf("invalid date %s: %v", s, err)
+	}
+	return d
+}
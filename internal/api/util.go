@@ -1,19 +1,66 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
+
+	"github.com/luikyv/go-oidc/pkg/goidc"
+)
+
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeJWT  = "application/jwt"
 )
 
 func WriteJSON(w http.ResponseWriter, data any, status int) {
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	w.WriteHeader(status)
+	_ = newEncoder(w).Encode(data)
+}
+
+// WriteJWT is the signed counterpart of [WriteJSON]. The data is written as
+// the payload of a JWT signed by signer.
+func WriteJWT(w http.ResponseWriter, data any, status int, signer JWTSigner, audience string) {
+	var buf bytes.Buffer
+	if err := newEncoder(&buf).Encode(data); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var claims map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &claims); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	jwt, err := signer.Sign(claims, audience)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeJWT)
 	w.WriteHeader(status)
+	_, _ = w.Write([]byte(jwt))
+}
+
+// ClientOrgID returns the organization the client belongs to. Clients
+// registered without a software statement are identified by their IDs.
+func ClientOrgID(client *goidc.Client) string {
+	if orgID, ok := client.CustomAttributes["org_id"].(string); ok && orgID != "" {
+		return orgID
+	}
+	return client.ID
+}
 
+func newEncoder(w io.Writer) *json.Encoder {
 	encoder := json.NewEncoder(w)
 	// By default, the encoding/json package escapes special characters like &, <, and >
 	// to prevent potential security issues (e.g., XSS when embedding JSON in HTML).
 	// However, this behavior is unnecessary in our case since some JSON objects
 	// contain URLs, and escaping these characters can cause issues.
 	encoder.SetEscapeHTML(false)
-	_ = encoder.Encode(data)
+	return encoder
 }
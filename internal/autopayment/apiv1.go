@@ -0,0 +1,931 @@
+package autopayment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-oidc/pkg/provider"
+	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/api/middleware"
+	"github.com/luikyv/go-open-finance/internal/idempotency"
+	"github.com/luikyv/go-open-finance/internal/payment"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+var (
+	errBadRequest = api.NewError("PARAMETRO_INVALIDO", http.StatusBadRequest, "invalid request")
+)
+
+type APIRouterV1 struct {
+	host               string
+	service            Service
+	op                 *provider.Provider
+	signer             api.JWTSigner
+	httpClient         *http.Client
+	idempotencyStorage idempotency.Storage
+}
+
+func NewAPIRouterV1(
+	host string,
+	service Service,
+	op *provider.Provider,
+	signer api.JWTSigner,
+	httpClient *http.Client,
+	idempotencyStorage idempotency.Storage,
+) APIRouterV1 {
+	return APIRouterV1{
+		host:               host,
+		service:            service,
+		op:                 op,
+		signer:             signer,
+		httpClient:         httpClient,
+		idempotencyStorage: idempotencyStorage,
+	}
+}
+
+func (router APIRouterV1) Register(mux *http.ServeMux) {
+	autoPaymentMux := http.NewServeMux()
+
+	handler := router.createConsentHandler()
+	handler = idempotency.Middleware(handler, router.idempotencyStorage)
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, Scope)
+	autoPaymentMux.Handle("POST /open-banking/automatic-payments/v1/recurring-consents", handler)
+
+	handler = router.getConsentHandler()
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, Scope)
+	autoPaymentMux.Handle("GET /open-banking/automatic-payments/v1/recurring-consents/{id}", handler)
+
+	handler = router.patchConsentHandler()
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, Scope)
+	autoPaymentMux.Handle("PATCH /open-banking/automatic-payments/v1/recurring-consents/{id}", handler)
+
+	handler = router.createPaymentHandler()
+	handler = idempotency.Middleware(handler, router.idempotencyStorage)
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, goidc.ScopeOpenID, ScopeConsentID, Scope)
+	autoPaymentMux.Handle("POST /open-banking/automatic-payments/v1/pix/recurring-payments", handler)
+
+	handler = router.getPaymentsHandler()
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, Scope)
+	autoPaymentMux.Handle("GET /open-banking/automatic-payments/v1/pix/recurring-payments", handler)
+
+	handler = router.getPaymentHandler()
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, Scope)
+	autoPaymentMux.Handle("GET /open-banking/automatic-payments/v1/pix/recurring-payments/{id}", handler)
+
+	handler = autoPaymentMux
+	handler = middleware.FAPIID(handler)
+	handler = middleware.Meta(handler, router.host)
+	mux.Handle("/open-banking/automatic-payments/v1/", handler)
+}
+
+func (router APIRouterV1) createConsentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req createConsentRequestV1
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.WriteError(w, errBadRequest)
+			return
+		}
+
+		if err := req.validate(); err != nil {
+			writeErrorV1(w, err)
+			return
+		}
+
+		c := req.toConsent(r.Context())
+		if err := router.service.createConsent(r.Context(), c); err != nil {
+			writeErrorV1(w, err)
+			return
+		}
+
+		resp := toConsentResponseV1(c, router.host)
+		api.WriteJSON(w, resp, http.StatusCreated)
+	})
+}
+
+func (router APIRouterV1) getConsentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		c, err := router.service.Consent(r.Context(), id)
+		if err != nil {
+			writeErrorV1(w, err)
+			return
+		}
+
+		resp := toConsentResponseV1(c, router.host)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+// patchConsentHandler either revokes or edits the consent depending on the
+// status informed.
+func (router APIRouterV1) patchConsentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req patchConsentRequestV1
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.WriteError(w, errBadRequest)
+			return
+		}
+
+		if err := req.validate(); err != nil {
+			writeErrorV1(w, err)
+			return
+		}
+
+		id := r.PathValue("id")
+		var c Consent
+		var err error
+		if req.Data.Status == ConsentStatusRevoked {
+			c, err = router.service.revoke(r.Context(), id, req.toRevocation())
+		} else {
+			c, err = router.service.edit(r.Context(), id, req.toEdition())
+		}
+		if err != nil {
+			writeErrorV1(w, err)
+			return
+		}
+
+		resp := toConsentResponseV1(c, router.host)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV1) createPaymentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req createPaymentRequestV1
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.WriteError(w, errBadRequest)
+			return
+		}
+
+		consentID, _ := ConsentID(r.Context().Value(api.CtxKeyScopes).(string))
+		if err := req.validate(consentID); err != nil {
+			writeErrorV1(w, err)
+			return
+		}
+
+		p, err := router.service.create(r.Context(), req.toPayment(r.Context()))
+		if err != nil {
+			writeErrorV1(w, err)
+			return
+		}
+
+		resp := toPaymentResponseV1(p, router.host)
+		api.WriteJSON(w, resp, http.StatusCreated)
+	})
+}
+
+func (router APIRouterV1) getPaymentsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.URL.Query().Get("recurringConsentId")
+		if consentID == "" {
+			api.WriteError(w, api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "recurringConsentId is required"))
+			return
+		}
+
+		ps, err := router.service.payments(r.Context(), consentID)
+		if err != nil {
+			writeErrorV1(w, err)
+			return
+		}
+
+		resp := toPaymentsResponseV1(ps, router.host+"/open-banking/automatic-payments/v1/pix/recurring-payments?recurringConsentId="+consentID)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV1) getPaymentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		p, err := router.service.Payment(r.Context(), id)
+		if err != nil {
+			writeErrorV1(w, err)
+			return
+		}
+
+		resp := toPaymentResponseV1(p, router.host)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+type createConsentRequestV1 struct {
+	Data struct {
+		LoggedUser             entityV1        `json:"loggedUser"`
+		BusinessEntity         *entityV1       `json:"businessEntity,omitempty"`
+		Creditors              []creditorV1    `json:"creditors"`
+		StartDateTime          timex.DateTime  `json:"startDateTime"`
+		ExpirationDateTime     *timex.DateTime `json:"expirationDateTime,omitempty"`
+		AdditionalInformation  string          `json:"additionalInformation,omitempty"`
+		RecurringConfiguration configurationV1 `json:"recurringConfiguration"`
+		DebtorAccount          *accountV1      `json:"debtorAccount,omitempty"`
+	} `json:"data"`
+}
+
+type entityV1 struct {
+	Document documentV1 `json:"document"`
+}
+
+type documentV1 struct {
+	Identification string `json:"identification"`
+	Relation       string `json:"rel"`
+}
+
+type creditorV1 struct {
+	PersonType payment.PersonType `json:"personType"`
+	CPFCNPJ    string             `json:"cpfCnpj"`
+	Name       string             `json:"name"`
+}
+
+type configurationV1 struct {
+	Sweeping  *sweepingV1  `json:"sweeping,omitempty"`
+	Automatic *automaticV1 `json:"automatic,omitempty"`
+}
+
+type automaticV1 struct {
+	ContractID            string          `json:"contractId"`
+	ContractDebtor        debtorV1        `json:"contractDebtor"`
+	Interval              Interval        `json:"interval"`
+	ReferenceStartDate    timex.Date      `json:"referenceStartDate"`
+	FixedAmount           string          `json:"fixedAmount,omitempty"`
+	MinimumVariableAmount string          `json:"minimumVariableAmount,omitempty"`
+	MaximumVariableAmount string          `json:"maximumVariableAmount,omitempty"`
+	IsRetryAccepted       bool            `json:"isRetryAccepted"`
+	FirstPayment          *firstPaymentV1 `json:"firstPayment,omitempty"`
+}
+
+type debtorV1 struct {
+	Name     string     `json:"name"`
+	Document documentV1 `json:"document"`
+}
+
+type firstPaymentV1 struct {
+	Type                  payment.Type `json:"type"`
+	Date                  timex.Date   `json:"date"`
+	Currency              string       `json:"currency"`
+	Amount                string       `json:"amount"`
+	RemittanceInformation string       `json:"remittanceInformation,omitempty"`
+	CreditorAccount       accountV1    `json:"creditorAccount"`
+}
+
+func (a automaticV1) toConfiguration() *AutomaticConfiguration {
+	config := &AutomaticConfiguration{
+		ContractID: a.ContractID,
+		ContractDebtor: Debtor{
+			Name:     a.ContractDebtor.Name,
+			Document: a.ContractDebtor.Document.Identification,
+		},
+		Interval:              a.Interval,
+		ReferenceStartDate:    a.ReferenceStartDate,
+		FixedAmount:           a.FixedAmount,
+		MinimumVariableAmount: a.MinimumVariableAmount,
+		MaximumVariableAmount: a.MaximumVariableAmount,
+		IsRetryAccepted:       a.IsRetryAccepted,
+	}
+	if a.FirstPayment != nil {
+		config.FirstPayment = &FirstPayment{
+			Date:                  a.FirstPayment.Date,
+			Amount:                a.FirstPayment.Amount,
+			Currency:              a.FirstPayment.Currency,
+			RemittanceInformation: a.FirstPayment.RemittanceInformation,
+			CreditorAccount:       a.FirstPayment.CreditorAccount.toAccount(),
+		}
+	}
+	return config
+}
+
+func toAutomaticV1(config AutomaticConfiguration) *automaticV1 {
+	rel := defaultUserDocumentRelation
+	if len(config.ContractDebtor.Document) > 11 {
+		rel = defaultBusinessDocumentRelation
+	}
+
+	a := &automaticV1{
+		ContractID: config.ContractID,
+		ContractDebtor: debtorV1{
+			Name: config.ContractDebtor.Name,
+			Document: documentV1{
+				Identification: config.ContractDebtor.Document,
+				Relation:       rel,
+			},
+		},
+		Interval:              config.Interval,
+		ReferenceStartDate:    config.ReferenceStartDate,
+		FixedAmount:           config.FixedAmount,
+		MinimumVariableAmount: config.MinimumVariableAmount,
+		MaximumVariableAmount: config.MaximumVariableAmount,
+		IsRetryAccepted:       config.IsRetryAccepted,
+	}
+	if config.FirstPayment != nil {
+		a.FirstPayment = &firstPaymentV1{
+			Type:                  payment.TypePix,
+			Date:                  config.FirstPayment.Date,
+			Currency:              config.FirstPayment.Currency,
+			Amount:                config.FirstPayment.Amount,
+			RemittanceInformation: config.FirstPayment.RemittanceInformation,
+			CreditorAccount:       toAccountV1(config.FirstPayment.CreditorAccount),
+		}
+	}
+	return a
+}
+
+type sweepingV1 struct {
+	TotalAllowedAmount string `json:"totalAllowedAmount,omitempty"`
+	TransactionLimit   string `json:"transactionLimit,omitempty"`
+	PeriodicLimits     struct {
+		Day   *periodicLimitV1 `json:"day,omitempty"`
+		Week  *periodicLimitV1 `json:"week,omitempty"`
+		Month *periodicLimitV1 `json:"month,omitempty"`
+		Year  *periodicLimitV1 `json:"year,omitempty"`
+	} `json:"periodicLimits"`
+}
+
+type periodicLimitV1 struct {
+	QuantityLimit    int    `json:"quantityLimit,omitempty"`
+	TransactionLimit string `json:"transactionLimit,omitempty"`
+}
+
+func (l *periodicLimitV1) toPeriodicLimit() *PeriodicLimit {
+	if l == nil {
+		return nil
+	}
+	return &PeriodicLimit{
+		QuantityLimit:    l.QuantityLimit,
+		TransactionLimit: l.TransactionLimit,
+	}
+}
+
+func toPeriodicLimitV1(l *PeriodicLimit) *periodicLimitV1 {
+	if l == nil {
+		return nil
+	}
+	return &periodicLimitV1{
+		QuantityLimit:    l.QuantityLimit,
+		TransactionLimit: l.TransactionLimit,
+	}
+}
+
+type accountV1 struct {
+	ISPB   string              `json:"ispb"`
+	Issuer string              `json:"issuer,omitempty"`
+	Number string              `json:"number"`
+	Type   payment.AccountType `json:"accountType"`
+}
+
+func (a accountV1) toAccount() payment.Account {
+	return payment.Account(a)
+}
+
+func toAccountV1(a payment.Account) accountV1 {
+	return accountV1(a)
+}
+
+func (req createConsentRequestV1) validate() error {
+	if req.Data.LoggedUser.Document.Identification == "" ||
+		req.Data.LoggedUser.Document.Relation != defaultUserDocumentRelation {
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid logged user")
+	}
+
+	if req.Data.BusinessEntity != nil && req.Data.BusinessEntity.Document.Relation != defaultBusinessDocumentRelation {
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid business entity")
+	}
+
+	for _, creditor := range req.Data.Creditors {
+		if creditor.CPFCNPJ == "" || creditor.Name == "" {
+			return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "creditor information is missing")
+		}
+	}
+
+	config := req.Data.RecurringConfiguration
+	if config.Sweeping == nil && config.Automatic == nil {
+		return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "recurring configuration is missing")
+	}
+
+	if config.Automatic != nil && config.Automatic.FirstPayment != nil &&
+		config.Automatic.FirstPayment.Type != payment.TypePix {
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid first payment type")
+	}
+
+	return nil
+}
+
+func (req createConsentRequestV1) toConsent(ctx context.Context) Consent {
+	now := timex.DateTimeNow()
+	c := Consent{
+		ID:                    consentID(),
+		Status:                ConsentStatusAwaitingAuthorization,
+		UserCPF:               req.Data.LoggedUser.Document.Identification,
+		StartDateTime:         req.Data.StartDateTime,
+		ExpirationDateTime:    req.Data.ExpirationDateTime,
+		AdditionalInformation: req.Data.AdditionalInformation,
+		ClientID:              ctx.Value(api.CtxKeyClientID).(string),
+		CreationDateTime:      now,
+		StatusUpdateDateTime:  now,
+	}
+
+	// The consent is valid from its creation if no start date time is
+	// informed.
+	if c.StartDateTime.IsZero() {
+		c.StartDateTime = now
+	}
+
+	if req.Data.BusinessEntity != nil {
+		c.BusinessCNPJ = req.Data.BusinessEntity.Document.Identification
+	}
+
+	for _, creditor := range req.Data.Creditors {
+		c.Creditors = append(c.Creditors, payment.Creditor(creditor))
+	}
+
+	if sweeping := req.Data.RecurringConfiguration.Sweeping; sweeping != nil {
+		c.Configuration.Sweeping = &SweepingConfiguration{
+			TotalAllowedAmount: sweeping.TotalAllowedAmount,
+			TransactionLimit:   sweeping.TransactionLimit,
+			PeriodicLimits: PeriodicLimits{
+				Day:   sweeping.PeriodicLimits.Day.toPeriodicLimit(),
+				Week:  sweeping.PeriodicLimits.Week.toPeriodicLimit(),
+				Month: sweeping.PeriodicLimits.Month.toPeriodicLimit(),
+				Year:  sweeping.PeriodicLimits.Year.toPeriodicLimit(),
+			},
+		}
+	}
+
+	if automatic := req.Data.RecurringConfiguration.Automatic; automatic != nil {
+		c.Configuration.Automatic = automatic.toConfiguration()
+	}
+
+	if req.Data.DebtorAccount != nil {
+		acc := req.Data.DebtorAccount.toAccount()
+		c.DebtorAccount = &acc
+	}
+
+	return c
+}
+
+type consentResponseV1 struct {
+	Data struct {
+		ID                     string          `json:"recurringConsentId"`
+		Status                 ConsentStatus   `json:"status"`
+		CreationDateTime       timex.DateTime  `json:"creationDateTime"`
+		StatusUpdateDateTime   timex.DateTime  `json:"statusUpdateDateTime"`
+		StartDateTime          timex.DateTime  `json:"startDateTime"`
+		ExpirationDateTime     *timex.DateTime `json:"expirationDateTime,omitempty"`
+		AdditionalInformation  string          `json:"additionalInformation,omitempty"`
+		LoggedUser             entityV1        `json:"loggedUser"`
+		BusinessEntity         *entityV1       `json:"businessEntity,omitempty"`
+		Creditors              []creditorV1    `json:"creditors"`
+		RecurringConfiguration configurationV1 `json:"recurringConfiguration"`
+		DebtorAccount          *accountV1      `json:"debtorAccount,omitempty"`
+		Rejection              *rejectionV1    `json:"rejection,omitempty"`
+		Revocation             *revocationV1   `json:"revocation,omitempty"`
+	} `json:"data"`
+	Links api.Links `json:"links"`
+	Meta  api.Meta  `json:"meta"`
+}
+
+type rejectionV1 struct {
+	RejectedAt timex.DateTime    `json:"rejectedAt"`
+	Reason     rejectionReasonV1 `json:"reason"`
+}
+
+type rejectionReasonV1 struct {
+	Code   payment.RejectionReasonCode `json:"code"`
+	Detail string                      `json:"detail"`
+}
+
+type revocationV1 struct {
+	RevokedAt   timex.DateTime `json:"revokedAt"`
+	RevokedBy   RevokedBy      `json:"revokedBy"`
+	RevokedFrom RevokedFrom    `json:"revokedFrom"`
+	Reason      struct {
+		Code   RevocationReasonCode `json:"code"`
+		Detail string               `json:"detail"`
+	} `json:"reason"`
+}
+
+func toConsentResponseV1(c Consent, host string) consentResponseV1 {
+	resp := consentResponseV1{
+		Links: api.NewLinks(host + "/open-banking/automatic-payments/v1/recurring-consents/" + c.ID),
+		Meta:  api.NewMeta(),
+	}
+	resp.Data.ID = c.ID
+	resp.Data.Status = c.Status
+	resp.Data.CreationDateTime = c.CreationDateTime
+	resp.Data.StatusUpdateDateTime = c.StatusUpdateDateTime
+	resp.Data.StartDateTime = c.StartDateTime
+	resp.Data.ExpirationDateTime = c.ExpirationDateTime
+	resp.Data.AdditionalInformation = c.AdditionalInformation
+	resp.Data.LoggedUser = entityV1{
+		Document: documentV1{
+			Identification: c.UserCPF,
+			Relation:       defaultUserDocumentRelation,
+		},
+	}
+	if c.BusinessCNPJ != "" {
+		resp.Data.BusinessEntity = &entityV1{
+			Document: documentV1{
+				Identification: c.BusinessCNPJ,
+				Relation:       defaultBusinessDocumentRelation,
+			},
+		}
+	}
+	resp.Data.Creditors = []creditorV1{}
+	for _, creditor := range c.Creditors {
+		resp.Data.Creditors = append(resp.Data.Creditors, creditorV1(creditor))
+	}
+	if sweeping := c.Configuration.Sweeping; sweeping != nil {
+		resp.Data.RecurringConfiguration.Sweeping = &sweepingV1{
+			TotalAllowedAmount: sweeping.TotalAllowedAmount,
+			TransactionLimit:   sweeping.TransactionLimit,
+		}
+		resp.Data.RecurringConfiguration.Sweeping.PeriodicLimits.Day = toPeriodicLimitV1(sweeping.PeriodicLimits.Day)
+		resp.Data.RecurringConfiguration.Sweeping.PeriodicLimits.Week = toPeriodicLimitV1(sweeping.PeriodicLimits.Week)
+		resp.Data.RecurringConfiguration.Sweeping.PeriodicLimits.Month = toPeriodicLimitV1(sweeping.PeriodicLimits.Month)
+		resp.Data.RecurringConfiguration.Sweeping.PeriodicLimits.Year = toPeriodicLimitV1(sweeping.PeriodicLimits.Year)
+	}
+	if c.Configuration.Automatic != nil {
+		resp.Data.RecurringConfiguration.Automatic = toAutomaticV1(*c.Configuration.Automatic)
+	}
+	if c.DebtorAccount != nil {
+		acc := toAccountV1(*c.DebtorAccount)
+		resp.Data.DebtorAccount = &acc
+	}
+	if c.Revocation != nil {
+		resp.Data.Revocation = &revocationV1{
+			RevokedAt:   c.Revocation.At,
+			RevokedBy:   c.Revocation.By,
+			RevokedFrom: c.Revocation.From,
+		}
+		resp.Data.Revocation.Reason.Code = c.Revocation.Reason.Code
+		resp.Data.Revocation.Reason.Detail = c.Revocation.Reason.Detail
+	}
+	if c.RejectionReason != nil {
+		resp.Data.Rejection = &rejectionV1{
+			RejectedAt: c.StatusUpdateDateTime,
+			Reason: rejectionReasonV1{
+				Code:   c.RejectionReason.Code,
+				Detail: c.RejectionReason.Detail,
+			},
+		}
+	}
+
+	return resp
+}
+
+type patchConsentRequestV1 struct {
+	Data struct {
+		Status             ConsentStatus   `json:"status,omitempty"`
+		ExpirationDateTime *timex.DateTime `json:"expirationDateTime,omitempty"`
+		Creditors          []struct {
+			Name string `json:"name"`
+		} `json:"creditors,omitempty"`
+		RecurringConfiguration *struct {
+			Automatic *struct {
+				MaximumVariableAmount string `json:"maximumVariableAmount,omitempty"`
+			} `json:"automatic,omitempty"`
+		} `json:"recurringConfiguration,omitempty"`
+		Revocation *struct {
+			RevokedBy   RevokedBy   `json:"revokedBy"`
+			RevokedFrom RevokedFrom `json:"revokedFrom"`
+			Reason      struct {
+				Code   RevocationReasonCode `json:"code"`
+				Detail string               `json:"detail"`
+			} `json:"reason"`
+		} `json:"revocation,omitempty"`
+	} `json:"data"`
+}
+
+func (req patchConsentRequestV1) validate() error {
+	if req.Data.Status == ConsentStatusRevoked {
+		if req.Data.Revocation == nil {
+			return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "revocation information is missing")
+		}
+
+		switch req.Data.Revocation.RevokedBy {
+		case RevokedByUser, RevokedByInitiator, RevokedByCreditor:
+		default:
+			return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid revokedBy")
+		}
+
+		switch req.Data.Revocation.RevokedFrom {
+		case RevokedFromInitiator, RevokedFromHolder:
+		default:
+			return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid revokedFrom")
+		}
+
+		return nil
+	}
+
+	if req.Data.Status != "" {
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "the status can only be changed to REVOKED")
+	}
+
+	if len(req.Data.Creditors) > 1 {
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "only one creditor can be informed")
+	}
+
+	return nil
+}
+
+func (req patchConsentRequestV1) toRevocation() Revocation {
+	return Revocation{
+		By:   req.Data.Revocation.RevokedBy,
+		From: req.Data.Revocation.RevokedFrom,
+		Reason: RevocationReason{
+			Code:   req.Data.Revocation.Reason.Code,
+			Detail: req.Data.Revocation.Reason.Detail,
+		},
+	}
+}
+
+func (req patchConsentRequestV1) toEdition() Edition {
+	edition := Edition{
+		ExpirationDateTime: req.Data.ExpirationDateTime,
+	}
+	if len(req.Data.Creditors) == 1 {
+		edition.CreditorName = req.Data.Creditors[0].Name
+	}
+	if config := req.Data.RecurringConfiguration; config != nil && config.Automatic != nil {
+		edition.MaximumVariableAmount = config.Automatic.MaximumVariableAmount
+	}
+	return edition
+}
+
+type createPaymentRequestV1 struct {
+	Data struct {
+		EndToEndID                string                  `json:"endToEndId"`
+		Date                      timex.Date              `json:"date"`
+		Payment                   amountV1                `json:"payment"`
+		CreditorAccount           accountV1               `json:"creditorAccount"`
+		RemittanceInformation     string                  `json:"remittanceInformation,omitempty"`
+		CNPJInitiator             string                  `json:"cnpjInitiator"`
+		IBGETownCode              string                  `json:"ibgeTownCode,omitempty"`
+		AuthorisationFlow         string                  `json:"authorisationFlow,omitempty"`
+		ConsentID                 string                  `json:"recurringConsentId"`
+		Proxy                     string                  `json:"proxy,omitempty"`
+		TransactionIdentification string                  `json:"transactionIdentification,omitempty"`
+		LocalInstrument           payment.LocalInstrument `json:"localInstrument"`
+		Document                  documentV1              `json:"document"`
+		OriginalPaymentID         string                  `json:"originalRecurringPaymentId,omitempty"`
+	} `json:"data"`
+}
+
+type amountV1 struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func (req createPaymentRequestV1) validate(consentID string) error {
+	if req.Data.ConsentID != consentID {
+		return api.NewError("CONSENTIMENTO_INVALIDO", http.StatusUnprocessableEntity, "the consent id does not match the token")
+	}
+
+	if req.Data.CNPJInitiator == "" {
+		return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "cnpjInitiator is required")
+	}
+
+	if req.Data.Document.Identification == "" {
+		return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "the creditor document is required")
+	}
+
+	return nil
+}
+
+func (req createPaymentRequestV1) toPayment(ctx context.Context) Payment {
+	now := timex.DateTimeNow()
+	return Payment{
+		ID:                        paymentID(),
+		EndToEndID:                req.Data.EndToEndID,
+		ConsentID:                 req.Data.ConsentID,
+		Status:                    payment.StatusRCVD,
+		Date:                      req.Data.Date,
+		Amount:                    req.Data.Payment.Amount,
+		Currency:                  req.Data.Payment.Currency,
+		LocalInstrument:           req.Data.LocalInstrument,
+		CreditorDocument:          req.Data.Document.Identification,
+		CreditorAccount:           req.Data.CreditorAccount.toAccount(),
+		Proxy:                     req.Data.Proxy,
+		IBGETownCode:              req.Data.IBGETownCode,
+		CNPJInitiator:             req.Data.CNPJInitiator,
+		TransactionIdentification: req.Data.TransactionIdentification,
+		RemittanceInformation:     req.Data.RemittanceInformation,
+		AuthorisationFlow:         req.Data.AuthorisationFlow,
+		OriginalPaymentID:         req.Data.OriginalPaymentID,
+		ClientID:                  ctx.Value(api.CtxKeyClientID).(string),
+		CreationDateTime:          now,
+		StatusUpdateDateTime:      now,
+	}
+}
+
+type paymentResponseV1 struct {
+	Data  paymentResponseDataV1 `json:"data"`
+	Links api.Links             `json:"links"`
+	Meta  api.Meta              `json:"meta"`
+}
+
+type paymentsResponseV1 struct {
+	Data  []paymentResponseDataV1 `json:"data"`
+	Links api.Links               `json:"links"`
+	Meta  api.Meta                `json:"meta"`
+}
+
+type paymentResponseDataV1 struct {
+	ID                        string                  `json:"recurringPaymentId"`
+	ConsentID                 string                  `json:"recurringConsentId"`
+	EndToEndID                string                  `json:"endToEndId"`
+	Date                      timex.Date              `json:"date"`
+	CreationDateTime          timex.DateTime          `json:"creationDateTime"`
+	StatusUpdateDateTime      timex.DateTime          `json:"statusUpdateDateTime"`
+	Status                    payment.Status          `json:"status"`
+	RejectionReason           *rejectionReasonV1      `json:"rejectionReason,omitempty"`
+	Payment                   amountV1                `json:"payment"`
+	LocalInstrument           payment.LocalInstrument `json:"localInstrument"`
+	Proxy                     string                  `json:"proxy,omitempty"`
+	IBGETownCode              string                  `json:"ibgeTownCode,omitempty"`
+	CNPJInitiator             string                  `json:"cnpjInitiator"`
+	TransactionIdentification string                  `json:"transactionIdentification,omitempty"`
+	RemittanceInformation     string                  `json:"remittanceInformation,omitempty"`
+	AuthorisationFlow         string                  `json:"authorisationFlow,omitempty"`
+	CreditorAccount           accountV1               `json:"creditorAccount"`
+	DebtorAccount             *accountV1              `json:"debtorAccount,omitempty"`
+	Document                  documentV1              `json:"document"`
+	OriginalPaymentID         string                  `json:"originalRecurringPaymentId,omitempty"`
+	Cancellation              *cancellationV1         `json:"cancellation,omitempty"`
+}
+
+type cancellationV1 struct {
+	Reason        payment.CancellationReason `json:"reason"`
+	CancelledFrom payment.CancelledFrom      `json:"cancelledFrom"`
+	CancelledAt   timex.DateTime             `json:"cancelledAt"`
+	CancelledBy   entityV1                   `json:"cancelledBy"`
+}
+
+func toPaymentResponseV1(p Payment, host string) paymentResponseV1 {
+	return paymentResponseV1{
+		Data:  toPaymentResponseDataV1(p),
+		Links: api.NewLinks(host + "/open-banking/automatic-payments/v1/pix/recurring-payments/" + p.ID),
+		Meta:  api.NewMeta(),
+	}
+}
+
+func toPaymentsResponseV1(ps []Payment, self string) paymentsResponseV1 {
+	resp := paymentsResponseV1{
+		Data:  []paymentResponseDataV1{},
+		Links: api.NewLinks(self),
+		Meta:  api.NewMeta(),
+	}
+	for _, p := range ps {
+		resp.Data = append(resp.Data, toPaymentResponseDataV1(p))
+	}
+	return resp
+}
+
+func toPaymentResponseDataV1(p Payment) paymentResponseDataV1 {
+	rel := defaultUserDocumentRelation
+	if len(p.CreditorDocument) > 11 {
+		rel = defaultBusinessDocumentRelation
+	}
+
+	data := paymentResponseDataV1{
+		ID:                   p.ID,
+		ConsentID:            p.ConsentID,
+		EndToEndID:           p.EndToEndID,
+		Date:                 p.Date,
+		CreationDateTime:     p.CreationDateTime,
+		StatusUpdateDateTime: p.StatusUpdateDateTime,
+		Status:               p.Status,
+		Payment: amountV1{
+			Amount:   p.Amount,
+			Currency: p.Currency,
+		},
+		LocalInstrument:           p.LocalInstrument,
+		Proxy:                     p.Proxy,
+		IBGETownCode:              p.IBGETownCode,
+		CNPJInitiator:             p.CNPJInitiator,
+		TransactionIdentification: p.TransactionIdentification,
+		RemittanceInformation:     p.RemittanceInformation,
+		AuthorisationFlow:         p.AuthorisationFlow,
+		CreditorAccount:           toAccountV1(p.CreditorAccount),
+		Document: documentV1{
+			Identification: p.CreditorDocument,
+			Relation:       rel,
+		},
+		OriginalPaymentID: p.OriginalPaymentID,
+	}
+
+	if p.Cancellation != nil {
+		data.Cancellation = &cancellationV1{
+			Reason:        p.Cancellation.Reason,
+			CancelledFrom: p.Cancellation.From,
+			CancelledAt:   p.Cancellation.At,
+			CancelledBy: entityV1{
+				Document: documentV1{
+					Identification: p.Cancellation.ByDocument,
+					Relation:       defaultUserDocumentRelation,
+				},
+			},
+		}
+	}
+
+	if p.DebtorAccount != nil {
+		acc := toAccountV1(*p.DebtorAccount)
+		data.DebtorAccount = &acc
+	}
+
+	if p.RejectionReason != nil {
+		data.RejectionReason = &rejectionReasonV1{
+			Code:   p.RejectionReason.Code,
+			Detail: p.RejectionReason.Detail,
+		}
+	}
+
+	return data
+}
+
+func writeErrorV1(w http.ResponseWriter, err error) {
+	if errors.Is(err, errAccessNotAllowed) {
+		api.WriteError(w, api.NewError("FORBIDDEN", http.StatusForbidden, errAccessNotAllowed.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidConfiguration) || errors.Is(err, errInvalidCreditor) ||
+		errors.Is(err, errInvalidAmount) || errors.Is(err, errInvalidCurrency) || errors.Is(err, errInvalidEndToEndID) {
+		api.WriteError(w, api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, err.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidDate) {
+		api.WriteError(w, api.NewError("DATA_PAGAMENTO_INVALIDA", http.StatusUnprocessableEntity, errInvalidDate.Error()))
+		return
+	}
+
+	if errors.Is(err, errConsentNotActive) {
+		api.WriteError(w, api.NewError("CONSENTIMENTO_INVALIDO", http.StatusUnprocessableEntity, errConsentNotActive.Error()))
+		return
+	}
+
+	if errors.Is(err, errDivergentPayment) {
+		api.WriteError(w, api.NewError("PAGAMENTO_DIVERGENTE_CONSENTIMENTO", http.StatusUnprocessableEntity, errDivergentPayment.Error()))
+		return
+	}
+
+	if errors.Is(err, errTransactionLimitExceeded) {
+		api.WriteError(w, api.NewError("LIMITE_VALOR_TRANSACAO_CONSENTIMENTO_EXCEDIDO", http.StatusUnprocessableEntity, errTransactionLimitExceeded.Error()))
+		return
+	}
+
+	if errors.Is(err, errTotalLimitExceeded) {
+		api.WriteError(w, api.NewError("LIMITE_VALOR_TOTAL_CONSENTIMENTO_EXCEDIDO", http.StatusUnprocessableEntity, errTotalLimitExceeded.Error()))
+		return
+	}
+
+	if errors.Is(err, errPeriodAmountLimitExceeded) {
+		api.WriteError(w, api.NewError("LIMITE_PERIODO_VALOR_EXCEDIDO", http.StatusUnprocessableEntity, errPeriodAmountLimitExceeded.Error()))
+		return
+	}
+
+	if errors.Is(err, errPeriodQuantityLimitExceeded) {
+		api.WriteError(w, api.NewError("LIMITE_PERIODO_QUANTIDADE_EXCEDIDO", http.StatusUnprocessableEntity, errPeriodQuantityLimitExceeded.Error()))
+		return
+	}
+
+	if errors.Is(err, errRetryNotAllowed) {
+		api.WriteError(w, api.NewError("DETALHE_TENTATIVA_INVALIDO", http.StatusUnprocessableEntity, errRetryNotAllowed.Error()))
+		return
+	}
+
+	if errors.Is(err, errRetryLimitExceeded) {
+		api.WriteError(w, api.NewError("LIMITE_TENTATIVAS_EXCEDIDO", http.StatusUnprocessableEntity, errRetryLimitExceeded.Error()))
+		return
+	}
+
+	if errors.Is(err, errRetryOutOfWindow) {
+		api.WriteError(w, api.NewError("FORA_PRAZO_PERMITIDO", http.StatusUnprocessableEntity, errRetryOutOfWindow.Error()))
+		return
+	}
+
+	if errors.Is(err, errConsentCannotBeModified) {
+		api.WriteError(w, api.NewError("CONSENTIMENTO_INVALIDO", http.StatusUnprocessableEntity, errConsentCannotBeModified.Error()))
+		return
+	}
+
+	if errors.Is(err, errFieldNotAllowed) {
+		api.WriteError(w, api.NewError("CAMPO_NAO_PERMITIDO", http.StatusUnprocessableEntity, errFieldNotAllowed.Error()))
+		return
+	}
+
+	var apiErr api.Error
+	if errors.As(err, &apiErr) {
+		api.WriteError(w, apiErr)
+		return
+	}
+
+	api.WriteError(w, errBadRequest)
+}
@@ -0,0 +1,302 @@
+package autopayment
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/payment"
+	"github.com/luikyv/go-open-finance/internal/spi"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+const (
+	maxTimeAwaitingAuthorizationSecs = 300
+	defaultUserDocumentRelation      = "CPF"
+	defaultBusinessDocumentRelation  = "CNPJ"
+	// cnpjRootLength is the number of digits of a CNPJ that identify the
+	// company regardless of its branch.
+	cnpjRootLength = 8
+	// Automatic payments must be scheduled by the initiator between
+	// minScheduleDays and maxScheduleDays before their date.
+	minScheduleDays = 2
+	maxScheduleDays = 10
+	// Failed automatic payments can be retried up to maxRetries times within
+	// maxRetryDays after their original date.
+	maxRetries   = 3
+	maxRetryDays = 7
+)
+
+var (
+	ScopeConsentID = goidc.NewDynamicScope("recurring-consent", func(requestedScope string) bool {
+		return strings.HasPrefix(requestedScope, "recurring-consent:")
+	})
+	Scope = goidc.NewScope("recurring-payments")
+)
+
+type Consent struct {
+	ID                    string                   `bson:"_id"`
+	Status                ConsentStatus            `bson:"status"`
+	UserCPF               string                   `bson:"user_cpf"`
+	BusinessCNPJ          string                   `bson:"business_cnpj,omitempty"`
+	Creditors             []payment.Creditor       `bson:"creditors"`
+	StartDateTime         timex.DateTime           `bson:"start_at"`
+	ExpirationDateTime    *timex.DateTime          `bson:"expires_at,omitempty"`
+	AdditionalInformation string                   `bson:"additional_information,omitempty"`
+	Configuration         Configuration            `bson:"configuration"`
+	DebtorAccount         *payment.Account         `bson:"debtor_account,omitempty"`
+	RejectionReason       *payment.RejectionReason `bson:"rejection,omitempty"`
+	Revocation            *Revocation              `bson:"revocation,omitempty"`
+
+	ClientID             string         `bson:"client_id"`
+	CreationDateTime     timex.DateTime `bson:"created_at"`
+	StatusUpdateDateTime timex.DateTime `bson:"status_updated_at"`
+}
+
+// AuthExpirationDateTime is the date time until which the consent can be
+// authorized by the user.
+func (c Consent) AuthExpirationDateTime() timex.DateTime {
+	return timex.NewDateTime(c.CreationDateTime.Add(timex.Second * maxTimeAwaitingAuthorizationSecs))
+}
+
+// HasAuthExpired returns true if the status is [ConsentStatusAwaitingAuthorization]
+// and the max time awaiting authorization has elapsed.
+func (c Consent) HasAuthExpired() bool {
+	return c.IsAwaitingAuthorization() && timex.Now().After(c.AuthExpirationDateTime().Time)
+}
+
+// IsActive returns true if the consent is authorized and payments can be made
+// at the current time.
+func (c Consent) IsActive() bool {
+	now := timex.Now()
+	if c.ExpirationDateTime != nil && now.After(c.ExpirationDateTime.Time) {
+		return false
+	}
+	return c.IsAuthorized() && !now.Before(c.StartDateTime.Time)
+}
+
+func (c Consent) IsAuthorized() bool {
+	return c.Status == ConsentStatusAuthorized
+}
+
+func (c Consent) IsAwaitingAuthorization() bool {
+	return c.Status == ConsentStatusAwaitingAuthorization
+}
+
+// HasCreditor returns true if document identifies one of the creditors the
+// consent allows payments to.
+func (c Consent) HasCreditor(document string) bool {
+	for _, creditor := range c.Creditors {
+		if creditor.CPFCNPJ == document {
+			return true
+		}
+	}
+	return false
+}
+
+type ConsentStatus string
+
+const (
+	ConsentStatusAwaitingAuthorization ConsentStatus = "AWAITING_AUTHORISATION"
+	ConsentStatusAuthorized            ConsentStatus = "AUTHORISED"
+	ConsentStatusRejected              ConsentStatus = "REJECTED"
+	ConsentStatusRevoked               ConsentStatus = "REVOKED"
+)
+
+// CanBeRevoked returns true if the consent still allows payments to be
+// initiated with it.
+func (c Consent) CanBeRevoked() bool {
+	return c.IsAuthorized()
+}
+
+type Revocation struct {
+	By     RevokedBy        `bson:"by"`
+	From   RevokedFrom      `bson:"from"`
+	Reason RevocationReason `bson:"reason"`
+	At     timex.DateTime   `bson:"at"`
+}
+
+type RevokedBy string
+
+const (
+	RevokedByUser      RevokedBy = "USUARIO"
+	RevokedByInitiator RevokedBy = "INICIADORA"
+	RevokedByCreditor  RevokedBy = "RECEBEDOR"
+)
+
+type RevokedFrom string
+
+const (
+	RevokedFromInitiator RevokedFrom = "INICIADORA"
+	RevokedFromHolder    RevokedFrom = "DETENTORA"
+)
+
+type RevocationReason struct {
+	Code   RevocationReasonCode `bson:"code"`
+	Detail string               `bson:"detail"`
+}
+
+type RevocationReasonCode string
+
+const (
+	RevocationReasonCodeRevokedByUser RevocationReasonCode = "REVOGADO_USUARIO"
+	RevocationReasonCodeNotInformed   RevocationReasonCode = "NAO_INFORMADO"
+)
+
+// Configuration defines the kind of recurring payments the consent allows.
+// Only one of its fields is informed.
+type Configuration struct {
+	Sweeping  *SweepingConfiguration  `bson:"sweeping,omitempty"`
+	Automatic *AutomaticConfiguration `bson:"automatic,omitempty"`
+}
+
+// SweepingConfiguration allows transfers between accounts of the same owner.
+// All the amounts are optional, when informed, the payments are limited by
+// them.
+type SweepingConfiguration struct {
+	TotalAllowedAmount string         `bson:"total_allowed_amount,omitempty"`
+	TransactionLimit   string         `bson:"transaction_limit,omitempty"`
+	PeriodicLimits     PeriodicLimits `bson:"periodic_limits"`
+}
+
+type PeriodicLimits struct {
+	Day   *PeriodicLimit `bson:"day,omitempty"`
+	Week  *PeriodicLimit `bson:"week,omitempty"`
+	Month *PeriodicLimit `bson:"month,omitempty"`
+	Year  *PeriodicLimit `bson:"year,omitempty"`
+}
+
+// PeriodicLimit restricts the number of payments and the amount paid within
+// a period. Zero values mean no restriction.
+type PeriodicLimit struct {
+	QuantityLimit    int    `bson:"quantity_limit,omitempty"`
+	TransactionLimit string `bson:"transaction_limit,omitempty"`
+}
+
+// AutomaticConfiguration allows the creditor to charge the user periodically
+// based on a contract (Pix Automático). The amount is either fixed or variable
+// up to a maximum.
+type AutomaticConfiguration struct {
+	ContractID            string        `bson:"contract_id"`
+	ContractDebtor        Debtor        `bson:"contract_debtor"`
+	Interval              Interval      `bson:"interval"`
+	ReferenceStartDate    timex.Date    `bson:"reference_start_date"`
+	FixedAmount           string        `bson:"fixed_amount,omitempty"`
+	MinimumVariableAmount string        `bson:"minimum_variable_amount,omitempty"`
+	MaximumVariableAmount string        `bson:"maximum_variable_amount,omitempty"`
+	IsRetryAccepted       bool          `bson:"is_retry_accepted"`
+	FirstPayment          *FirstPayment `bson:"first_payment,omitempty"`
+}
+
+// Debtor is who owes the amounts charged by the contract. It may differ from
+// the owner of the account.
+type Debtor struct {
+	Name     string `bson:"name"`
+	Document string `bson:"document"`
+}
+
+// FirstPayment is a payment made right after the consent is authorized and
+// before the periodic charges start, e.g. an adhesion fee.
+type FirstPayment struct {
+	Date                  timex.Date      `bson:"date"`
+	Amount                string          `bson:"amount"`
+	Currency              string          `bson:"currency"`
+	RemittanceInformation string          `bson:"remittance_information,omitempty"`
+	CreditorAccount       payment.Account `bson:"creditor_account"`
+}
+
+// Interval is the period between two automatic payments.
+type Interval string
+
+const (
+	IntervalWeekly     Interval = "SEMANAL"
+	IntervalMonthly    Interval = "MENSAL"
+	IntervalQuarterly  Interval = "TRIMESTRAL"
+	IntervalSemiannual Interval = "SEMESTRAL"
+	IntervalAnnual     Interval = "ANUAL"
+)
+
+func (i Interval) isValid() bool {
+	switch i {
+	case IntervalWeekly, IntervalMonthly, IntervalQuarterly, IntervalSemiannual, IntervalAnnual:
+		return true
+	default:
+		return false
+	}
+}
+
+// period returns the index of the period date belongs to counting from start.
+func (i Interval) period(start, date timex.Date) int {
+	months := (date.Year()-start.Year())*12 + int(date.Month()-start.Month())
+	switch i {
+	case IntervalWeekly:
+		return int(date.Sub(start.Time).Hours()/24) / 7
+	case IntervalMonthly:
+		return months
+	case IntervalQuarterly:
+		return months / 3
+	case IntervalSemiannual:
+		return months / 6
+	default:
+		return months / 12
+	}
+}
+
+type Payment struct {
+	ID                        string                   `bson:"_id"`
+	EndToEndID                string                   `bson:"end_to_end_id"`
+	ConsentID                 string                   `bson:"consent_id"`
+	Status                    payment.Status           `bson:"status"`
+	UserCPF                   string                   `bson:"user_cpf"`
+	BusinessCNPJ              string                   `bson:"business_cnpj,omitempty"`
+	Date                      timex.Date               `bson:"date"`
+	Amount                    string                   `bson:"amount"`
+	Currency                  string                   `bson:"currency"`
+	LocalInstrument           payment.LocalInstrument  `bson:"local_instrument"`
+	CreditorDocument          string                   `bson:"creditor_document"`
+	CreditorAccount           payment.Account          `bson:"creditor_account"`
+	DebtorAccount             *payment.Account         `bson:"debtor_account,omitempty"`
+	Proxy                     string                   `bson:"proxy,omitempty"`
+	IBGETownCode              string                   `bson:"ibge_town_code,omitempty"`
+	CNPJInitiator             string                   `bson:"cnpj_initiator"`
+	TransactionIdentification string                   `bson:"transaction_identification,omitempty"`
+	RemittanceInformation     string                   `bson:"remittance_information,omitempty"`
+	AuthorisationFlow         string                   `bson:"authorisation_flow,omitempty"`
+	RejectionReason           *payment.RejectionReason `bson:"rejection,omitempty"`
+	Cancellation              *payment.Cancellation    `bson:"cancellation,omitempty"`
+	// OriginalPaymentID is informed when the payment is a retry of a payment
+	// that failed.
+	OriginalPaymentID string `bson:"original_payment_id,omitempty"`
+	// Settlement is the outcome of the transfer sent to the SPI once the
+	// payment is accepted.
+	Settlement *spi.Settlement `bson:"settlement,omitempty"`
+
+	ClientID             string         `bson:"client_id"`
+	CreationDateTime     timex.DateTime `bson:"created_at"`
+	StatusUpdateDateTime timex.DateTime `bson:"status_updated_at"`
+}
+
+// IsScheduled returns true if the payment must only be executed at a future
+// date.
+func (p Payment) IsScheduled() bool {
+	return p.Date.After(timex.DateNow().Time)
+}
+
+func (p Payment) IsRetry() bool {
+	return p.OriginalPaymentID != ""
+}
+
+// CountsTowardsLimits returns true if the payment must be considered when
+// verifying the limits of the consent.
+func (p Payment) CountsTowardsLimits() bool {
+	return p.Status != payment.StatusRJCT && p.Status != payment.StatusCANC
+}
+
+func consentID() string {
+	return fmt.Sprintf("urn:mockbank:%s", uuid.NewString())
+}
+
+func paymentID() string {
+	return uuid.NewString()
+}
@@ -0,0 +1,911 @@
+package autopayment
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/luikyv/go-open-finance/internal/account"
+	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/money"
+	"github.com/luikyv/go-open-finance/internal/payment"
+	"github.com/luikyv/go-open-finance/internal/risk"
+	"github.com/luikyv/go-open-finance/internal/spi"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+var (
+	errAccessNotAllowed            = errors.New("access to recurring consent is not allowed")
+	errInvalidConfiguration        = errors.New("the recurring configuration is invalid")
+	errInvalidCreditor             = errors.New("the creditors must be the owners of the consent")
+	errInvalidDate                 = errors.New("the date is invalid")
+	errInvalidAmount               = errors.New("the payment amount is invalid")
+	errInvalidCurrency             = errors.New("the payment currency is invalid")
+	errInvalidEndToEndID           = errors.New("the end to end id is invalid")
+	errConsentAlreadyRejected      = errors.New("the recurring consent is already rejected")
+	errConsentNotActive            = errors.New("the recurring consent does not allow payments")
+	errDivergentPayment            = errors.New("the payment information diverges from the consent")
+	errTransactionLimitExceeded    = errors.New("the payment amount exceeds the transaction limit")
+	errTotalLimitExceeded          = errors.New("the payment amount exceeds the total allowed amount")
+	errPeriodAmountLimitExceeded   = errors.New("the payment amount exceeds the limit for the period")
+	errPeriodQuantityLimitExceeded = errors.New("the number of payments exceeds the limit for the period")
+	errRetryNotAllowed             = errors.New("the payment cannot be retried")
+	errRetryLimitExceeded          = errors.New("the payment was already retried the maximum number of times")
+	errRetryOutOfWindow            = errors.New("the retry date is out of the allowed window")
+	errConsentCannotBeModified     = errors.New("the recurring consent cannot be modified in its current status")
+	errFieldNotAllowed             = errors.New("the field cannot be edited for the recurring consent")
+
+	endToEndIDPattern = regexp.MustCompile(`^E\d{8}(\d{12})[a-zA-Z0-9]{11}$`)
+)
+
+// ConsentID extracts the recurring consent ID from the scopes.
+func ConsentID(scopes string) (string, bool) {
+	for _, s := range strings.Split(scopes, " ") {
+		if ScopeConsentID.Matches(s) {
+			return strings.Replace(s, "recurring-consent:", "", 1), true
+		}
+	}
+	return "", false
+}
+
+type Service struct {
+	storage Storage
+	// spiClient settles the payments.
+	spiClient spi.Client
+	// accountService debits the accounts of the users once their payments
+	// are settled.
+	accountService account.Service
+	// riskService evaluates the limits and fraud rules of the users before
+	// their payments are accepted.
+	riskService risk.Service
+}
+
+func NewService(st Storage, spiClient spi.Client, accountService account.Service, riskService risk.Service) Service {
+	return Service{
+		storage:        st,
+		spiClient:      spiClient,
+		accountService: accountService,
+		riskService:    riskService,
+	}
+}
+
+func (s Service) Authorize(ctx context.Context, c Consent) error {
+
+	slog.DebugContext(ctx, "trying to authorize recurring consent", slog.String("consent_id", c.ID))
+
+	if !c.IsAwaitingAuthorization() {
+		slog.DebugContext(ctx, "cannot authorize a recurring consent that is not awaiting authorization", slog.Any("status", c.Status))
+		return errors.New("invalid consent status")
+	}
+
+	slog.InfoContext(ctx, "authorizing recurring consent", slog.String("consent_id", c.ID))
+	c.Status = ConsentStatusAuthorized
+	c.StatusUpdateDateTime = timex.DateTimeNow()
+	return s.saveConsent(ctx, c)
+}
+
+func (s Service) Consent(ctx context.Context, id string) (Consent, error) {
+	c, err := s.storage.consent(ctx, id)
+	if err != nil {
+		return Consent{}, err
+	}
+
+	if ctx.Value(api.CtxKeyClientID) != nil && ctx.Value(api.CtxKeyClientID) != c.ClientID {
+		return Consent{}, errAccessNotAllowed
+	}
+
+	if err := s.modifyConsent(ctx, &c); err != nil {
+		return Consent{}, err
+	}
+
+	return c, nil
+}
+
+func (s Service) Reject(ctx context.Context, id string, reason payment.RejectionReason) error {
+	c, err := s.Consent(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if c.Status == ConsentStatusRejected {
+		return errConsentAlreadyRejected
+	}
+
+	c.Status = ConsentStatusRejected
+	c.StatusUpdateDateTime = timex.DateTimeNow()
+	c.RejectionReason = &reason
+	return s.saveConsent(ctx, c)
+}
+
+func (s Service) createConsent(ctx context.Context, c Consent) error {
+	if err := validateConsent(c); err != nil {
+		return err
+	}
+
+	return s.saveConsent(ctx, c)
+}
+
+// modifyConsent will evaluate the consent information and modify it to be
+// compliant.
+func (s Service) modifyConsent(ctx context.Context, c *Consent) error {
+	// Reject the consent if the time awaiting the user authorization has elapsed.
+	if c.HasAuthExpired() {
+		slog.DebugContext(ctx, "recurring consent awaiting authorization for too long, moving to rejected")
+		c.Status = ConsentStatusRejected
+		c.RejectionReason = &payment.RejectionReason{
+			Code:   payment.RejectionReasonCodeAuthorizationTimeExpired,
+			Detail: "the consent was not authorized in time",
+		}
+		c.StatusUpdateDateTime = timex.DateTimeNow()
+		return s.saveConsent(ctx, *c)
+	}
+
+	return nil
+}
+
+func (s Service) saveConsent(ctx context.Context, c Consent) error {
+	return s.storage.saveConsent(ctx, c)
+}
+
+func (s Service) create(ctx context.Context, p Payment) (Payment, error) {
+	c, err := s.Consent(ctx, p.ConsentID)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	if !c.IsActive() {
+		return Payment{}, errConsentNotActive
+	}
+
+	if err := validatePayment(p, c); err != nil {
+		return Payment{}, err
+	}
+
+	ps, err := s.storage.payments(ctx, c.ID)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	switch {
+	case c.Configuration.Sweeping != nil:
+		err = validateSweepingPayment(p, ps, *c.Configuration.Sweeping)
+	case c.Configuration.Automatic != nil:
+		err = validateAutomaticPayment(p, ps, *c.Configuration.Automatic)
+	}
+	if err != nil {
+		return Payment{}, err
+	}
+
+	p.UserCPF = c.UserCPF
+	p.BusinessCNPJ = c.BusinessCNPJ
+	p.DebtorAccount = c.DebtorAccount
+	if err := s.savePayment(ctx, p); err != nil {
+		return Payment{}, err
+	}
+
+	return p, nil
+}
+
+// revoke stops the consent from being used and cancels the payments
+// scheduled with it.
+func (s Service) revoke(ctx context.Context, id string, revocation Revocation) (Consent, error) {
+	c, err := s.Consent(ctx, id)
+	if err != nil {
+		return Consent{}, err
+	}
+
+	if !c.CanBeRevoked() {
+		return Consent{}, errConsentCannotBeModified
+	}
+
+	ps, err := s.storage.payments(ctx, c.ID)
+	if err != nil {
+		return Consent{}, err
+	}
+
+	cancelledFrom := payment.CancelledFromInitiator
+	if revocation.From == RevokedFromHolder {
+		cancelledFrom = payment.CancelledFromHolder
+	}
+	for _, p := range ps {
+		if p.Status != payment.StatusRCVD && p.Status != payment.StatusSCHD {
+			continue
+		}
+
+		slog.InfoContext(ctx, "cancelling recurring payment of revoked consent", slog.String("payment_id", p.ID))
+		previous := p.Status
+		p.Status = payment.StatusCANC
+		p.StatusUpdateDateTime = timex.DateTimeNow()
+		p.Cancellation = &payment.Cancellation{
+			Reason:     payment.CancellationReasonScheduled,
+			From:       cancelledFrom,
+			At:         timex.DateTimeNow(),
+			ByDocument: c.UserCPF,
+		}
+		// Payments moved forward in the meantime are not cancelled.
+		if _, err := s.storage.updatePayment(ctx, p, previous); err != nil {
+			return Consent{}, err
+		}
+	}
+
+	slog.InfoContext(ctx, "revoking recurring consent", slog.String("consent_id", c.ID))
+	revocation.At = timex.DateTimeNow()
+	c.Status = ConsentStatusRevoked
+	c.StatusUpdateDateTime = revocation.At
+	c.Revocation = &revocation
+	if err := s.saveConsent(ctx, c); err != nil {
+		return Consent{}, err
+	}
+
+	return c, nil
+}
+
+// Edition holds the information of an automatic consent that can be changed
+// after it is authorized. Empty fields are not modified.
+type Edition struct {
+	ExpirationDateTime    *timex.DateTime
+	MaximumVariableAmount string
+	CreditorName          string
+}
+
+func (s Service) edit(ctx context.Context, id string, edition Edition) (Consent, error) {
+	c, err := s.Consent(ctx, id)
+	if err != nil {
+		return Consent{}, err
+	}
+
+	if !c.IsAuthorized() {
+		return Consent{}, errConsentCannotBeModified
+	}
+
+	config := c.Configuration.Automatic
+	if config == nil {
+		return Consent{}, errFieldNotAllowed
+	}
+
+	if edition.ExpirationDateTime != nil {
+		if !edition.ExpirationDateTime.After(timex.Now()) {
+			return Consent{}, errInvalidDate
+		}
+		c.ExpirationDateTime = edition.ExpirationDateTime
+	}
+
+	if edition.MaximumVariableAmount != "" {
+		if config.FixedAmount != "" {
+			return Consent{}, errFieldNotAllowed
+		}
+		config.MaximumVariableAmount = edition.MaximumVariableAmount
+	}
+
+	if edition.CreditorName != "" {
+		c.Creditors[0].Name = edition.CreditorName
+	}
+
+	if err := validateAutomaticConfiguration(*config); err != nil {
+		return Consent{}, err
+	}
+
+	slog.InfoContext(ctx, "editing recurring consent", slog.String("consent_id", c.ID))
+	if err := s.saveConsent(ctx, c); err != nil {
+		return Consent{}, err
+	}
+
+	return c, nil
+}
+
+func (s Service) Payment(ctx context.Context, id string) (Payment, error) {
+	p, err := s.storage.payment(ctx, id)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	if ctx.Value(api.CtxKeyClientID) != nil && ctx.Value(api.CtxKeyClientID) != p.ClientID {
+		return Payment{}, errAccessNotAllowed
+	}
+
+	if err := s.modifyPayment(ctx, &p); err != nil {
+		return Payment{}, err
+	}
+
+	return p, nil
+}
+
+// payments returns all the payments made with the consent.
+func (s Service) payments(ctx context.Context, consentID string) ([]Payment, error) {
+	if _, err := s.Consent(ctx, consentID); err != nil {
+		return nil, err
+	}
+
+	ps, err := s.storage.payments(ctx, consentID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ps {
+		if err := s.modifyPayment(ctx, &ps[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return ps, nil
+}
+
+// modifyPayment moves the payment one step forward in its life cycle every
+// time it is evaluated.
+// A step is only applied if the payment was not moved forward in the meantime
+// by a concurrent evaluation, otherwise p is reloaded. The account is only
+// debited or refunded by the evaluation whose step was applied.
+func (s Service) modifyPayment(ctx context.Context, p *Payment) error {
+	previous := p.Status
+	status := p.Status
+	// transfer is sent to the SPI once the payment is moved to ACPD.
+	var transfer *spi.Transfer
+	refund := false
+	switch p.Status {
+	case payment.StatusRCVD:
+		status = payment.StatusACCP
+		if p.IsScheduled() {
+			status = payment.StatusSCHD
+		}
+	case payment.StatusSCHD:
+		if !p.IsScheduled() {
+			status = payment.StatusACCP
+		}
+	case payment.StatusACCP:
+		if code, ok := payment.MockRejection(p.Amount); ok {
+			status = payment.StatusRJCT
+			p.RejectionReason = &payment.RejectionReason{
+				Code:   code,
+				Detail: "the payment was refused by the account holder",
+			}
+			break
+		}
+
+		t, err := s.newTransfer(ctx, *p)
+		if err != nil {
+			return err
+		}
+		status = payment.StatusACPD
+		transfer = &t
+	case payment.StatusACPD:
+		// The payment stays in ACPD until the SPI informs the outcome of the
+		// transfer. Payments without a settlement are still being sent.
+		if p.Settlement == nil || !p.Settlement.IsDue() {
+			break
+		}
+
+		if !p.Settlement.IsSettled() {
+			status = payment.StatusRJCT
+			reason := payment.SettlementRejectionReason(*p.Settlement)
+			p.RejectionReason = &reason
+			refund = true
+			break
+		}
+
+		status = payment.StatusACSC
+	}
+
+	// The limits and fraud rules are evaluated right before the payment is
+	// accepted.
+	if status == payment.StatusACCP {
+		if err := s.evaluateRisk(ctx, *p); err != nil {
+			reason, ok := payment.RiskRejectionReason(err)
+			if !ok {
+				return err
+			}
+			status = payment.StatusRJCT
+			p.RejectionReason = &reason
+		}
+	}
+
+	if status == p.Status {
+		return nil
+	}
+
+	slog.DebugContext(ctx, "updating recurring payment status", slog.String("payment_id", p.ID),
+		slog.Any("from", p.Status), slog.Any("to", status))
+	p.Status = status
+	p.StatusUpdateDateTime = timex.DateTimeNow()
+	if ok, err := s.updatePayment(ctx, p, previous); err != nil || !ok {
+		// The amount counted towards the limits when the recurring payment was
+		// evaluated is given back, since the recurring payment was not accepted.
+		if status == payment.StatusACCP {
+			s.releaseRisk(*p)
+		}
+		return err
+	}
+
+	// Recurring payments rejected after being accepted no longer count towards
+	// the limits of the user.
+	if status == payment.StatusRJCT && (previous == payment.StatusACCP || previous == payment.StatusACPD) {
+		s.releaseRisk(*p)
+	}
+
+	if transfer != nil {
+		return s.send(ctx, p, *transfer)
+	}
+	if refund {
+		s.refund(ctx, *p)
+	}
+	return nil
+}
+
+// updatePayment saves the recurring payment if its status is still previous.
+// Otherwise, p is reloaded with the current state of the payment and false is
+// returned.
+func (s Service) updatePayment(ctx context.Context, p *Payment, previous payment.Status) (bool, error) {
+	ok, err := s.storage.updatePayment(ctx, *p, previous)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	slog.DebugContext(ctx, "the recurring payment was modified concurrently", slog.String("payment_id", p.ID))
+	current, err := s.storage.payment(ctx, p.ID)
+	if err != nil {
+		return false, err
+	}
+	*p = current
+	return false, nil
+}
+
+// evaluateRisk checks the recurring payment against the limits and fraud
+// rules of the user.
+func (s Service) evaluateRisk(ctx context.Context, p Payment) error {
+	var number string
+	if p.DebtorAccount != nil {
+		number = p.DebtorAccount.Number
+	}
+
+	return s.riskService.Evaluate(ctx, risk.Payment{
+		ID:               p.ID,
+		UserCPF:          p.UserCPF,
+		AccountNumber:    number,
+		Amount:           p.Amount,
+		CreditorDocument: p.CreditorDocument,
+		Proxy:            p.Proxy,
+	})
+}
+
+// releaseRisk gives back the amount of the recurring payment to the limits of
+// the user.
+func (s Service) releaseRisk(p Payment) {
+	s.riskService.Release(p.UserCPF, p.ID)
+}
+
+// newTransfer builds the transfer of the recurring payment to the SPI.
+func (s Service) newTransfer(ctx context.Context, p Payment) (spi.Transfer, error) {
+	c, err := s.storage.consent(ctx, p.ConsentID)
+	if err != nil {
+		return spi.Transfer{}, err
+	}
+
+	var creditorName string
+	for _, creditor := range c.Creditors {
+		if creditor.CPFCNPJ == p.CreditorDocument {
+			creditorName = creditor.Name
+		}
+	}
+
+	debtorDocument := p.UserCPF
+	if p.BusinessCNPJ != "" {
+		debtorDocument = p.BusinessCNPJ
+	}
+
+	return spi.Transfer{
+		EndToEndID:            p.EndToEndID,
+		Amount:                p.Amount,
+		Currency:              p.Currency,
+		DebtorDocument:        debtorDocument,
+		DebtorAccount:         payment.TransferDebtorAccount(p.DebtorAccount),
+		CreditorName:          creditorName,
+		CreditorDocument:      p.CreditorDocument,
+		CreditorAccount:       payment.TransferAccount(p.CreditorAccount),
+		Proxy:                 p.Proxy,
+		RemittanceInformation: p.RemittanceInformation,
+	}, nil
+}
+
+// send debits the account of the user and sends the transfer of the recurring
+// payment to the SPI. Payments whose account cannot be debited are rejected
+// without being sent.
+func (s Service) send(ctx context.Context, p *Payment, t spi.Transfer) error {
+	if err := s.debit(ctx, *p); err != nil {
+		slog.InfoContext(ctx, "the account could not be debited, rejecting the recurring payment",
+			slog.String("payment_id", p.ID), slog.String("error", err.Error()))
+		reason := payment.DebitRejectionReason(err)
+		p.RejectionReason = &reason
+		p.Status = payment.StatusRJCT
+		p.StatusUpdateDateTime = timex.DateTimeNow()
+		if ok, err := s.updatePayment(ctx, p, payment.StatusACPD); err != nil || !ok {
+			return err
+		}
+		s.releaseRisk(*p)
+		return nil
+	}
+
+	settlement := s.spiClient.Transfer(ctx, t)
+	p.Settlement = &settlement
+	_, err := s.updatePayment(ctx, p, payment.StatusACPD)
+	return err
+}
+
+// debit withdraws the amount of the recurring payment from the account of the
+// user.
+func (s Service) debit(ctx context.Context, p Payment) error {
+	var number string
+	if p.DebtorAccount != nil {
+		number = p.DebtorAccount.Number
+	}
+
+	return s.accountService.Debit(ctx, p.UserCPF, number, p.Amount, account.TransactionTypePix, "PIX "+p.EndToEndID)
+}
+
+// refund gives the amount of a recurring payment rejected by the SPI back to
+// the user. Failures are only logged, since the payment was already rejected.
+func (s Service) refund(ctx context.Context, p Payment) {
+	var number string
+	if p.DebtorAccount != nil {
+		number = p.DebtorAccount.Number
+	}
+
+	if err := s.accountService.Credit(ctx, p.UserCPF, number, p.Amount, account.TransactionTypePix,
+		"ESTORNO PIX "+p.EndToEndID); err != nil {
+		slog.ErrorContext(ctx, "could not refund the rejected recurring payment", slog.String("payment_id", p.ID),
+			slog.String("error", err.Error()))
+	}
+}
+
+func (s Service) savePayment(ctx context.Context, p Payment) error {
+	return s.storage.savePayment(ctx, p)
+}
+
+func validateConsent(c Consent) error {
+	if c.ExpirationDateTime != nil && !c.ExpirationDateTime.After(c.StartDateTime.Time) {
+		return errInvalidDate
+	}
+
+	switch {
+	case c.Configuration.Sweeping != nil && c.Configuration.Automatic != nil:
+		return errInvalidConfiguration
+	case c.Configuration.Sweeping != nil:
+		return validateSweepingConsent(c)
+	case c.Configuration.Automatic != nil:
+		// Automatic payments are charged by a single creditor.
+		if len(c.Creditors) != 1 {
+			return errInvalidCreditor
+		}
+		return validateAutomaticConfiguration(*c.Configuration.Automatic)
+	default:
+		return errInvalidConfiguration
+	}
+}
+
+func validateSweepingConsent(c Consent) error {
+	if err := validateSweepingConfiguration(*c.Configuration.Sweeping); err != nil {
+		return err
+	}
+
+	if len(c.Creditors) == 0 {
+		return errInvalidCreditor
+	}
+
+	// Sweeping only allows transfers between accounts of the same owner.
+	for _, creditor := range c.Creditors {
+		switch creditor.PersonType {
+		case payment.PersonTypeNatural:
+			if creditor.CPFCNPJ != c.UserCPF {
+				return errInvalidCreditor
+			}
+		case payment.PersonTypeLegal:
+			if len(c.BusinessCNPJ) < cnpjRootLength || len(creditor.CPFCNPJ) < cnpjRootLength ||
+				creditor.CPFCNPJ[:cnpjRootLength] != c.BusinessCNPJ[:cnpjRootLength] {
+				return errInvalidCreditor
+			}
+		default:
+			return errInvalidCreditor
+		}
+	}
+
+	return nil
+}
+
+func validateSweepingConfiguration(config SweepingConfiguration) error {
+	for _, amount := range []string{config.TotalAllowedAmount, config.TransactionLimit} {
+		if err := validateOptionalAmount(amount); err != nil {
+			return err
+		}
+	}
+
+	for _, limit := range []*PeriodicLimit{
+		config.PeriodicLimits.Day,
+		config.PeriodicLimits.Week,
+		config.PeriodicLimits.Month,
+		config.PeriodicLimits.Year,
+	} {
+		if limit == nil {
+			continue
+		}
+
+		if limit.QuantityLimit < 0 {
+			return errInvalidConfiguration
+		}
+
+		if err := validateOptionalAmount(limit.TransactionLimit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateOptionalAmount(amount string) error {
+	if amount == "" {
+		return nil
+	}
+
+	cents, err := money.Parse(amount)
+	if err != nil || cents <= 0 {
+		return errInvalidConfiguration
+	}
+
+	return nil
+}
+
+func validateAutomaticConfiguration(config AutomaticConfiguration) error {
+	if config.ContractID == "" || config.ContractDebtor.Name == "" || config.ContractDebtor.Document == "" {
+		return errInvalidConfiguration
+	}
+
+	if !config.Interval.isValid() {
+		return errInvalidConfiguration
+	}
+
+	// The amount is either fixed or variable.
+	if config.FixedAmount != "" && (config.MinimumVariableAmount != "" || config.MaximumVariableAmount != "") {
+		return errInvalidConfiguration
+	}
+
+	for _, amount := range []string{config.FixedAmount, config.MinimumVariableAmount, config.MaximumVariableAmount} {
+		if err := validateOptionalAmount(amount); err != nil {
+			return err
+		}
+	}
+
+	if config.MinimumVariableAmount != "" && config.MaximumVariableAmount != "" {
+		minAmount, _ := money.Parse(config.MinimumVariableAmount)
+		maxAmount, _ := money.Parse(config.MaximumVariableAmount)
+		if minAmount > maxAmount {
+			return errInvalidConfiguration
+		}
+	}
+
+	if config.FirstPayment != nil {
+		if config.FirstPayment.Currency != payment.DefaultCurrency {
+			return errInvalidCurrency
+		}
+
+		if cents, err := money.Parse(config.FirstPayment.Amount); err != nil || cents <= 0 {
+			return errInvalidAmount
+		}
+	}
+
+	return nil
+}
+
+func validatePayment(p Payment, c Consent) error {
+	matches := endToEndIDPattern.FindStringSubmatch(p.EndToEndID)
+	if matches == nil {
+		return errInvalidEndToEndID
+	}
+
+	// The end to end id carries the date the payment is meant to be settled.
+	if !strings.HasPrefix(matches[1], strings.ReplaceAll(p.Date.String(), "-", "")) {
+		return errInvalidEndToEndID
+	}
+
+	if p.Currency != payment.DefaultCurrency {
+		return errInvalidCurrency
+	}
+
+	if cents, err := money.Parse(p.Amount); err != nil || cents <= 0 {
+		return errInvalidAmount
+	}
+
+	if !c.HasCreditor(p.CreditorDocument) {
+		return errDivergentPayment
+	}
+
+	return nil
+}
+
+// validateSweepingPayment verifies that p respects the limits of the consent
+// considering the payments previously made with it.
+func validateSweepingPayment(p Payment, ps []Payment, config SweepingConfiguration) error {
+	// Sweeping payments are settled on the same day they are requested.
+	if !p.Date.Equal(timex.DateNow().Time) {
+		return errInvalidDate
+	}
+
+	if p.IsRetry() {
+		return errRetryNotAllowed
+	}
+
+	amount, _ := money.Parse(p.Amount)
+
+	if config.TransactionLimit != "" {
+		limit, _ := money.Parse(config.TransactionLimit)
+		if amount > limit {
+			return errTransactionLimitExceeded
+		}
+	}
+
+	var previous []Payment
+	for _, other := range ps {
+		if other.CountsTowardsLimits() {
+			previous = append(previous, other)
+		}
+	}
+
+	if config.TotalAllowedAmount != "" {
+		limit, _ := money.Parse(config.TotalAllowedAmount)
+		if total(previous)+amount > limit {
+			return errTotalLimitExceeded
+		}
+	}
+
+	periods := []struct {
+		limit      *PeriodicLimit
+		samePeriod func(a, b time.Time) bool
+	}{
+		{config.PeriodicLimits.Day, func(a, b time.Time) bool {
+			return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+		}},
+		{config.PeriodicLimits.Week, func(a, b time.Time) bool {
+			aYear, aWeek := a.ISOWeek()
+			bYear, bWeek := b.ISOWeek()
+			return aYear == bYear && aWeek == bWeek
+		}},
+		{config.PeriodicLimits.Month, func(a, b time.Time) bool {
+			return a.Year() == b.Year() && a.Month() == b.Month()
+		}},
+		{config.PeriodicLimits.Year, func(a, b time.Time) bool {
+			return a.Year() == b.Year()
+		}},
+	}
+	for _, period := range periods {
+		if period.limit == nil {
+			continue
+		}
+
+		var inPeriod []Payment
+		for _, other := range previous {
+			if period.samePeriod(other.Date.Time, p.Date.Time) {
+				inPeriod = append(inPeriod, other)
+			}
+		}
+
+		if period.limit.QuantityLimit != 0 && len(inPeriod)+1 > period.limit.QuantityLimit {
+			return errPeriodQuantityLimitExceeded
+		}
+
+		if period.limit.TransactionLimit != "" {
+			limit, _ := money.Parse(period.limit.TransactionLimit)
+			if total(inPeriod)+amount > limit {
+				return errPeriodAmountLimitExceeded
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateAutomaticPayment verifies that p was scheduled within the allowed
+// window and respects the contract of the consent considering the payments
+// previously made with it.
+func validateAutomaticPayment(p Payment, ps []Payment, config AutomaticConfiguration) error {
+	// The first payment is made right after the authorization and must
+	// match exactly what the user agreed with.
+	if config.FirstPayment != nil && len(ps) == 0 {
+		first := config.FirstPayment
+		if p.Amount != first.Amount || p.Currency != first.Currency ||
+			p.CreditorAccount != first.CreditorAccount || !p.Date.Equal(first.Date.Time) {
+			return errDivergentPayment
+		}
+		return nil
+	}
+
+	if p.IsRetry() {
+		return validateRetry(p, ps, config)
+	}
+
+	today := timex.DateNow()
+	if p.Date.Before(today.AddDate(0, 0, minScheduleDays)) || p.Date.After(today.AddDate(0, 0, maxScheduleDays)) {
+		return errInvalidDate
+	}
+
+	if p.Date.Before(config.ReferenceStartDate.Time) {
+		return errInvalidDate
+	}
+
+	amount, _ := money.Parse(p.Amount)
+	if config.FixedAmount != "" && p.Amount != config.FixedAmount {
+		return errDivergentPayment
+	}
+
+	if config.MaximumVariableAmount != "" {
+		maxAmount, _ := money.Parse(config.MaximumVariableAmount)
+		if amount > maxAmount {
+			return errTransactionLimitExceeded
+		}
+	}
+
+	if config.MinimumVariableAmount != "" {
+		minAmount, _ := money.Parse(config.MinimumVariableAmount)
+		if amount < minAmount {
+			return errInvalidAmount
+		}
+	}
+
+	// Only one payment can be charged per interval. Retries and payments
+	// before the reference start date, i.e. the first payment, don't count.
+	period := config.Interval.period(config.ReferenceStartDate, p.Date)
+	for _, other := range ps {
+		if !other.CountsTowardsLimits() || other.IsRetry() || other.Date.Before(config.ReferenceStartDate.Time) {
+			continue
+		}
+
+		if config.Interval.period(config.ReferenceStartDate, other.Date) == period {
+			return errPeriodQuantityLimitExceeded
+		}
+	}
+
+	return nil
+}
+
+// validateRetry verifies that p can retry a payment that failed.
+func validateRetry(p Payment, ps []Payment, config AutomaticConfiguration) error {
+	if !config.IsRetryAccepted {
+		return errRetryNotAllowed
+	}
+
+	var original *Payment
+	retries := 0
+	for _, other := range ps {
+		if other.ID == p.OriginalPaymentID {
+			original = &other
+		}
+		if other.OriginalPaymentID == p.OriginalPaymentID {
+			retries++
+		}
+	}
+
+	if original == nil || original.IsRetry() || original.Status != payment.StatusRJCT {
+		return errRetryNotAllowed
+	}
+
+	if retries >= maxRetries {
+		return errRetryLimitExceeded
+	}
+
+	if p.Date.Before(timex.DateNow().Time) || p.Date.After(original.Date.AddDate(0, 0, maxRetryDays)) {
+		return errRetryOutOfWindow
+	}
+
+	if p.Amount != original.Amount {
+		return errDivergentPayment
+	}
+
+	return nil
+}
+
+func total(ps []Payment) int64 {
+	var sum int64
+	for _, p := range ps {
+		amount, _ := money.Parse(p.Amount)
+		sum += amount
+	}
+	return sum
+}
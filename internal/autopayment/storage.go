@@ -0,0 +1,105 @@
+package autopayment
+
+import (
+	"context"
+
+	"github.com/luikyv/go-open-finance/internal/payment"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Storage struct {
+	consentCollection *mongo.Collection
+	paymentCollection *mongo.Collection
+}
+
+func NewStorage(db *mongo.Database) Storage {
+	return Storage{
+		consentCollection: db.Collection("recurring_consents"),
+		paymentCollection: db.Collection("recurring_payments"),
+	}
+}
+
+func (st Storage) saveConsent(ctx context.Context, c Consent) error {
+	shouldUpsert := true
+	filter := bson.D{{Key: "_id", Value: c.ID}}
+	if _, err := st.consentCollection.ReplaceOne(ctx, filter, c, &options.ReplaceOptions{
+		Upsert: &shouldUpsert,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (st Storage) consent(ctx context.Context, id string) (Consent, error) {
+	filter := bson.D{{Key: "_id", Value: id}}
+	result := st.consentCollection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		return Consent{}, result.Err()
+	}
+
+	var c Consent
+	if err := result.Decode(&c); err != nil {
+		return Consent{}, err
+	}
+
+	return c, nil
+}
+
+func (st Storage) savePayment(ctx context.Context, p Payment) error {
+	shouldUpsert := true
+	filter := bson.D{{Key: "_id", Value: p.ID}}
+	if _, err := st.paymentCollection.ReplaceOne(ctx, filter, p, &options.ReplaceOptions{
+		Upsert: &shouldUpsert,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// updatePayment replaces the recurring payment only if its status is still
+// previous, so concurrent evaluations don't move it forward twice. It returns
+// false if the payment was modified in the meantime.
+func (st Storage) updatePayment(ctx context.Context, p Payment, previous payment.Status) (bool, error) {
+	filter := bson.D{{Key: "_id", Value: p.ID}, {Key: "status", Value: previous}}
+	result, err := st.paymentCollection.ReplaceOne(ctx, filter, p)
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+func (st Storage) payment(ctx context.Context, id string) (Payment, error) {
+	filter := bson.D{{Key: "_id", Value: id}}
+	result := st.paymentCollection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		return Payment{}, result.Err()
+	}
+
+	var p Payment
+	if err := result.Decode(&p); err != nil {
+		return Payment{}, err
+	}
+
+	return p, nil
+}
+
+func (st Storage) payments(ctx context.Context, consentID string) ([]Payment, error) {
+	filter := bson.D{{Key: "consent_id", Value: consentID}}
+	cursor, err := st.paymentCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ps []Payment
+	if err := cursor.All(ctx, &ps); err != nil {
+		return nil, err
+	}
+
+	return ps, nil
+}
@@ -0,0 +1,360 @@
+package bankfixedincome
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-oidc/pkg/provider"
+	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/api/middleware"
+	"github.com/luikyv/go-open-finance/internal/consent"
+	"github.com/luikyv/go-open-finance/internal/mock"
+	"github.com/luikyv/go-open-finance/internal/page"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+type APIRouterV1 struct {
+	host           string
+	service        Service
+	consentService consent.Service
+	op             *provider.Provider
+}
+
+func NewAPIRouterV1(host string, service Service, consentService consent.Service, op *provider.Provider) APIRouterV1 {
+	return APIRouterV1{
+		host:           host,
+		service:        service,
+		consentService: consentService,
+		op:             op,
+	}
+}
+
+func (router APIRouterV1) Register(mux *http.ServeMux) {
+	investmentMux := http.NewServeMux()
+
+	handler := router.getInvestmentsHandler()
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, consent.PermissionBankFixedIncomesRead)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	investmentMux.Handle("GET /open-banking/bank-fixed-incomes/v1/investments", handler)
+
+	handler = router.getInvestmentHandler()
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, consent.PermissionBankFixedIncomesRead)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	investmentMux.Handle("GET /open-banking/bank-fixed-incomes/v1/investments/{id}", handler)
+
+	handler = router.getInvestmentBalancesHandler()
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, consent.PermissionBankFixedIncomesRead)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	investmentMux.Handle("GET /open-banking/bank-fixed-incomes/v1/investments/{id}/balances", handler)
+
+	handler = router.getInvestmentTransactionsHandler(false)
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, consent.PermissionBankFixedIncomesRead)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	investmentMux.Handle("GET /open-banking/bank-fixed-incomes/v1/investments/{id}/transactions", handler)
+
+	handler = router.getInvestmentTransactionsHandler(true)
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, consent.PermissionBankFixedIncomesRead)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	investmentMux.Handle("GET /open-banking/bank-fixed-incomes/v1/investments/{id}/transactions-current", handler)
+
+	handler = investmentMux
+	handler = middleware.Meta(handler, router.host)
+	mux.Handle("/open-banking/bank-fixed-incomes/v1/", handler)
+}
+
+func (router APIRouterV1) getInvestmentsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
+		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
+		pag, err := api.NewPagination(r)
+		if err != nil {
+			writeErrorV1(w, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, err.Error()), true)
+			return
+		}
+
+		invs, err := router.service.investments(r.Context(), consentID, pag)
+		if err != nil {
+			writeErrorV1(w, err, true)
+			return
+		}
+
+		resp := toInvestmentsResponseV1(invs, reqURL)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV1) getInvestmentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
+		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
+		invID := r.PathValue("id")
+
+		inv, err := router.service.investment(r.Context(), invID, consentID)
+		if err != nil {
+			writeErrorV1(w, err, false)
+			return
+		}
+
+		resp := toInvestmentResponseV1(inv, reqURL)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV1) getInvestmentBalancesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
+		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
+		invID := r.PathValue("id")
+
+		balance, err := router.service.balance(r.Context(), invID, consentID)
+		if err != nil {
+			writeErrorV1(w, err, false)
+			return
+		}
+
+		resp := toBalanceResponseV1(balance, reqURL)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV1) getInvestmentTransactionsHandler(current bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
+		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
+		invID := r.PathValue("id")
+		pag, err := api.NewPagination(r)
+		if err != nil {
+			writeErrorV1(w, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, err.Error()), true)
+			return
+		}
+
+		filter, err := newTransactionFilter(r, current)
+		if err != nil {
+			writeErrorV1(w, err, true)
+			return
+		}
+
+		trs, err := router.service.transactions(r.Context(), invID, consentID, filter, pag)
+		if err != nil {
+			writeErrorV1(w, err, true)
+			return
+		}
+
+		resp := toTransactionsResponseV1(trs, reqURL)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+type investmentsResponseV1 struct {
+	Data  []investmentSummaryV1 `json:"data"`
+	Meta  api.Meta              `json:"meta"`
+	Links api.Links             `json:"links"`
+}
+
+type investmentSummaryV1 struct {
+	BrandName   string `json:"brandName"`
+	CompanyCNPJ string `json:"companyCnpj"`
+	Type        Type   `json:"investmentType"`
+	ID          string `json:"investmentId"`
+}
+
+func toInvestmentsResponseV1(invs page.Page[Investment], reqURL string) investmentsResponseV1 {
+	resp := investmentsResponseV1{
+		Data:  []investmentSummaryV1{},
+		Meta:  api.NewPaginatedMeta(invs),
+		Links: api.NewPaginatedLinks(reqURL, invs),
+	}
+
+	for _, inv := range invs.Records {
+		resp.Data = append(resp.Data, investmentSummaryV1{
+			BrandName:   mock.MockBankBrand,
+			CompanyCNPJ: mock.MockBankCNPJ,
+			Type:        inv.Type,
+			ID:          inv.ID,
+		})
+	}
+
+	return resp
+}
+
+type investmentResponseV1 struct {
+	Data  investmentV1 `json:"data"`
+	Meta  api.Meta     `json:"meta"`
+	Links api.Links    `json:"links"`
+}
+
+type investmentV1 struct {
+	IssuerCNPJ      string           `json:"issuerInstitutionCnpjNumber"`
+	ISINCode        string           `json:"isinCode,omitempty"`
+	Type            Type             `json:"investmentType"`
+	Remuneration    remunerationV1   `json:"remuneration"`
+	IssueUnitPrice  amountResponseV1 `json:"issueUnitPrice"`
+	DueDate         timex.Date       `json:"dueDate"`
+	IssueDate       timex.Date       `json:"issueDate"`
+	ClearingCode    string           `json:"clearingCode,omitempty"`
+	PurchaseDate    timex.Date       `json:"purchaseDate"`
+	GracePeriodDate timex.Date       `json:"gracePeriodDate"`
+}
+
+type remunerationV1 struct {
+	PreFixedRate               string          `json:"preFixedRate,omitempty"`
+	PostFixedIndexerPercentage string          `json:"postFixedIndexerPercentage,omitempty"`
+	RateType                   RateType        `json:"rateType,omitempty"`
+	RatePeriodicity            RatePeriodicity `json:"ratePeriodicity,omitempty"`
+	Calculation                Calculation     `json:"calculation,omitempty"`
+	Indexer                    Indexer         `json:"indexer"`
+	IndexerAdditionalInfo      string          `json:"indexerAdditionalInfo,omitempty"`
+}
+
+type amountResponseV1 struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func toInvestmentResponseV1(inv Investment, reqURL string) investmentResponseV1 {
+	return investmentResponseV1{
+		Data: investmentV1{
+			IssuerCNPJ:      inv.IssuerCNPJ,
+			ISINCode:        inv.ISINCode,
+			Type:            inv.Type,
+			Remuneration:    remunerationV1(inv.Remuneration),
+			IssueUnitPrice:  newAmountResponseV1(inv.IssueUnitPrice),
+			DueDate:         inv.DueDate,
+			IssueDate:       inv.IssueDate,
+			ClearingCode:    inv.ClearingCode,
+			PurchaseDate:    inv.PurchaseDate,
+			GracePeriodDate: inv.GracePeriodDate,
+		},
+		Meta: api.NewSingleRecordMeta(),
+		Links: api.Links{
+			Self: reqURL,
+		},
+	}
+}
+
+type balanceResponseV1 struct {
+	Data  balanceV1 `json:"data"`
+	Meta  api.Meta  `json:"meta"`
+	Links api.Links `json:"links"`
+}
+
+type balanceV1 struct {
+	ReferenceDateTime       timex.DateTime   `json:"referenceDateTime"`
+	Quantity                string           `json:"quantity"`
+	UpdatedUnitPrice        amountResponseV1 `json:"updatedUnitPrice"`
+	GrossAmount             amountResponseV1 `json:"grossAmount"`
+	NetAmount               amountResponseV1 `json:"netAmount"`
+	IncomeTax               amountResponseV1 `json:"incomeTax"`
+	FinancialTransactionTax amountResponseV1 `json:"financialTransactionTax"`
+	BlockedBalance          amountResponseV1 `json:"blockedBalance"`
+	PurchaseUnitPrice       amountResponseV1 `json:"purchaseUnitPrice"`
+}
+
+func toBalanceResponseV1(balance Balance, reqURL string) balanceResponseV1 {
+	return balanceResponseV1{
+		Data: balanceV1{
+			ReferenceDateTime:       balance.ReferenceDateTime,
+			Quantity:                formatQuantity(balance.Quantity),
+			UpdatedUnitPrice:        newAmountResponseV1(balance.UpdatedUnitPrice),
+			GrossAmount:             newAmountResponseV1(balance.GrossAmount),
+			NetAmount:               newAmountResponseV1(balance.NetAmount),
+			IncomeTax:               newAmountResponseV1(balance.IncomeTax),
+			FinancialTransactionTax: newAmountResponseV1(balance.FinancialTransactionTax),
+			BlockedBalance:          newAmountResponseV1(balance.BlockedAmount),
+			PurchaseUnitPrice:       newAmountResponseV1(balance.PurchaseUnitPrice),
+		},
+		Meta: api.NewSingleRecordMeta(),
+		Links: api.Links{
+			Self: reqURL,
+		},
+	}
+}
+
+type transactionsResponseV1 struct {
+	Data  []transactionV1 `json:"data"`
+	Meta  api.Meta        `json:"meta"`
+	Links api.Links       `json:"links"`
+}
+
+type transactionV1 struct {
+	ID                      string           `json:"transactionId"`
+	MovementType            MovementType     `json:"type"`
+	Type                    TransactionType  `json:"transactionType"`
+	TypeAdditionalInfo      string           `json:"transactionTypeAdditionalInfo,omitempty"`
+	Date                    timex.Date       `json:"transactionDate"`
+	UnitPrice               amountResponseV1 `json:"transactionUnitPrice"`
+	Quantity                string           `json:"transactionQuantity"`
+	GrossValue              amountResponseV1 `json:"transactionGrossValue"`
+	IncomeTax               amountResponseV1 `json:"incomeTax"`
+	FinancialTransactionTax amountResponseV1 `json:"financialTransactionTax"`
+	NetValue                amountResponseV1 `json:"transactionNetValue"`
+}
+
+func toTransactionsResponseV1(trs page.Page[Transaction], reqURL string) transactionsResponseV1 {
+	resp := transactionsResponseV1{
+		Data:  []transactionV1{},
+		Meta:  api.NewPaginatedMeta(trs),
+		Links: api.NewPaginatedLinks(reqURL, trs),
+	}
+
+	for _, tr := range trs.Records {
+		resp.Data = append(resp.Data, transactionV1{
+			ID:                      tr.ID,
+			MovementType:            tr.MovementType,
+			Type:                    tr.Type,
+			TypeAdditionalInfo:      tr.TypeAdditionalInfo,
+			Date:                    tr.Date,
+			UnitPrice:               newAmountResponseV1(tr.UnitPrice),
+			Quantity:                formatQuantity(tr.Quantity),
+			GrossValue:              newAmountResponseV1(tr.GrossValue),
+			IncomeTax:               newAmountResponseV1(tr.IncomeTax),
+			FinancialTransactionTax: newAmountResponseV1(tr.FinancialTransactionTax),
+			NetValue:                newAmountResponseV1(tr.NetValue),
+		})
+	}
+
+	return resp
+}
+
+func newAmountResponseV1(amount string) amountResponseV1 {
+	return amountResponseV1{
+		Amount:   amount,
+		Currency: defaultCurrency,
+	}
+}
+
+func formatQuantity(quantity int) string {
+	return fmt.Sprintf("%d.00", quantity)
+}
+
+func newTransactionFilter(r *http.Request, current bool) (transactionFilter, error) {
+	dates, err := api.NewTransactionDateRange(r, "fromTransactionDate", "toTransactionDate", current)
+	if err != nil {
+		return transactionFilter{}, err
+	}
+
+	return transactionFilter{
+		from: dates.From,
+		to:   dates.To,
+	}, nil
+}
+
+func writeErrorV1(w http.ResponseWriter, err error, pagination bool) {
+	if errors.Is(err, errInvestmentNotAllowed) {
+		err := api.NewError("FORBIDDEN", http.StatusForbidden, errInvestmentNotAllowed.Error())
+		if pagination {
+			err = err.WithPagination()
+		}
+		api.WriteError(w, err)
+		return
+	}
+
+	api.WriteError(w, err)
+}
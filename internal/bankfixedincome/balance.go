@@ -0,0 +1,181 @@
+package bankfixedincome
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/luikyv/go-open-finance/internal/money"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+const (
+	// cdiRate and ipcaRate are the yearly rates assumed for the indexers,
+	// since their historical series are not available.
+	cdiRate  = 0.1490
+	ipcaRate = 0.0450
+
+	businessDaysPerYear = 252
+	calendarDaysPerYear = 365
+)
+
+// iofRates are the rates of the financial transaction tax (IOF) over the
+// income of redemptions made in the first 29 days after the purchase, indexed
+// by the number of days.
+var iofRates = []float64{
+	1.00, 0.96, 0.93, 0.90, 0.86, 0.83, 0.80, 0.76, 0.73, 0.70,
+	0.66, 0.63, 0.60, 0.56, 0.53, 0.50, 0.46, 0.43, 0.40, 0.36,
+	0.33, 0.30, 0.26, 0.23, 0.20, 0.16, 0.13, 0.10, 0.06, 0.03,
+}
+
+// balance calculates the position of the investment today from its
+// transactions, as if it were redeemed in full.
+func (inv Investment) balance() Balance {
+	today := timex.DateNow()
+	quantity := 0
+	for _, tr := range inv.Transactions {
+		if tr.MovementType == MovementTypeIn {
+			quantity += tr.Quantity
+		} else {
+			quantity -= tr.Quantity
+		}
+	}
+
+	redemption := inv.Redemption(today, quantity)
+	b := Balance{
+		ReferenceDateTime:       timex.DateTimeNow(),
+		Quantity:                quantity,
+		UpdatedUnitPrice:        redemption.UnitPrice,
+		PurchaseUnitPrice:       money.Format(inv.unitPrice(inv.PurchaseDate)),
+		GrossAmount:             redemption.GrossValue,
+		IncomeTax:               redemption.IncomeTax,
+		FinancialTransactionTax: redemption.FinancialTransactionTax,
+		NetAmount:               redemption.NetValue,
+		BlockedAmount:           money.Format(0),
+	}
+	if today.Before(inv.GracePeriodDate.Time) {
+		b.BlockedAmount = redemption.GrossValue
+	}
+	return b
+}
+
+// Application returns the purchase of quantity units of the investment at
+// its purchase date.
+func (inv Investment) Application(quantity int) Transaction {
+	unitPrice := inv.unitPrice(inv.PurchaseDate)
+	value := money.Format(unitPrice * int64(quantity))
+	return Transaction{
+		MovementType:            MovementTypeIn,
+		Type:                    TransactionTypeApplication,
+		Date:                    inv.PurchaseDate,
+		UnitPrice:               money.Format(unitPrice),
+		Quantity:                quantity,
+		GrossValue:              value,
+		IncomeTax:               money.Format(0),
+		FinancialTransactionTax: money.Format(0),
+		NetValue:                value,
+	}
+}
+
+// Redemption returns the redemption of quantity units of the investment at
+// date with the taxes due over its income.
+// The financial transaction tax (IOF) is due for redemptions in the first 30
+// days after the purchase and the income tax follows the regressive table
+// over what remains of the income.
+func (inv Investment) Redemption(date timex.Date, quantity int) Transaction {
+	unitPrice := inv.unitPrice(date)
+	gross := unitPrice * int64(quantity)
+	income := max(gross-inv.unitPrice(inv.PurchaseDate)*int64(quantity), 0)
+
+	days := daysBetween(inv.PurchaseDate, date)
+	var iof int64
+	if days < len(iofRates) {
+		iof = int64(math.Round(float64(income) * iofRates[days]))
+	}
+
+	var incomeTax int64
+	if !inv.Type.IsTaxExempt() {
+		incomeTax = int64(math.Round(float64(income-iof) * incomeTaxRate(days)))
+	}
+
+	return Transaction{
+		MovementType:            MovementTypeOut,
+		Type:                    TransactionTypeRedemption,
+		Date:                    date,
+		UnitPrice:               money.Format(unitPrice),
+		Quantity:                quantity,
+		GrossValue:              money.Format(gross),
+		IncomeTax:               money.Format(incomeTax),
+		FinancialTransactionTax: money.Format(iof),
+		NetValue:                money.Format(gross - iof - incomeTax),
+	}
+}
+
+// unitPrice returns the price of a unit of the investment at date in cents,
+// i.e. the issue unit price updated by the remuneration since the issue.
+func (inv Investment) unitPrice(date timex.Date) int64 {
+	issueUnitPrice, _ := money.Parse(inv.IssueUnitPrice)
+	return int64(math.Round(float64(issueUnitPrice) * inv.factor(inv.IssueDate, date)))
+}
+
+// factor returns how much the investment grows from one date to another.
+func (inv Investment) factor(from, to timex.Date) float64 {
+	if !to.After(from.Time) {
+		return 1
+	}
+
+	r := inv.Remuneration
+	preFixedRate, _ := strconv.ParseFloat(r.PreFixedRate, 64)
+	years := float64(daysBetween(from, to)) / calendarDaysPerYear
+	if r.Calculation == CalculationBusinessDays {
+		years = float64(businessDaysBetween(from, to)) / businessDaysPerYear
+	}
+
+	preFixedFactor := math.Pow(1+preFixedRate, years)
+	if r.RateType == RateTypeLinear {
+		preFixedFactor = 1 + preFixedRate*years
+	}
+
+	switch r.Indexer {
+	case IndexerCDI, IndexerDI, IndexerSELIC:
+		// The CDI accrues every business day and the investment pays a
+		// percentage of it.
+		percentage, _ := strconv.ParseFloat(r.PostFixedIndexerPercentage, 64)
+		dailyRate := math.Pow(1+cdiRate, 1.0/businessDaysPerYear) - 1
+		return math.Pow(1+dailyRate*percentage, float64(businessDaysBetween(from, to))) * preFixedFactor
+	case IndexerIPCA:
+		return math.Pow(1+ipcaRate, float64(daysBetween(from, to))/calendarDaysPerYear) * preFixedFactor
+	default:
+		return preFixedFactor
+	}
+}
+
+// incomeTaxRate returns the rate of the income tax over the income of an
+// investment held for the number of days informed.
+func incomeTaxRate(days int) float64 {
+	switch {
+	case days <= 180:
+		return 0.225
+	case days <= 360:
+		return 0.20
+	case days <= 720:
+		return 0.175
+	default:
+		return 0.15
+	}
+}
+
+func daysBetween(from, to timex.Date) int {
+	return int(to.Sub(from.Time) / (24 * time.Hour))
+}
+
+// businessDaysBetween counts the business days after from up to to.
+func businessDaysBetween(from, to timex.Date) int {
+	days := 0
+	for d := timex.NewDate(from.AddDate(0, 0, 1)); !d.After(to.Time); d = timex.NewDate(d.AddDate(0, 0, 1)) {
+		if timex.IsBusinessDay(d) {
+			days++
+		}
+	}
+	return days
+}
@@ -0,0 +1,102 @@
+package bankfixedincome
+
+import (
+	"testing"
+
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+func TestRedemption(t *testing.T) {
+	testCases := []struct {
+		name    string
+		invType Type
+		days    int
+		// want holds the gross value, IOF, income tax and net value.
+		want [4]string
+	}{
+		// The IOF is 66% of the income of 10.00 on the 10th day and the
+		// income tax is 22.5% of what remains.
+		{"IOF on the 10th day", TypeCDB, 10, [4]string{"1010.00", "6.60", "0.77", "1002.63"}},
+		{"IOF on the 29th day", TypeCDB, 29, [4]string{"1029.00", "0.87", "6.33", "1021.80"}},
+		{"no IOF from the 30th day", TypeCDB, 30, [4]string{"1030.00", "0.00", "6.75", "1023.25"}},
+		{"income tax up to 180 days", TypeCDB, 180, [4]string{"1180.00", "0.00", "40.50", "1139.50"}},
+		{"income tax up to 360 days", TypeCDB, 181, [4]string{"1181.00", "0.00", "36.20", "1144.80"}},
+		{"income tax up to 720 days", TypeCDB, 361, [4]string{"1361.00", "0.00", "63.18", "1297.82"}},
+		{"income tax above 720 days", TypeCDB, 721, [4]string{"1721.00", "0.00", "108.15", "1612.85"}},
+		{"exempt from income tax", TypeLCI, 10, [4]string{"1010.00", "6.60", "0.00", "1003.40"}},
+		{"redeemed on the purchase date", TypeCDB, 0, [4]string{"1000.00", "0.00", "0.00", "1000.00"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// A linear rate of 36.5% a year grows the investment 0.1% a day.
+			purchaseDate, _ := timex.ParseDate("2024-01-01")
+			inv := Investment{
+				Type: tc.invType,
+				Remuneration: Remuneration{
+					PreFixedRate: "0.3650",
+					RateType:     RateTypeLinear,
+					Calculation:  CalculationCalendarDays,
+					Indexer:      IndexerPreFixed,
+				},
+				IssueUnitPrice: "1000.00",
+				IssueDate:      purchaseDate,
+				PurchaseDate:   purchaseDate,
+			}
+
+			tr := inv.Redemption(timex.NewDate(purchaseDate.AddDate(0, 0, tc.days)), 1)
+			got := [4]string{tr.GrossValue, tr.FinancialTransactionTax, tr.IncomeTax, tr.NetValue}
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIOFRates(t *testing.T) {
+	// The IOF decreases from 100% on the day of the purchase to 3% on the
+	// 29th day, following the table of Decree 6306/2007.
+	testCases := []struct {
+		days int
+		want float64
+	}{
+		{0, 1.00},
+		{1, 0.96},
+		{5, 0.83},
+		{15, 0.50},
+		{20, 0.33},
+		{29, 0.03},
+	}
+
+	if len(iofRates) != 30 {
+		t.Fatalf("got %d IOF rates, want 30", len(iofRates))
+	}
+
+	for _, tc := range testCases {
+		if got := iofRates[tc.days]; got != tc.want {
+			t.Errorf("day %d: got %.2f, want %.2f", tc.days, got, tc.want)
+		}
+	}
+}
+
+func TestIncomeTaxRate(t *testing.T) {
+	testCases := []struct {
+		days int
+		want float64
+	}{
+		{0, 0.225},
+		{180, 0.225},
+		{181, 0.20},
+		{360, 0.20},
+		{361, 0.175},
+		{720, 0.175},
+		{721, 0.15},
+		{3650, 0.15},
+	}
+
+	for _, tc := range testCases {
+		if got := incomeTaxRate(tc.days); got != tc.want {
+			t.Errorf("%d days: got %.3f, want %.3f", tc.days, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package bankfixedincome
+
+import (
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+var (
+	Scope = goidc.NewScope("bank-fixed-incomes")
+)
+
+const (
+	defaultCurrency string = "BRL"
+)
+
+type Investment struct {
+	ID           string
+	UserID       string
+	Type         Type
+	IssuerCNPJ   string
+	ISINCode     string
+	ClearingCode string
+	Remuneration Remuneration
+	// IssueUnitPrice is the price of a unit of the investment when it was
+	// issued, from which its updated price is calculated.
+	IssueUnitPrice string
+	IssueDate      timex.Date
+	PurchaseDate   timex.Date
+	DueDate        timex.Date
+	// GracePeriodDate is when the investment can start to be redeemed. Until
+	// then, its balance is blocked.
+	GracePeriodDate timex.Date
+	Transactions    []Transaction
+}
+
+type Type string
+
+const (
+	TypeCDB Type = "CDB"
+	TypeRDB Type = "RDB"
+	TypeLCI Type = "LCI"
+	TypeLCA Type = "LCA"
+)
+
+// IsTaxExempt returns whether the income of the investment is exempt from
+// income tax, which is the case of real estate and agribusiness credit notes.
+func (t Type) IsTaxExempt() bool {
+	return t == TypeLCI || t == TypeLCA
+}
+
+type Remuneration struct {
+	// PreFixedRate is the yearly rate paid, e.g. "0.1200" for 12% a year, in
+	// addition to the indexer if the investment is post fixed.
+	PreFixedRate string
+	// PostFixedIndexerPercentage is the percentage of the indexer paid, e.g.
+	// "1.1000" for 110% of the CDI.
+	PostFixedIndexerPercentage string
+	RateType                   RateType
+	RatePeriodicity            RatePeriodicity
+	Calculation                Calculation
+	Indexer                    Indexer
+	IndexerAdditionalInfo      string
+}
+
+type RateType string
+
+const (
+	RateTypeLinear      RateType = "LINEAR"
+	RateTypeExponential RateType = "EXPONENCIAL"
+)
+
+type RatePeriodicity string
+
+const (
+	RatePeriodicityMonthly    RatePeriodicity = "MENSAL"
+	RatePeriodicityYearly     RatePeriodicity = "ANUAL"
+	RatePeriodicityDaily      RatePeriodicity = "DIARIO"
+	RatePeriodicitySemiannual RatePeriodicity = "SEMESTRAL"
+)
+
+type Calculation string
+
+const (
+	CalculationBusinessDays Calculation = "DIAS_UTEIS"
+	CalculationCalendarDays Calculation = "DIAS_CORRIDOS"
+)
+
+type Indexer string
+
+const (
+	IndexerCDI      Indexer = "CDI"
+	IndexerDI       Indexer = "DI"
+	IndexerTR       Indexer = "TR"
+	IndexerIPCA     Indexer = "IPCA"
+	IndexerIGPM     Indexer = "IGP_M"
+	IndexerIGPDI    Indexer = "IGP_DI"
+	IndexerINPC     Indexer = "INPC"
+	IndexerBCP      Indexer = "BCP"
+	IndexerTLC      Indexer = "TLC"
+	IndexerSELIC    Indexer = "SELIC"
+	IndexerPreFixed Indexer = "PRE_FIXADO"
+	IndexerOthers   Indexer = "OUTROS"
+)
+
+type Transaction struct {
+	ID                      string
+	MovementType            MovementType
+	Type                    TransactionType
+	TypeAdditionalInfo      string
+	Date                    timex.Date
+	UnitPrice               string
+	Quantity                int
+	GrossValue              string
+	IncomeTax               string
+	FinancialTransactionTax string
+	NetValue                string
+}
+
+type MovementType string
+
+const (
+	MovementTypeIn  MovementType = "ENTRADA"
+	MovementTypeOut MovementType = "SAIDA"
+)
+
+type TransactionType string
+
+const (
+	TransactionTypeApplication       TransactionType = "APLICACAO"
+	TransactionTypeRedemption        TransactionType = "RESGATE"
+	TransactionTypeInterestPayment   TransactionType = "PAGAMENTO_JUROS"
+	TransactionTypeTax               TransactionType = "IMPOSTO"
+	TransactionTypeOwnershipTransfer TransactionType = "TRANSFERENCIA_TITULARIDADE"
+	TransactionTypeOthers            TransactionType = "OUTROS"
+)
+
+// Balance is the position of an investment at a moment in time.
+type Balance struct {
+	ReferenceDateTime       timex.DateTime
+	Quantity                int
+	UpdatedUnitPrice        string
+	PurchaseUnitPrice       string
+	GrossAmount             string
+	IncomeTax               string
+	FinancialTransactionTax string
+	NetAmount               string
+	BlockedAmount           string
+}
+
+type transactionFilter struct {
+	from timex.Date
+	to   timex.Date
+}
+
+func (f transactionFilter) matches(tr Transaction) bool {
+	return !tr.Date.Before(f.from.Time) && !tr.Date.After(f.to.Time)
+}
@@ -0,0 +1,91 @@
+package bankfixedincome
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/luikyv/go-open-finance/internal/consent"
+	"github.com/luikyv/go-open-finance/internal/page"
+)
+
+var (
+	errInvestmentNotAllowed = errors.New("the investment was not consented")
+)
+
+type Service struct {
+	storage        *Storage
+	consentService consent.Service
+}
+
+func NewService(storage *Storage, consentService consent.Service) Service {
+	return Service{
+		storage:        storage,
+		consentService: consentService,
+	}
+}
+
+func (s Service) Add(userID string, inv Investment) {
+	inv.UserID = userID
+	s.storage.save(inv)
+}
+
+func (s Service) investments(ctx context.Context, consentID string, pag page.Pagination) (page.Page[Investment], error) {
+	c, err := s.consentService.Consent(ctx, consentID)
+	if err != nil {
+		return page.Page[Investment]{}, err
+	}
+
+	var invs []Investment
+	for _, id := range c.BankFixedIncomeIDs {
+		invs = append(invs, s.storage.investment(id))
+	}
+
+	return page.Paginate(invs, pag), nil
+}
+
+func (s Service) investment(ctx context.Context, id, consentID string) (Investment, error) {
+	c, err := s.consentService.Consent(ctx, consentID)
+	if err != nil {
+		return Investment{}, err
+	}
+
+	if !slices.Contains(c.BankFixedIncomeIDs, id) {
+		return Investment{}, errInvestmentNotAllowed
+	}
+
+	return s.storage.investment(id), nil
+}
+
+func (s Service) balance(ctx context.Context, id, consentID string) (Balance, error) {
+	inv, err := s.investment(ctx, id, consentID)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	return inv.balance(), nil
+}
+
+func (s Service) transactions(
+	ctx context.Context,
+	id, consentID string,
+	filter transactionFilter,
+	pag page.Pagination,
+) (
+	page.Page[Transaction],
+	error,
+) {
+	inv, err := s.investment(ctx, id, consentID)
+	if err != nil {
+		return page.Page[Transaction]{}, err
+	}
+
+	var trs []Transaction
+	for _, tr := range inv.Transactions {
+		if filter.matches(tr) {
+			trs = append(trs, tr)
+		}
+	}
+
+	return page.Paginate(trs, pag), nil
+}
@@ -0,0 +1,19 @@
+package bankfixedincome
+
+type Storage struct {
+	investmentsMap map[string]Investment
+}
+
+func NewStorage() *Storage {
+	return &Storage{
+		investmentsMap: map[string]Investment{},
+	}
+}
+
+func (s *Storage) save(inv Investment) {
+	s.investmentsMap[inv.ID] = inv
+}
+
+func (s *Storage) investment(id string) Investment {
+	return s.investmentsMap[id]
+}
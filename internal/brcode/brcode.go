@@ -0,0 +1,326 @@
+// Package brcode parses and generates BR Codes, the EMV® Merchant Presented
+// QR codes used by Pix as defined by the Manual do BR Code.
+package brcode
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	pixGUI = "br.gov.bcb.pix"
+
+	idPayloadFormatIndicator  = "00"
+	idPointOfInitiationMethod = "01"
+	idMerchantAccountPix      = "26"
+	idMerchantCategoryCode    = "52"
+	idTransactionCurrency     = "53"
+	idTransactionAmount       = "54"
+	idCountryCode             = "58"
+	idMerchantName            = "59"
+	idMerchantCity            = "60"
+	idPostalCode              = "61"
+	idAdditionalDataField     = "62"
+	idCRC                     = "63"
+	idMerchantAccountGUI      = "00"
+	idMerchantAccountKey      = "01"
+	idMerchantAccountInfo     = "02"
+	idMerchantAccountURL      = "25"
+	idAdditionalDataTxID      = "05"
+	payloadFormatIndicator    = "01"
+	currencyBRL               = "986"
+	countryCodeBR             = "BR"
+	defaultMerchantCategory   = "0000"
+	pointOfInitiationStatic   = "11"
+	pointOfInitiationDynamic  = "12"
+	firstMerchantAccountID    = 26
+	lastMerchantAccountID     = 51
+	crcLength                 = 4
+	maxMerchantNameLength     = 25
+	maxMerchantCityLength     = 15
+	txIDNotInformed           = "***"
+	crcHeader                 = idCRC + "04"
+	minCodeLength             = len(crcHeader) + crcLength
+)
+
+var (
+	ErrInvalidCode = errors.New("the br code is invalid")
+
+	amountPattern = regexp.MustCompile(`^\d{1,10}(\.\d{1,2})?$`)
+)
+
+// Code is the information carried by a BR Code.
+type Code struct {
+	// Key is the Pix key of the receiver. It is only informed by static codes.
+	Key string
+	// URL is the location of the payload of a dynamic code without the
+	// scheme.
+	URL                   string
+	AdditionalInformation string
+	MerchantCategoryCode  string
+	// Amount is formatted with two decimal places. It is empty if the payer
+	// must inform the amount.
+	Amount       string
+	MerchantName string
+	MerchantCity string
+	PostalCode   string
+	TxID         string
+	Dynamic      bool
+}
+
+// Parse decodes and validates a BR Code.
+func Parse(s string) (Code, error) {
+	if len(s) < minCodeLength || s[len(s)-minCodeLength:len(s)-crcLength] != crcHeader {
+		return Code{}, fmt.Errorf("%w: missing crc", ErrInvalidCode)
+	}
+
+	payload, checksum := s[:len(s)-crcLength], s[len(s)-crcLength:]
+	if !strings.EqualFold(checksum, crc16(payload)) {
+		return Code{}, fmt.Errorf("%w: crc mismatch", ErrInvalidCode)
+	}
+
+	fields, err := parseTLV(s)
+	if err != nil {
+		return Code{}, err
+	}
+
+	if len(fields) == 0 || fields[0].id != idPayloadFormatIndicator || fields[0].value != payloadFormatIndicator {
+		return Code{}, fmt.Errorf("%w: invalid payload format indicator", ErrInvalidCode)
+	}
+
+	values := map[string]string{}
+	var merchantAccount string
+	for _, f := range fields {
+		if _, ok := values[f.id]; ok {
+			return Code{}, fmt.Errorf("%w: duplicated field %s", ErrInvalidCode, f.id)
+		}
+		values[f.id] = f.value
+
+		if id, _ := strconv.Atoi(f.id); id >= firstMerchantAccountID && id <= lastMerchantAccountID &&
+			merchantAccount == "" && isPixMerchantAccount(f.value) {
+			merchantAccount = f.value
+		}
+	}
+
+	if merchantAccount == "" {
+		return Code{}, fmt.Errorf("%w: missing pix merchant account information", ErrInvalidCode)
+	}
+
+	for _, id := range []string{idMerchantCategoryCode, idTransactionCurrency, idCountryCode, idMerchantName, idMerchantCity} {
+		if values[id] == "" {
+			return Code{}, fmt.Errorf("%w: missing field %s", ErrInvalidCode, id)
+		}
+	}
+
+	if values[idTransactionCurrency] != currencyBRL || values[idCountryCode] != countryCodeBR {
+		return Code{}, fmt.Errorf("%w: invalid currency or country", ErrInvalidCode)
+	}
+
+	code := Code{
+		MerchantCategoryCode: values[idMerchantCategoryCode],
+		MerchantName:         values[idMerchantName],
+		MerchantCity:         values[idMerchantCity],
+		PostalCode:           values[idPostalCode],
+	}
+
+	switch values[idPointOfInitiationMethod] {
+	case "", pointOfInitiationStatic:
+	case pointOfInitiationDynamic:
+		code.Dynamic = true
+	default:
+		return Code{}, fmt.Errorf("%w: invalid point of initiation method", ErrInvalidCode)
+	}
+
+	if amount, ok := values[idTransactionAmount]; ok {
+		if code.Amount, err = normalizeAmount(amount); err != nil {
+			return Code{}, err
+		}
+	}
+
+	accountFields, err := parseTLV(merchantAccount)
+	if err != nil {
+		return Code{}, err
+	}
+	for _, f := range accountFields {
+		switch f.id {
+		case idMerchantAccountKey:
+			code.Key = f.value
+		case idMerchantAccountInfo:
+			code.AdditionalInformation = f.value
+		case idMerchantAccountURL:
+			code.URL = f.value
+		}
+	}
+
+	// Static codes identify the receiver by its key, while dynamic ones point
+	// to the location of their payload.
+	if (code.Key == "") == (code.URL == "") {
+		return Code{}, fmt.Errorf("%w: either the key or the url must be informed", ErrInvalidCode)
+	}
+
+	if code.URL != "" && !code.Dynamic {
+		return Code{}, fmt.Errorf("%w: only dynamic codes can inform an url", ErrInvalidCode)
+	}
+
+	if additionalData, ok := values[idAdditionalDataField]; ok {
+		additionalFields, err := parseTLV(additionalData)
+		if err != nil {
+			return Code{}, err
+		}
+		for _, f := range additionalFields {
+			if f.id == idAdditionalDataTxID && f.value != txIDNotInformed {
+				code.TxID = f.value
+			}
+		}
+	}
+
+	return code, nil
+}
+
+// String encodes the code as a BR Code.
+func (c Code) String() string {
+	var sb strings.Builder
+	writeField(&sb, idPayloadFormatIndicator, payloadFormatIndicator)
+	if c.Dynamic {
+		writeField(&sb, idPointOfInitiationMethod, pointOfInitiationDynamic)
+	}
+
+	var account strings.Builder
+	writeField(&account, idMerchantAccountGUI, pixGUI)
+	if c.Key != "" {
+		writeField(&account, idMerchantAccountKey, c.Key)
+	}
+	if c.AdditionalInformation != "" {
+		writeField(&account, idMerchantAccountInfo, c.AdditionalInformation)
+	}
+	if c.URL != "" {
+		writeField(&account, idMerchantAccountURL, c.URL)
+	}
+	writeField(&sb, idMerchantAccountPix, account.String())
+
+	mcc := c.MerchantCategoryCode
+	if mcc == "" {
+		mcc = defaultMerchantCategory
+	}
+	writeField(&sb, idMerchantCategoryCode, mcc)
+	writeField(&sb, idTransactionCurrency, currencyBRL)
+	if c.Amount != "" {
+		writeField(&sb, idTransactionAmount, c.Amount)
+	}
+	writeField(&sb, idCountryCode, countryCodeBR)
+	writeField(&sb, idMerchantName, truncate(c.MerchantName, maxMerchantNameLength))
+	writeField(&sb, idMerchantCity, truncate(c.MerchantCity, maxMerchantCityLength))
+	if c.PostalCode != "" {
+		writeField(&sb, idPostalCode, c.PostalCode)
+	}
+
+	txID := c.TxID
+	if txID == "" {
+		txID = txIDNotInformed
+	}
+	var additionalData strings.Builder
+	writeField(&additionalData, idAdditionalDataTxID, txID)
+	writeField(&sb, idAdditionalDataField, additionalData.String())
+
+	sb.WriteString(crcHeader)
+	return sb.String() + crc16(sb.String())
+}
+
+// MatchesName returns true if name could be the one the merchant name of the
+// code was generated from. Merchant names are limited to 25 characters and
+// usually have no accents, so only the beginning of name is compared ignoring
+// case and accents.
+func (c Code) MatchesName(name string) bool {
+	merchantName := normalizeName(c.MerchantName)
+	return merchantName != "" && strings.HasPrefix(normalizeName(name), merchantName)
+}
+
+type field struct {
+	id    string
+	value string
+}
+
+func parseTLV(s string) ([]field, error) {
+	var fields []field
+	for len(s) > 0 {
+		if len(s) < 4 {
+			return nil, fmt.Errorf("%w: truncated field", ErrInvalidCode)
+		}
+
+		length, err := strconv.Atoi(s[2:4])
+		if err != nil || length == 0 || len(s) < 4+length {
+			return nil, fmt.Errorf("%w: invalid length of field %s", ErrInvalidCode, s[:2])
+		}
+
+		fields = append(fields, field{id: s[:2], value: s[4 : 4+length]})
+		s = s[4+length:]
+	}
+	return fields, nil
+}
+
+func writeField(sb *strings.Builder, id, value string) {
+	fmt.Fprintf(sb, "%s%02d%s", id, len(value), value)
+}
+
+func isPixMerchantAccount(value string) bool {
+	fields, err := parseTLV(value)
+	if err != nil || len(fields) == 0 {
+		return false
+	}
+	return fields[0].id == idMerchantAccountGUI && strings.EqualFold(fields[0].value, pixGUI)
+}
+
+// normalizeAmount formats the amount of a code, which may have up to two
+// decimal places, with exactly two decimal places.
+func normalizeAmount(amount string) (string, error) {
+	if !amountPattern.MatchString(amount) {
+		return "", fmt.Errorf("%w: invalid amount", ErrInvalidCode)
+	}
+
+	units, cents, _ := strings.Cut(amount, ".")
+	units = strings.TrimLeft(units, "0")
+	if units == "" {
+		units = "0"
+	}
+	return units + "." + (cents + "00")[:2], nil
+}
+
+var accentReplacer = strings.NewReplacer(
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ç", "C", "Ñ", "N",
+)
+
+func normalizeName(name string) string {
+	return accentReplacer.Replace(strings.ToUpper(strings.TrimSpace(name)))
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// crc16 calculates the CRC-16/CCITT-FALSE checksum of s as four uppercase
+// hexadecimal digits.
+func crc16(s string) string {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return fmt.Sprintf("%04X", crc)
+}
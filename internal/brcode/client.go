@@ -0,0 +1,162 @@
+package brcode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+const (
+	defaultChargeExpirationSecs = 3600
+	headerJKU                   = "jku"
+	maxPayloadBytes             = 1 << 20
+)
+
+var (
+	ErrPayloadUnavailable = errors.New("could not fetch the payload of the br code")
+	ErrInvalidPayload     = errors.New("the payload of the br code is invalid")
+)
+
+type ChargeStatus string
+
+const (
+	ChargeStatusActive ChargeStatus = "ATIVA"
+	// ChargeStatusCompleted is the status of a charge which was already paid.
+	ChargeStatusCompleted      ChargeStatus = "CONCLUIDA"
+	ChargeStatusRemovedByPayee ChargeStatus = "REMOVIDA_PELO_USUARIO_RECEBEDOR"
+	ChargeStatusRemovedByPSP   ChargeStatus = "REMOVIDA_PELO_PSP"
+)
+
+// Payload is the charge a dynamic BR Code points to, as defined by the API
+// Pix. It is served by the PSP of the receiver as a signed JWS.
+type Payload struct {
+	TxID     string          `json:"txid"`
+	Revision int             `json:"revisao"`
+	Calendar PayloadCalendar `json:"calendario"`
+	Status   ChargeStatus    `json:"status"`
+	Amount   PayloadAmount   `json:"valor"`
+	// Key is the Pix key of the receiver.
+	Key          string `json:"chave"`
+	PayerRequest string `json:"solicitacaoPagador,omitempty"`
+}
+
+// HasExpired returns true if the charge can no longer be paid.
+func (p Payload) HasExpired() bool {
+	expiration := p.Calendar.ExpirationSecs
+	if expiration == 0 {
+		expiration = defaultChargeExpirationSecs
+	}
+	return timex.Now().After(p.Calendar.CreationDateTime.Add(timex.Second * time.Duration(expiration)))
+}
+
+func (p Payload) IsActive() bool {
+	return p.Status == ChargeStatusActive
+}
+
+type PayloadCalendar struct {
+	// The date times are parsed as RFC 3339, since PSPs may inform fractional
+	// seconds.
+	CreationDateTime     time.Time `json:"criacao"`
+	PresentationDateTime time.Time `json:"apresentacao"`
+	ExpirationSecs       int       `json:"expiracao"`
+}
+
+type PayloadAmount struct {
+	Original string `json:"original"`
+}
+
+// Client fetches the payloads of dynamic BR Codes from the PSPs of the
+// receivers.
+type Client struct {
+	httpClient *http.Client
+}
+
+func NewClient(httpClient *http.Client) Client {
+	return Client{
+		httpClient: httpClient,
+	}
+}
+
+// Payload fetches the payload at the location informed by a dynamic BR Code
+// and verifies its signature.
+// The payload must be signed with PS256 by one of the keys published at the
+// "jku" header, which must be hosted in the same domain as the location.
+func (c Client) Payload(ctx context.Context, location string) (Payload, error) {
+	locationURL, err := url.Parse("https://" + strings.TrimPrefix(location, "https://"))
+	if err != nil || locationURL.Host == "" {
+		return Payload{}, fmt.Errorf("%w: invalid location", ErrInvalidCode)
+	}
+
+	body, err := c.get(ctx, locationURL.String())
+	if err != nil {
+		return Payload{}, err
+	}
+
+	jws, err := jose.ParseSignedCompact(string(body), []jose.SignatureAlgorithm{jose.PS256})
+	if err != nil || len(jws.Signatures) != 1 {
+		return Payload{}, fmt.Errorf("%w: invalid jws", ErrInvalidPayload)
+	}
+	header := jws.Signatures[0].Header
+
+	jku, _ := header.ExtraHeaders[headerJKU].(string)
+	jkuURL, err := url.Parse(jku)
+	if err != nil || jkuURL.Scheme != "https" || jkuURL.Host != locationURL.Host {
+		return Payload{}, fmt.Errorf("%w: the jku must be in the domain of the location", ErrInvalidPayload)
+	}
+
+	jwksBytes, err := c.get(ctx, jkuURL.String())
+	if err != nil {
+		return Payload{}, err
+	}
+
+	var jwks goidc.JSONWebKeySet
+	if err := json.Unmarshal(jwksBytes, &jwks); err != nil {
+		return Payload{}, fmt.Errorf("%w: invalid jwks", ErrInvalidPayload)
+	}
+
+	jwk, err := jwks.Key(header.KeyID)
+	if err != nil {
+		return Payload{}, fmt.Errorf("%w: unknown key", ErrInvalidPayload)
+	}
+
+	payloadBytes, err := jws.Verify(jwk.Key)
+	if err != nil {
+		return Payload{}, fmt.Errorf("%w: invalid signature", ErrInvalidPayload)
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return Payload{}, fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+
+	return payload, nil
+}
+
+func (c Client) get(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPayloadUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status %d", ErrPayloadUnavailable, resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxPayloadBytes))
+}
@@ -0,0 +1,288 @@
+package brcode
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/mock"
+	"github.com/luikyv/go-open-finance/internal/money"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+const (
+	// PathPrefixPSP is where the PSP stub is served.
+	PathPrefixPSP         = "/psp"
+	contentTypeJOSE       = "application/jose"
+	defaultMerchantCity   = "SAO PAULO"
+	txIDCharset           = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	generatedTxIDLength   = 32
+	minTxIDLength         = 26
+	maxTxIDLength         = 35
+	maxPayerRequestLength = 140
+)
+
+// PSPRouter is a stub of the PSP of a receiver. It creates immediate charges
+// (cobranças imediatas) and serves the signed payloads of their dynamic BR
+// Codes, so QRDN payments can be tested locally.
+type PSPRouter struct {
+	// host is the URL the stub is reachable at. It is used to build the
+	// locations of the payloads and the "jku" header.
+	host string
+	jwks goidc.JSONWebKeySet
+
+	mu      *sync.Mutex
+	charges map[string]charge
+}
+
+type charge struct {
+	payload      Payload
+	merchantName string
+	merchantCity string
+}
+
+func NewPSPRouter(host string, jwks goidc.JSONWebKeySet) PSPRouter {
+	return PSPRouter{
+		host:    host,
+		jwks:    jwks,
+		mu:      &sync.Mutex{},
+		charges: map[string]charge{},
+	}
+}
+
+func (router PSPRouter) Register(mux *http.ServeMux) {
+	mux.Handle("POST "+PathPrefixPSP+"/cob", router.createChargeHandler())
+	mux.Handle("GET "+PathPrefixPSP+"/cob/{txid}", router.chargeHandler())
+	mux.Handle("GET "+PathPrefixPSP+"/qr/v2/{id}", router.payloadHandler())
+	mux.Handle("GET "+PathPrefixPSP+"/jwks", router.jwksHandler())
+}
+
+func (router PSPRouter) createChargeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req createChargeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.WriteError(w, api.NewError("INVALID_REQUEST", http.StatusBadRequest, "could not parse the charge"))
+			return
+		}
+
+		if err := req.validate(); err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		c := req.toCharge()
+		router.mu.Lock()
+		for _, other := range router.charges {
+			if other.payload.TxID == c.payload.TxID {
+				router.mu.Unlock()
+				api.WriteError(w, api.NewError("INVALID_REQUEST", http.StatusConflict, "the txid is already in use"))
+				return
+			}
+		}
+		id := uuid.NewString()
+		router.charges[id] = c
+		router.mu.Unlock()
+
+		api.WriteJSON(w, router.toChargeResponse(id, c), http.StatusCreated)
+	})
+}
+
+func (router PSPRouter) chargeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router.mu.Lock()
+		defer router.mu.Unlock()
+
+		for id, c := range router.charges {
+			if c.payload.TxID == r.PathValue("txid") {
+				api.WriteJSON(w, router.toChargeResponse(id, c), http.StatusOK)
+				return
+			}
+		}
+
+		api.WriteError(w, api.NewError("NOT_FOUND", http.StatusNotFound, "charge not found"))
+	})
+}
+
+// payloadHandler serves the payload of the charge as a JWS signed with PS256.
+// The "jku" header points to the keys of the stub.
+func (router PSPRouter) payloadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router.mu.Lock()
+		c, ok := router.charges[r.PathValue("id")]
+		router.mu.Unlock()
+		if !ok {
+			api.WriteError(w, api.NewError("NOT_FOUND", http.StatusNotFound, "charge not found"))
+			return
+		}
+
+		jws, err := router.sign(c.payload)
+		if err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentTypeJOSE)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(jws))
+	})
+}
+
+func (router PSPRouter) jwksHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.WriteJSON(w, router.jwks.Public(), http.StatusOK)
+	})
+}
+
+func (router PSPRouter) sign(payload Payload) (string, error) {
+	jwk, err := router.jwks.KeyByAlg(string(goidc.PS256))
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.PS256, Key: jwk},
+		(&jose.SignerOptions{}).WithHeader(headerJKU, router.host+PathPrefixPSP+"/jwks"),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	jws, err := signer.Sign(claims)
+	if err != nil {
+		return "", err
+	}
+
+	return jws.CompactSerialize()
+}
+
+// location is the URL of the payload of the charge without the scheme, as
+// informed by dynamic BR Codes.
+func (router PSPRouter) location(id string) string {
+	host := strings.TrimPrefix(router.host, "https://")
+	return host + PathPrefixPSP + "/qr/v2/" + id
+}
+
+type createChargeRequest struct {
+	TxID     string `json:"txid"`
+	Calendar struct {
+		ExpirationSecs int `json:"expiracao"`
+	} `json:"calendario"`
+	Amount struct {
+		Original string `json:"original"`
+	} `json:"valor"`
+	Key          string `json:"chave"`
+	PayerRequest string `json:"solicitacaoPagador"`
+	// Receiver is not part of the API Pix, it allows informing the merchant
+	// data of the BR Code.
+	Receiver *struct {
+		Name string `json:"nome"`
+		City string `json:"cidade"`
+	} `json:"recebedor"`
+}
+
+func (req createChargeRequest) validate() error {
+	if req.Key == "" {
+		return api.NewError("INVALID_REQUEST", http.StatusBadRequest, "chave is required")
+	}
+
+	if _, err := money.Parse(req.Amount.Original); err != nil {
+		return api.NewError("INVALID_REQUEST", http.StatusBadRequest, "valor.original is invalid")
+	}
+
+	if req.TxID != "" && (len(req.TxID) < minTxIDLength || len(req.TxID) > maxTxIDLength) {
+		return api.NewError("INVALID_REQUEST", http.StatusBadRequest, "txid is invalid")
+	}
+
+	if req.Calendar.ExpirationSecs < 0 {
+		return api.NewError("INVALID_REQUEST", http.StatusBadRequest, "calendario.expiracao is invalid")
+	}
+
+	if len(req.PayerRequest) > maxPayerRequestLength {
+		return api.NewError("INVALID_REQUEST", http.StatusBadRequest, "solicitacaoPagador is too long")
+	}
+
+	return nil
+}
+
+func (req createChargeRequest) toCharge() charge {
+	now := timex.Now()
+	c := charge{
+		payload: Payload{
+			TxID: req.TxID,
+			Calendar: PayloadCalendar{
+				CreationDateTime:     now,
+				PresentationDateTime: now,
+				ExpirationSecs:       req.Calendar.ExpirationSecs,
+			},
+			Status:       ChargeStatusActive,
+			Amount:       PayloadAmount{Original: req.Amount.Original},
+			Key:          req.Key,
+			PayerRequest: req.PayerRequest,
+		},
+		merchantName: strings.ToUpper(mock.MockBankBrand),
+		merchantCity: defaultMerchantCity,
+	}
+
+	if c.payload.TxID == "" {
+		c.payload.TxID = txID()
+	}
+
+	if c.payload.Calendar.ExpirationSecs == 0 {
+		c.payload.Calendar.ExpirationSecs = defaultChargeExpirationSecs
+	}
+
+	if req.Receiver != nil {
+		if req.Receiver.Name != "" {
+			c.merchantName = req.Receiver.Name
+		}
+		if req.Receiver.City != "" {
+			c.merchantCity = req.Receiver.City
+		}
+	}
+
+	return c
+}
+
+type chargeResponse struct {
+	Payload
+	Location string `json:"location"`
+	// PixCopiaECola is the BR Code of the charge.
+	PixCopiaECola string `json:"pixCopiaECola"`
+}
+
+func (router PSPRouter) toChargeResponse(id string, c charge) chargeResponse {
+	location := router.location(id)
+	return chargeResponse{
+		Payload:  c.payload,
+		Location: location,
+		PixCopiaECola: Code{
+			URL:          location,
+			MerchantName: c.merchantName,
+			MerchantCity: c.merchantCity,
+			Dynamic:      true,
+		}.String(),
+	}
+}
+
+// txID generates a random transaction ID as accepted by the API Pix.
+func txID() string {
+	var sb strings.Builder
+	charsetLen := big.NewInt(int64(len(txIDCharset)))
+	for range generatedTxIDLength {
+		n, _ := rand.Int(rand.Reader, charsetLen)
+		sb.WriteByte(txIDCharset[n.Int64()])
+	}
+	return sb.String()
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/luikyv/go-oidc/pkg/provider"
 	"github.com/luikyv/go-open-finance/internal/api"
 	"github.com/luikyv/go-open-finance/internal/api/middleware"
+	"github.com/luikyv/go-open-finance/internal/idempotency"
 	"github.com/luikyv/go-open-finance/internal/page"
 	"github.com/luikyv/go-open-finance/internal/timex"
 )
@@ -20,16 +21,18 @@ var (
 )
 
 type APIRouterV3 struct {
-	host    string
-	service Service
-	op      *provider.Provider
+	host               string
+	service            Service
+	op                 *provider.Provider
+	idempotencyStorage idempotency.Storage
 }
 
-func NewAPIRouterV3(host string, service Service, op *provider.Provider) APIRouterV3 {
+func NewAPIRouterV3(host string, service Service, op *provider.Provider, idempotencyStorage idempotency.Storage) APIRouterV3 {
 	return APIRouterV3{
-		host:    host,
-		service: service,
-		op:      op,
+		host:               host,
+		service:            service,
+		op:                 op,
+		idempotencyStorage: idempotencyStorage,
 	}
 }
 
@@ -49,6 +52,7 @@ func (router APIRouterV3) Register(mux *http.ServeMux) {
 	consentMux.Handle("DELETE /open-banking/consents/v3/consents/{id}", handler)
 
 	handler = router.ExtendHandler()
+	handler = idempotency.Middleware(handler, router.idempotencyStorage)
 	handler = middleware.AuthScopes(handler, router.op, goidc.ScopeOpenID, ScopeID)
 	consentMux.Handle("POST /open-banking/consents/v3/consents/{id}/extends", handler)
 
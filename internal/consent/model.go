@@ -40,8 +40,13 @@ type Consent struct {
 	ExpirationDateTime   *timex.DateTime `bson:"expires_at,omitempty"`
 
 	// Resources consented by the user.
-	AccountID       string `json:"account_id,omitempty"`
-	CreditAccountID string `json:"credit_account_id,omitempty"`
+	AccountID              string   `json:"account_id,omitempty"`
+	CreditAccountID        string   `json:"credit_account_id,omitempty"`
+	LoanIDs                []string `bson:"loan_ids,omitempty"`
+	FinancingIDs           []string `bson:"financing_ids,omitempty"`
+	UnarrangedOverdraftIDs []string `bson:"unarranged_overdraft_ids,omitempty"`
+	InvoiceFinancingIDs    []string `bson:"invoice_financing_ids,omitempty"`
+	BankFixedIncomeIDs     []string `bson:"bank_fixed_income_ids,omitempty"`
 }
 
 // HasAuthExpired returns true if the status is [StatusAwaitingAuthorisation] and
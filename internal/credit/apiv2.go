@@ -0,0 +1,545 @@
+package credit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-oidc/pkg/provider"
+	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/api/middleware"
+	"github.com/luikyv/go-open-finance/internal/consent"
+	"github.com/luikyv/go-open-finance/internal/mock"
+	"github.com/luikyv/go-open-finance/internal/page"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+// ContractService gives access to the consented contracts of a credit
+// operation.
+type ContractService interface {
+	Contracts(ctx context.Context, consentID string, pag page.Pagination) (page.Page[Contract], error)
+	// Contract returns ErrContractNotAllowed if the contract was not consented.
+	Contract(ctx context.Context, id, consentID string) (Contract, error)
+}
+
+// PermissionsV2 are the permissions required by the endpoints of the API of a
+// credit operation.
+type PermissionsV2 struct {
+	Read                     consent.Permission
+	WarrantiesRead           consent.Permission
+	ScheduledInstalmentsRead consent.Permission
+	PaymentsRead             consent.Permission
+}
+
+// APIRouterV2 serves the contracts of a credit operation. The APIs of the
+// credit operations only differ by their path and permissions.
+type APIRouterV2 struct {
+	host string
+	// prefix is the base path of the API, e.g. /open-banking/loans/v2.
+	prefix         string
+	permissions    PermissionsV2
+	service        ContractService
+	consentService consent.Service
+	op             *provider.Provider
+}
+
+func NewAPIRouterV2(
+	host string,
+	prefix string,
+	permissions PermissionsV2,
+	service ContractService,
+	consentService consent.Service,
+	op *provider.Provider,
+) APIRouterV2 {
+	return APIRouterV2{
+		host:           host,
+		prefix:         prefix,
+		permissions:    permissions,
+		service:        service,
+		consentService: consentService,
+		op:             op,
+	}
+}
+
+func (router APIRouterV2) Register(mux *http.ServeMux) {
+	creditMux := http.NewServeMux()
+
+	handler := router.getContractsHandler()
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, router.permissions.Read)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	creditMux.Handle("GET "+router.prefix+"/contracts", handler)
+
+	handler = router.getContractHandler()
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, router.permissions.Read)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	creditMux.Handle("GET "+router.prefix+"/contracts/{id}", handler)
+
+	handler = router.getWarrantiesHandler()
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, router.permissions.WarrantiesRead)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	creditMux.Handle("GET "+router.prefix+"/contracts/{id}/warranties", handler)
+
+	handler = router.getScheduledInstalmentsHandler()
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, router.permissions.ScheduledInstalmentsRead)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	creditMux.Handle("GET "+router.prefix+"/contracts/{id}/scheduled-instalments", handler)
+
+	handler = router.getPaymentsHandler()
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, router.permissions.PaymentsRead)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	creditMux.Handle("GET "+router.prefix+"/contracts/{id}/payments", handler)
+
+	handler = creditMux
+	handler = middleware.Meta(handler, router.host)
+	mux.Handle(router.prefix+"/", handler)
+}
+
+func (router APIRouterV2) getContractsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
+		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
+		pag, err := api.NewPagination(r)
+		if err != nil {
+			writeErrorV2(w, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, err.Error()), true)
+			return
+		}
+
+		contracts, err := router.service.Contracts(r.Context(), consentID, pag)
+		if err != nil {
+			writeErrorV2(w, err, true)
+			return
+		}
+
+		resp := toContractsResponseV2(contracts, reqURL)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV2) getContractHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
+		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
+		contractID := r.PathValue("id")
+
+		contract, err := router.service.Contract(r.Context(), contractID, consentID)
+		if err != nil {
+			writeErrorV2(w, err, true)
+			return
+		}
+
+		resp := toContractResponseV2(contract, reqURL)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV2) getWarrantiesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
+		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
+		contractID := r.PathValue("id")
+		pag, err := api.NewPagination(r)
+		if err != nil {
+			writeErrorV2(w, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, err.Error()), true)
+			return
+		}
+
+		contract, err := router.service.Contract(r.Context(), contractID, consentID)
+		if err != nil {
+			writeErrorV2(w, err, true)
+			return
+		}
+
+		resp := toWarrantiesResponseV2(page.Paginate(contract.Warranties, pag), reqURL)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV2) getScheduledInstalmentsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
+		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
+		contractID := r.PathValue("id")
+		pag, err := api.NewPagination(r)
+		if err != nil {
+			writeErrorV2(w, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, err.Error()), true)
+			return
+		}
+
+		contract, err := router.service.Contract(r.Context(), contractID, consentID)
+		if err != nil {
+			writeErrorV2(w, err, true)
+			return
+		}
+
+		resp := toScheduledInstalmentsResponseV2(contract.Terms.Position(contract.Payments), pag, reqURL)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV2) getPaymentsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
+		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
+		contractID := r.PathValue("id")
+		pag, err := api.NewPagination(r)
+		if err != nil {
+			writeErrorV2(w, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, err.Error()), true)
+			return
+		}
+
+		contract, err := router.service.Contract(r.Context(), contractID, consentID)
+		if err != nil {
+			writeErrorV2(w, err, true)
+			return
+		}
+
+		resp := toPaymentsResponseV2(contract.Terms.Position(contract.Payments), pag, reqURL)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+type contractsResponseV2 struct {
+	Data  []contractSummaryV2 `json:"data"`
+	Meta  api.Meta            `json:"meta"`
+	Links api.Links           `json:"links"`
+}
+
+type contractSummaryV2 struct {
+	ID             string         `json:"contractId"`
+	BrandName      string         `json:"brandName"`
+	CompanyCNPJ    string         `json:"companyCnpj"`
+	ProductType    ProductType    `json:"productType"`
+	ProductSubType ProductSubType `json:"productSubType"`
+	IPOCCode       string         `json:"ipocCode"`
+}
+
+func toContractsResponseV2(contracts page.Page[Contract], reqURL string) contractsResponseV2 {
+	resp := contractsResponseV2{
+		Data:  []contractSummaryV2{},
+		Meta:  api.NewPaginatedMeta(contracts),
+		Links: api.NewPaginatedLinks(reqURL, contracts),
+	}
+
+	for _, contract := range contracts.Records {
+		resp.Data = append(resp.Data, contractSummaryV2{
+			ID:             contract.ID,
+			BrandName:      mock.MockBankBrand,
+			CompanyCNPJ:    mock.MockBankCNPJ,
+			ProductType:    contract.ProductType,
+			ProductSubType: contract.ProductSubType,
+			IPOCCode:       contract.IPOCCode,
+		})
+	}
+
+	return resp
+}
+
+type contractResponseV2 struct {
+	Data  contractV2 `json:"data"`
+	Meta  api.Meta   `json:"meta"`
+	Links api.Links  `json:"links"`
+}
+
+type contractV2 struct {
+	Number                              string               `json:"contractNumber"`
+	IPOCCode                            string               `json:"ipocCode"`
+	ProductName                         string               `json:"productName"`
+	ProductType                         ProductType          `json:"productType"`
+	ProductSubType                      ProductSubType       `json:"productSubType"`
+	Date                                timex.Date           `json:"contractDate"`
+	DisbursementDates                   []timex.Date         `json:"disbursementDates,omitempty"`
+	SettlementDate                      *timex.Date          `json:"settlementDate,omitempty"`
+	Amount                              string               `json:"contractAmount"`
+	Currency                            string               `json:"currency"`
+	DueDate                             timex.Date           `json:"dueDate"`
+	InstalmentPeriodicity               Periodicity          `json:"instalmentPeriodicity"`
+	InstalmentPeriodicityAdditionalInfo string               `json:"instalmentPeriodicityAdditionalInfo,omitempty"`
+	FirstInstalmentDueDate              *timex.Date          `json:"firstInstalmentDueDate,omitempty"`
+	CET                                 string               `json:"CET"`
+	AmortizationSchedule                AmortizationSchedule `json:"amortizationScheduled"`
+	AmortizationScheduleAdditionalInfo  string               `json:"amortizationScheduledAdditionalInfo,omitempty"`
+	ConsigneeCNPJ                       string               `json:"cnpjConsignee,omitempty"`
+	InterestRates                       []interestRateV2     `json:"interestRates"`
+	Fees                                []feeV2              `json:"contractedFees"`
+	FinanceCharges                      []financeChargeV2    `json:"contractedFinanceCharges"`
+}
+
+func toContractResponseV2(contract Contract, reqURL string) contractResponseV2 {
+	terms := contract.Terms
+	data := contractV2{
+		Number:                              contract.Number,
+		IPOCCode:                            contract.IPOCCode,
+		ProductName:                         contract.ProductName,
+		ProductType:                         contract.ProductType,
+		ProductSubType:                      contract.ProductSubType,
+		Date:                                terms.Date,
+		DisbursementDates:                   contract.DisbursementDates,
+		SettlementDate:                      terms.Position(contract.Payments).SettlementDate,
+		Amount:                              terms.Amount,
+		Currency:                            DefaultCurrency,
+		DueDate:                             contract.dueDate(),
+		InstalmentPeriodicity:               terms.InstalmentPeriodicity,
+		InstalmentPeriodicityAdditionalInfo: contract.InstalmentPeriodicityAdditionalInfo,
+		CET:                                 terms.CET(),
+		AmortizationSchedule:                terms.AmortizationSchedule,
+		AmortizationScheduleAdditionalInfo:  contract.AmortizationScheduleAdditionalInfo,
+		ConsigneeCNPJ:                       contract.ConsigneeCNPJ,
+		InterestRates:                       toInterestRatesV2(terms.InterestRate),
+		Fees:                                toFeesV2(terms.Fees),
+		FinanceCharges:                      toFinanceChargesV2(contract.FinanceCharges),
+	}
+
+	// Contracts without a schedule, such as the unarranged overdraft, have no
+	// instalments.
+	if terms.InstalmentNumber > 0 {
+		data.FirstInstalmentDueDate = &terms.FirstInstalmentDueDate
+	}
+
+	return contractResponseV2{
+		Data: data,
+		Meta: api.NewSingleRecordMeta(),
+		Links: api.Links{
+			Self: reqURL,
+		},
+	}
+}
+
+type interestRateV2 struct {
+	TaxType                              TaxType          `json:"taxType"`
+	InterestRateType                     InterestRateType `json:"interestRateType"`
+	TaxPeriodicity                       TaxPeriodicity   `json:"taxPeriodicity"`
+	Calculation                          Calculation      `json:"calculation"`
+	ReferentialRateIndexerType           IndexerType      `json:"referentialRateIndexerType"`
+	ReferentialRateIndexerSubType        IndexerSubType   `json:"referentialRateIndexerSubType,omitempty"`
+	ReferentialRateIndexerAdditionalInfo string           `json:"referentialRateIndexerAdditionalInfo,omitempty"`
+	PreFixedRate                         string           `json:"preFixedRate,omitempty"`
+	PostFixedRate                        string           `json:"postFixedRate,omitempty"`
+	AdditionalInfo                       string           `json:"additionalInfo,omitempty"`
+}
+
+type feeV2 struct {
+	Name       string        `json:"feeName"`
+	Code       string        `json:"feeCode"`
+	ChargeType FeeChargeType `json:"feeChargeType"`
+	Charge     FeeCharge     `json:"feeCharge"`
+	Amount     string        `json:"feeAmount,omitempty"`
+	Rate       string        `json:"feeRate,omitempty"`
+}
+
+type financeChargeV2 struct {
+	Type           FinanceChargeType `json:"chargeType"`
+	AdditionalInfo string            `json:"chargeAdditionalInfo,omitempty"`
+	Rate           string            `json:"chargeRate,omitempty"`
+}
+
+func toInterestRatesV2(rate InterestRate) []interestRateV2 {
+	return []interestRateV2{interestRateV2(rate)}
+}
+
+func toFeesV2(fees []Fee) []feeV2 {
+	feesV2 := []feeV2{}
+	for _, fee := range fees {
+		feesV2 = append(feesV2, feeV2(fee))
+	}
+	return feesV2
+}
+
+func toFinanceChargesV2(charges []FinanceCharge) []financeChargeV2 {
+	chargesV2 := []financeChargeV2{}
+	for _, charge := range charges {
+		chargesV2 = append(chargesV2, financeChargeV2(charge))
+	}
+	return chargesV2
+}
+
+type warrantiesResponseV2 struct {
+	Data  []warrantyV2 `json:"data"`
+	Meta  api.Meta     `json:"meta"`
+	Links api.Links    `json:"links"`
+}
+
+type warrantyV2 struct {
+	Currency string          `json:"currency"`
+	Type     WarrantyType    `json:"warrantyType"`
+	SubType  WarrantySubType `json:"warrantySubType"`
+	Amount   string          `json:"warrantyAmount"`
+}
+
+func toWarrantiesResponseV2(warranties page.Page[Warranty], reqURL string) warrantiesResponseV2 {
+	resp := warrantiesResponseV2{
+		Data:  []warrantyV2{},
+		Meta:  api.NewPaginatedMeta(warranties),
+		Links: api.NewPaginatedLinks(reqURL, warranties),
+	}
+
+	for _, warranty := range warranties.Records {
+		resp.Data = append(resp.Data, warrantyV2{
+			Currency: DefaultCurrency,
+			Type:     warranty.Type,
+			SubType:  warranty.SubType,
+			Amount:   warranty.Amount,
+		})
+	}
+
+	return resp
+}
+
+type scheduledInstalmentsResponseV2 struct {
+	Data  scheduledInstalmentsV2 `json:"data"`
+	Meta  api.Meta               `json:"meta"`
+	Links api.Links              `json:"links"`
+}
+
+type scheduledInstalmentsV2 struct {
+	TotalNumberType InstalmentPeriodType `json:"typeNumberOfInstalments"`
+	TotalNumber     int                  `json:"totalNumberOfInstalments,omitempty"`
+	RemainingType   InstalmentPeriodType `json:"typeContractRemaining"`
+	RemainingNumber int                  `json:"contractRemainingNumber,omitempty"`
+	Paid            int                  `json:"paidInstalments"`
+	Due             int                  `json:"dueInstalments"`
+	PastDue         int                  `json:"pastDueInstalments"`
+	BalloonPayments []balloonPaymentV2   `json:"balloonPayments,omitempty"`
+}
+
+type balloonPaymentV2 struct {
+	DueDate timex.Date       `json:"dueDate"`
+	Amount  amountResponseV2 `json:"amount"`
+}
+
+type amountResponseV2 struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func toScheduledInstalmentsResponseV2(pos Position, pag page.Pagination, reqURL string) scheduledInstalmentsResponseV2 {
+	instalments := pos.Instalments
+	// Balloon payments are the records paginated.
+	balloonPayments := page.Paginate(instalments.BalloonPayments, pag)
+	data := scheduledInstalmentsV2{
+		TotalNumberType: instalments.TotalNumberType,
+		TotalNumber:     instalments.TotalNumber,
+		RemainingType:   instalments.RemainingType,
+		RemainingNumber: instalments.RemainingNumber,
+		Paid:            instalments.Paid,
+		Due:             instalments.Due,
+		PastDue:         instalments.PastDue,
+	}
+
+	for _, p := range balloonPayments.Records {
+		data.BalloonPayments = append(data.BalloonPayments, balloonPaymentV2{
+			DueDate: p.DueDate,
+			Amount: amountResponseV2{
+				Amount:   p.Amount,
+				Currency: DefaultCurrency,
+			},
+		})
+	}
+
+	return scheduledInstalmentsResponseV2{
+		Data:  data,
+		Meta:  api.NewPaginatedMeta(balloonPayments),
+		Links: api.NewPaginatedLinks(reqURL, balloonPayments),
+	}
+}
+
+type paymentsResponseV2 struct {
+	Data  paymentsV2 `json:"data"`
+	Meta  api.Meta   `json:"meta"`
+	Links api.Links  `json:"links"`
+}
+
+type paymentsV2 struct {
+	PaidInstalments    int         `json:"paidInstalments"`
+	OutstandingBalance string      `json:"contractOutstandingBalance"`
+	Releases           []releaseV2 `json:"releases"`
+}
+
+type releaseV2 struct {
+	ID           string        `json:"paymentId,omitempty"`
+	IsOverParcel bool          `json:"isOverParcelPayment"`
+	InstalmentID string        `json:"instalmentId,omitempty"`
+	Date         timex.Date    `json:"paidDate"`
+	Currency     string        `json:"currency"`
+	Amount       string        `json:"paidAmount"`
+	OverParcel   *overParcelV2 `json:"overParcel,omitempty"`
+}
+
+type overParcelV2 struct {
+	Fees    []overParcelFeeV2    `json:"fees"`
+	Charges []overParcelChargeV2 `json:"charges"`
+}
+
+type overParcelFeeV2 struct {
+	Name   string `json:"feeName"`
+	Code   string `json:"feeCode"`
+	Amount string `json:"feeAmount"`
+}
+
+type overParcelChargeV2 struct {
+	Type           FinanceChargeType `json:"chargeType"`
+	AdditionalInfo string            `json:"chargeAdditionalInfo,omitempty"`
+	Amount         string            `json:"chargeAmount"`
+}
+
+// toPaymentsResponseV2 builds the payments response of a contract with the
+// number of instalments paid and what remains to be paid.
+func toPaymentsResponseV2(pos Position, pag page.Pagination, reqURL string) paymentsResponseV2 {
+	// The releases are the records paginated.
+	paymentsPage := page.Paginate(pos.Payments, pag)
+	data := paymentsV2{
+		PaidInstalments:    pos.Instalments.Paid,
+		OutstandingBalance: pos.OutstandingBalance,
+		Releases:           []releaseV2{},
+	}
+
+	for _, p := range paymentsPage.Records {
+		release := releaseV2{
+			ID:           p.ID,
+			IsOverParcel: p.IsOverParcel,
+			InstalmentID: p.InstalmentID,
+			Date:         p.Date,
+			Currency:     DefaultCurrency,
+			Amount:       p.Amount,
+		}
+
+		if p.OverParcel != nil {
+			release.OverParcel = &overParcelV2{
+				Fees:    []overParcelFeeV2{},
+				Charges: []overParcelChargeV2{},
+			}
+			for _, fee := range p.OverParcel.Fees {
+				release.OverParcel.Fees = append(release.OverParcel.Fees, overParcelFeeV2(fee))
+			}
+			for _, charge := range p.OverParcel.Charges {
+				release.OverParcel.Charges = append(release.OverParcel.Charges, overParcelChargeV2(charge))
+			}
+		}
+
+		data.Releases = append(data.Releases, release)
+	}
+
+	return paymentsResponseV2{
+		Data:  data,
+		Meta:  api.NewPaginatedMeta(paymentsPage),
+		Links: api.NewPaginatedLinks(reqURL, paymentsPage),
+	}
+}
+
+func writeErrorV2(w http.ResponseWriter, err error, pagination bool) {
+	if errors.Is(err, ErrContractNotAllowed) {
+		err := api.NewError("FORBIDDEN", http.StatusForbidden, ErrContractNotAllowed.Error())
+		if pagination {
+			err = err.WithPagination()
+		}
+		api.WriteError(w, err)
+		return
+	}
+
+	api.WriteError(w, err)
+}
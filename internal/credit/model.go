@@ -0,0 +1,295 @@
+// Package credit holds what is shared by the APIs of the credit operations,
+// such as the contracts and the router serving them.
+package credit
+
+import (
+	"errors"
+
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+const (
+	DefaultCurrency string = "BRL"
+)
+
+var (
+	ErrContractNotAllowed = errors.New("the contract was not consented")
+)
+
+// Contract is what the APIs of the credit operations inform about a contract.
+// The contracts of each credit operation embed it along with what is specific
+// to them, e.g. who holds the contract.
+type Contract struct {
+	ID             string
+	Number         string
+	IPOCCode       string
+	ProductName    string
+	ProductType    ProductType
+	ProductSubType ProductSubType
+	// Terms are the conditions of the contract from which its instalments and
+	// balances are calculated.
+	Terms             Terms
+	DisbursementDates []timex.Date
+	// DueDate is only informed for contracts without a schedule. The due date
+	// of the others is the one of their last instalment.
+	DueDate                             timex.Date
+	InstalmentPeriodicityAdditionalInfo string
+	AmortizationScheduleAdditionalInfo  string
+	// ConsigneeCNPJ identifies the employer that deducts the instalments of
+	// payroll loans.
+	ConsigneeCNPJ  string
+	FinanceCharges []FinanceCharge
+	Warranties     []Warranty
+	Payments       []Payment
+}
+
+func (c Contract) dueDate() timex.Date {
+	if c.Terms.InstalmentNumber <= 0 {
+		return c.DueDate
+	}
+	return c.Terms.DueDate()
+}
+
+// ProductType is the modality of a credit operation. Each credit operation
+// defines its own product types.
+type ProductType string
+
+// ProductSubType is the submodality of a credit operation. Each credit
+// operation defines its own product sub types.
+type ProductSubType string
+
+type Periodicity string
+
+const (
+	PeriodicityIrregular   Periodicity = "SEM_PERIODICIDADE_REGULAR"
+	PeriodicityWeekly      Periodicity = "SEMANAL"
+	PeriodicityFortnightly Periodicity = "QUINZENAL"
+	PeriodicityMonthly     Periodicity = "MENSAL"
+	PeriodicityBimonthly   Periodicity = "BIMESTRAL"
+	PeriodicityQuarterly   Periodicity = "TRIMESTRAL"
+	PeriodicitySemiannual  Periodicity = "SEMESTRAL"
+	PeriodicityAnnual      Periodicity = "ANUAL"
+	PeriodicityOthers      Periodicity = "OUTROS"
+)
+
+type AmortizationSchedule string
+
+const (
+	AmortizationScheduleSAC    AmortizationSchedule = "SAC"
+	AmortizationSchedulePRICE  AmortizationSchedule = "PRICE"
+	AmortizationScheduleSAM    AmortizationSchedule = "SAM"
+	AmortizationScheduleNone   AmortizationSchedule = "SEM_SISTEMA_AMORTIZACAO"
+	AmortizationScheduleOthers AmortizationSchedule = "OUTROS"
+)
+
+type InterestRate struct {
+	TaxType                              TaxType
+	InterestRateType                     InterestRateType
+	TaxPeriodicity                       TaxPeriodicity
+	Calculation                          Calculation
+	ReferentialRateIndexerType           IndexerType
+	ReferentialRateIndexerSubType        IndexerSubType
+	ReferentialRateIndexerAdditionalInfo string
+	PreFixedRate                         string
+	PostFixedRate                        string
+	AdditionalInfo                       string
+}
+
+type TaxType string
+
+const (
+	TaxTypeNominal   TaxType = "NOMINAL"
+	TaxTypeEffective TaxType = "EFETIVA"
+)
+
+type InterestRateType string
+
+const (
+	InterestRateTypeSimple   InterestRateType = "SIMPLES"
+	InterestRateTypeCompound InterestRateType = "COMPOSTO"
+)
+
+type TaxPeriodicity string
+
+const (
+	TaxPeriodicityMonthly TaxPeriodicity = "AM"
+	TaxPeriodicityYearly  TaxPeriodicity = "AA"
+)
+
+type Calculation string
+
+const (
+	Calculation21Over252 Calculation = "21/252"
+	Calculation30Over360 Calculation = "30/360"
+	Calculation30Over365 Calculation = "30/365"
+)
+
+type IndexerType string
+
+const (
+	IndexerTypeNone         IndexerType = "SEM_TIPO_INDEXADOR"
+	IndexerTypePreFixed     IndexerType = "PRE_FIXADO"
+	IndexerTypePostFixed    IndexerType = "POS_FIXADO"
+	IndexerTypeFloating     IndexerType = "FLUTUANTES"
+	IndexerTypePriceIndexes IndexerType = "INDICES_PRECOS"
+	IndexerTypeRuralCredit  IndexerType = "CREDITO_RURAL"
+	IndexerTypeOthers       IndexerType = "OUTROS_INDEXADORES"
+)
+
+type IndexerSubType string
+
+const (
+	IndexerSubTypeNone   IndexerSubType = "SEM_SUB_TIPO_INDEXADOR"
+	IndexerSubTypePre    IndexerSubType = "PRE_FIXADO"
+	IndexerSubTypeTRTBF  IndexerSubType = "TR_TBF"
+	IndexerSubTypeTJLP   IndexerSubType = "TJLP"
+	IndexerSubTypeTLP    IndexerSubType = "TLP"
+	IndexerSubTypeCDI    IndexerSubType = "CDI"
+	IndexerSubTypeSELIC  IndexerSubType = "SELIC"
+	IndexerSubTypeIGPM   IndexerSubType = "IGPM"
+	IndexerSubTypeIPCA   IndexerSubType = "IPCA"
+	IndexerSubTypeINPC   IndexerSubType = "INPC"
+	IndexerSubTypeOthers IndexerSubType = "OUTROS_INDEXADORES"
+)
+
+type Fee struct {
+	Name       string
+	Code       string
+	ChargeType FeeChargeType
+	Charge     FeeCharge
+	Amount     string
+	Rate       string
+}
+
+type FeeChargeType string
+
+const (
+	FeeChargeTypeSingle        FeeChargeType = "UNICA"
+	FeeChargeTypePerInstalment FeeChargeType = "POR_PARCELA"
+)
+
+type FeeCharge string
+
+const (
+	FeeChargeMinimum    FeeCharge = "MINIMO"
+	FeeChargeMaximum    FeeCharge = "MAXIMO"
+	FeeChargeFixed      FeeCharge = "FIXO"
+	FeeChargePercentage FeeCharge = "PERCENTUAL"
+)
+
+type FinanceCharge struct {
+	Type           FinanceChargeType
+	AdditionalInfo string
+	Rate           string
+}
+
+type FinanceChargeType string
+
+const (
+	FinanceChargeTypeLateInterest FinanceChargeType = "JUROS_REMUNERATORIOS_POR_ATRASO"
+	FinanceChargeTypeLateFine     FinanceChargeType = "MULTA_ATRASO_PAGAMENTO"
+	FinanceChargeTypeDefault      FinanceChargeType = "JUROS_MORA_ATRASO"
+	FinanceChargeTypeIOF          FinanceChargeType = "IOF_CONTRATACAO"
+	FinanceChargeTypeLateIOF      FinanceChargeType = "IOF_POR_ATRASO"
+	FinanceChargeTypeNone         FinanceChargeType = "SEM_ENCARGO"
+	FinanceChargeTypeOthers       FinanceChargeType = "OUTROS"
+)
+
+type Warranty struct {
+	Type    WarrantyType
+	SubType WarrantySubType
+	Amount  string
+}
+
+type WarrantyType string
+
+const (
+	WarrantyTypeCreditRightsAssignment WarrantyType = "CESSAO_DIREITOS_CREDITORIOS"
+	WarrantyTypeBond                   WarrantyType = "CAUCAO"
+	WarrantyTypePledge                 WarrantyType = "PENHOR"
+	WarrantyTypeFiduciaryAlienation    WarrantyType = "ALIENACAO_FIDUCIARIA"
+	WarrantyTypeMortgage               WarrantyType = "HIPOTECA"
+	WarrantyTypeGovernmentGuaranteed   WarrantyType = "OPERACOES_GARANTIDAS_PELO_GOVERNO"
+	WarrantyTypeOthersNonFiduciary     WarrantyType = "OUTRAS_GARANTIAS_NAO_FIDEJUSSORIAS"
+	WarrantyTypeInsurance              WarrantyType = "SEGUROS_ASSEMELHADOS"
+	WarrantyTypeFiduciary              WarrantyType = "GARANTIA_FIDEJUSSORIA"
+	WarrantyTypeLeasedAssets           WarrantyType = "BENS_ARRENDADOS"
+	WarrantyTypeInternational          WarrantyType = "GARANTIAS_INTERNACIONAIS"
+	WarrantyTypeOtherEntitiesGuarantee WarrantyType = "OPERACOES_GARANTIDAS_OUTRAS_ENTIDADES"
+	WarrantyTypeCompensationAgreements WarrantyType = "ACORDOS_COMPENSACAO"
+)
+
+type WarrantySubType string
+
+const (
+	WarrantySubTypeStocks              WarrantySubType = "ACOES_DEBENTURES"
+	WarrantySubTypeFixedIncome         WarrantySubType = "APLICACOES_FINANCEIRAS_RENDA_FIXA"
+	WarrantySubTypeVariableIncome      WarrantySubType = "APLICACOES_FINANCEIRAS_RENDA_VARIAVEL"
+	WarrantySubTypeVehicles            WarrantySubType = "VEICULOS"
+	WarrantySubTypeResidentialProperty WarrantySubType = "IMOVEIS_RESIDENCIAIS"
+	WarrantySubTypeCommercialProperty  WarrantySubType = "IMOVEIS_COMERCIAIS"
+	WarrantySubTypeTradeBills          WarrantySubType = "DUPLICATAS"
+	WarrantySubTypeOthers              WarrantySubType = "OUTROS"
+)
+
+// Instalments summarizes the instalments of a contract.
+type Instalments struct {
+	TotalNumberType InstalmentPeriodType
+	TotalNumber     int
+	RemainingType   InstalmentPeriodType
+	RemainingNumber int
+	Paid            int
+	Due             int
+	PastDue         int
+	BalloonPayments []BalloonPayment
+}
+
+// InstalmentPeriodType is the unit in which the number of instalments of a
+// contract is informed.
+type InstalmentPeriodType string
+
+const (
+	InstalmentPeriodTypeDay     InstalmentPeriodType = "DIA"
+	InstalmentPeriodTypeWeek    InstalmentPeriodType = "SEMANA"
+	InstalmentPeriodTypeMonth   InstalmentPeriodType = "MES"
+	InstalmentPeriodTypeYear    InstalmentPeriodType = "ANO"
+	InstalmentPeriodTypeNoTotal InstalmentPeriodType = "SEM_PRAZO_TOTAL"
+	// InstalmentPeriodTypeNoRemaining is used when no instalments remain.
+	InstalmentPeriodTypeNoRemaining InstalmentPeriodType = "SEM_PRAZO_REMANESCENTE"
+)
+
+// BalloonPayment is an instalment that doesn't follow the regular
+// periodicity of the contract.
+type BalloonPayment struct {
+	DueDate timex.Date
+	Amount  string
+}
+
+type Payment struct {
+	ID string
+	// IsOverParcel indicates whether the payment was not of an instalment,
+	// e.g. an early repayment.
+	IsOverParcel bool
+	InstalmentID string
+	Date         timex.Date
+	Amount       string
+	OverParcel   *OverParcel
+}
+
+// OverParcel details the fees and charges paid along with a payment.
+type OverParcel struct {
+	Fees    []OverParcelFee
+	Charges []OverParcelCharge
+}
+
+type OverParcelFee struct {
+	Name   string
+	Code   string
+	Amount string
+}
+
+type OverParcelCharge struct {
+	Type           FinanceChargeType
+	AdditionalInfo string
+	Amount         string
+}
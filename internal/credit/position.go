@@ -0,0 +1,219 @@
+package credit
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/luikyv/go-open-finance/internal/money"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+// Position is the situation of a contract at a moment in time.
+type Position struct {
+	Instalments Instalments
+	// OutstandingBalance is how much of the principal remains to be paid to
+	// settle the contract.
+	OutstandingBalance string
+	// SettlementDate is when the contract was fully paid, if it was.
+	SettlementDate *timex.Date
+	Payments       []Payment
+}
+
+// Position calculates the situation of the contract today given the payments
+// made so far.
+// Instalments without a payment are past due once their due date is gone, so
+// the position of a contract changes over time even if no payment is made.
+// Over parcel payments amortize the principal with what exceeds their fees and
+// charges.
+func (t Terms) Position(payments []Payment) Position {
+	today := timex.DateNow()
+	pos := Position{
+		Payments: payments,
+	}
+
+	var prepaid int64
+	paidInstalments := map[string]bool{}
+	for _, p := range payments {
+		if p.IsOverParcel {
+			prepaid += p.Amortization()
+		} else {
+			paidInstalments[p.InstalmentID] = true
+		}
+	}
+
+	principal, _ := money.Parse(t.Amount)
+	instalments := t.Schedule()
+	if len(instalments) == 0 {
+		for _, p := range payments {
+			if !p.IsOverParcel {
+				amount, _ := money.Parse(p.Amount)
+				prepaid += amount
+			}
+		}
+		outstanding := max(principal-prepaid, 0)
+		pos.OutstandingBalance = money.Format(outstanding)
+		pos.Instalments = Instalments{
+			TotalNumberType: InstalmentPeriodTypeNoTotal,
+			RemainingType:   InstalmentPeriodTypeNoRemaining,
+			Paid:            len(payments),
+		}
+		if outstanding > 0 {
+			pos.Instalments.Due = 1
+		} else if len(payments) != 0 {
+			pos.SettlementDate = lastPaymentDate(payments)
+		}
+		return pos
+	}
+
+	unit := t.InstalmentPeriodicity.unit()
+	dueDate := t.DueDate()
+	pos.Instalments = Instalments{
+		TotalNumberType: unit,
+		TotalNumber:     unit.between(t.Date, dueDate),
+		RemainingType:   unit,
+		RemainingNumber: unit.between(today, dueDate),
+	}
+	if !dueDate.After(today.Time) {
+		pos.Instalments.RemainingType = InstalmentPeriodTypeNoRemaining
+		pos.Instalments.RemainingNumber = 0
+	}
+
+	var unpaid int64
+	for _, inst := range instalments {
+		if paidInstalments[inst.ID] {
+			pos.Instalments.Paid++
+			continue
+		}
+
+		amount, _ := money.Parse(inst.Principal)
+		unpaid += amount
+		if inst.DueDate.Before(today.Time) {
+			pos.Instalments.PastDue++
+		} else {
+			pos.Instalments.Due++
+		}
+	}
+
+	// Contracts without an amortization system pay the principal at once in
+	// the last instalment, which is then informed as a balloon payment.
+	if !t.AmortizationSchedule.amortizes() {
+		last := instalments[len(instalments)-1]
+		if !paidInstalments[last.ID] {
+			pos.Instalments.BalloonPayments = []BalloonPayment{{
+				DueDate: last.DueDate,
+				Amount:  last.Amount,
+			}}
+		}
+	}
+
+	outstanding := max(unpaid-prepaid, 0)
+	pos.OutstandingBalance = money.Format(outstanding)
+	if outstanding == 0 {
+		pos.SettlementDate = lastPaymentDate(payments)
+	}
+	return pos
+}
+
+// LateCharges calculates the charges for paying an instalment after its due
+// date according to the finance charges of the contract, i.e. the late fine
+// over the amount of the instalment and the default interest for the days in
+// arrears. Default interest rates are yearly.
+func LateCharges(inst Instalment, paymentDate timex.Date, charges []FinanceCharge) []OverParcelCharge {
+	days := int(paymentDate.Sub(inst.DueDate.Time) / (24 * time.Hour))
+	if days <= 0 {
+		return nil
+	}
+
+	amount, _ := money.Parse(inst.Amount)
+	var overParcelCharges []OverParcelCharge
+	for _, charge := range charges {
+		rate, _ := strconv.ParseFloat(charge.Rate, 64)
+		var chargeAmount float64
+		switch charge.Type {
+		case FinanceChargeTypeLateFine:
+			chargeAmount = float64(amount) * rate
+		case FinanceChargeTypeDefault:
+			chargeAmount = float64(amount) * (math.Pow(1+rate, float64(days)/360) - 1)
+		default:
+			continue
+		}
+
+		overParcelCharges = append(overParcelCharges, OverParcelCharge{
+			Type:   charge.Type,
+			Amount: money.Format(int64(math.Round(chargeAmount))),
+		})
+	}
+	return overParcelCharges
+}
+
+// Amortization returns how much of the payment amortizes the principal, i.e.
+// what exceeds its fees and charges.
+func (p Payment) Amortization() int64 {
+	amount, _ := money.Parse(p.Amount)
+	if p.OverParcel == nil {
+		return amount
+	}
+
+	for _, fee := range p.OverParcel.Fees {
+		feeAmount, _ := money.Parse(fee.Amount)
+		amount -= feeAmount
+	}
+	for _, charge := range p.OverParcel.Charges {
+		chargeAmount, _ := money.Parse(charge.Amount)
+		amount -= chargeAmount
+	}
+	return max(amount, 0)
+}
+
+func lastPaymentDate(payments []Payment) *timex.Date {
+	if len(payments) == 0 {
+		return nil
+	}
+
+	last := slices.MaxFunc(payments, func(p1, p2 Payment) int {
+		return p1.Date.Compare(p2.Date.Time)
+	})
+	return &last.Date
+}
+
+// amortizes returns whether the principal is paid along the instalments.
+func (s AmortizationSchedule) amortizes() bool {
+	return s == AmortizationSchedulePRICE || s == AmortizationScheduleSAC || s == AmortizationScheduleSAM
+}
+
+// unit returns the unit in which the term of contracts with the periodicity
+// is informed.
+func (p Periodicity) unit() InstalmentPeriodType {
+	switch p {
+	case PeriodicityIrregular:
+		return InstalmentPeriodTypeDay
+	case PeriodicityWeekly, PeriodicityFortnightly:
+		return InstalmentPeriodTypeWeek
+	default:
+		return InstalmentPeriodTypeMonth
+	}
+}
+
+// between counts how many units there are from one date to another, rounding
+// partial units up.
+func (u InstalmentPeriodType) between(from, to timex.Date) int {
+	if !to.After(from.Time) {
+		return 0
+	}
+
+	days := int(to.Sub(from.Time) / (24 * time.Hour))
+	switch u {
+	case InstalmentPeriodTypeDay:
+		return days
+	case InstalmentPeriodTypeWeek:
+		return (days + 6) / 7
+	default:
+		months := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+		if from.AddDate(0, months, 0).Before(to.Time) {
+			months++
+		}
+		return months
+	}
+}
@@ -0,0 +1,250 @@
+package credit
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/luikyv/go-open-finance/internal/money"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+// Terms are the conditions under which the credit of a contract was granted.
+// The instalments, the outstanding balance and the total effective cost of the
+// contract are all derived from them.
+type Terms struct {
+	// Amount is the principal granted.
+	Amount string
+	Date   timex.Date
+	// InstalmentNumber is how many instalments the contract is paid in. Zero
+	// means the contract has no schedule, e.g. the unarranged overdraft of an
+	// account, which is paid whenever the account has funds.
+	InstalmentNumber       int
+	InstalmentPeriodicity  Periodicity
+	FirstInstalmentDueDate timex.Date
+	// AmortizationSchedule defines how the principal is paid back. Contracts
+	// without an amortization system pay only interest in the instalments and
+	// all the principal in the last one (bullet).
+	AmortizationSchedule AmortizationSchedule
+	InterestRate         InterestRate
+	Fees                 []Fee
+}
+
+// Instalment is an instalment of the schedule of a contract.
+type Instalment struct {
+	// ID is the sequential number of the instalment starting from 1.
+	ID      string
+	DueDate timex.Date
+	// Amount is the principal plus the interest of the instalment.
+	Amount    string
+	Principal string
+	Interest  string
+}
+
+// Schedule generates the instalments of the contract.
+// Amounts are calculated in cents and the last instalment absorbs the rounding
+// differences, so the principal of the instalments always adds up to the
+// amount of the contract.
+func (t Terms) Schedule() []Instalment {
+	n := t.InstalmentNumber
+	if n <= 0 {
+		return nil
+	}
+
+	principal, _ := money.Parse(t.Amount)
+	balance := float64(principal)
+	rate := t.periodRate()
+	// The fixed payment of the PRICE table, also used by SAM, which averages
+	// PRICE and SAC.
+	pmt := balance / float64(n)
+	if rate > 0 {
+		pmt = balance * rate / (1 - math.Pow(1+rate, -float64(n)))
+	}
+
+	var instalments []Instalment
+	previousDueDate := t.Date
+	// SAM amortizes the average of what PRICE and SAC would, so the balance of
+	// the PRICE table is tracked as well.
+	priceBalance := balance
+	for i := range n {
+		dueDate := t.instalmentDueDate(i)
+		var interest float64
+		switch t.AmortizationSchedule {
+		case AmortizationSchedulePRICE, AmortizationScheduleSAC, AmortizationScheduleSAM:
+			interest = math.Round(balance * rate)
+		default:
+			// Without an amortization system, the interest is due for the days
+			// elapsed since the previous instalment.
+			interest = math.Round(balance * t.rateFor(days360(previousDueDate, dueDate)))
+		}
+
+		var amortization float64
+		switch t.AmortizationSchedule {
+		case AmortizationSchedulePRICE:
+			amortization = math.Round(pmt) - interest
+		case AmortizationScheduleSAC:
+			amortization = math.Round(float64(principal) / float64(n))
+		case AmortizationScheduleSAM:
+			priceAmortization := math.Round(pmt) - math.Round(priceBalance*rate)
+			priceBalance -= priceAmortization
+			amortization = math.Round((priceAmortization + float64(principal)/float64(n)) / 2)
+		}
+		if i == n-1 {
+			amortization = balance
+		}
+		balance -= amortization
+
+		instalments = append(instalments, Instalment{
+			ID:        strconv.Itoa(i + 1),
+			DueDate:   dueDate,
+			Amount:    money.Format(int64(amortization + interest)),
+			Principal: money.Format(int64(amortization)),
+			Interest:  money.Format(int64(interest)),
+		})
+		previousDueDate = dueDate
+	}
+
+	return instalments
+}
+
+// DueDate returns the date when the last instalment of the contract is due.
+// Contracts without a schedule have no due date.
+func (t Terms) DueDate() timex.Date {
+	if t.InstalmentNumber <= 0 {
+		return timex.Date{}
+	}
+	return t.instalmentDueDate(t.InstalmentNumber - 1)
+}
+
+// CET calculates the total effective cost (custo efetivo total) of the
+// contract per year, i.e. the yearly rate that discounts the instalments and
+// fees to the amount released to the customer.
+// For contracts without a schedule, it is the effective yearly interest rate.
+func (t Terms) CET() string {
+	instalments := t.Schedule()
+	if len(instalments) == 0 {
+		return formatRate(math.Pow(1+t.monthlyRate(), 12) - 1)
+	}
+
+	principal, _ := money.Parse(t.Amount)
+	released := float64(principal)
+	var instalmentFees float64
+	for _, fee := range t.Fees {
+		amount, _ := money.Parse(fee.Amount)
+		if fee.Charge == FeeChargePercentage {
+			feeRate, _ := strconv.ParseFloat(fee.Rate, 64)
+			amount = int64(math.Round(float64(principal) * feeRate))
+		}
+
+		if fee.ChargeType == FeeChargeTypePerInstalment {
+			instalmentFees += float64(amount)
+		} else {
+			released -= float64(amount)
+		}
+	}
+
+	// presentValue discounts the instalments to the date of the contract with
+	// the monthly rate informed.
+	presentValue := func(monthlyRate float64) float64 {
+		var pv float64
+		for _, inst := range instalments {
+			amount, _ := money.Parse(inst.Amount)
+			months := float64(days360(t.Date, inst.DueDate)) / 30
+			pv += (float64(amount) + instalmentFees) / math.Pow(1+monthlyRate, months)
+		}
+		return pv
+	}
+
+	// The present value decreases as the rate increases, then the rate that
+	// matches the amount released is found by bisection.
+	low, high := 0.0, 1.0
+	for range 100 {
+		mid := (low + high) / 2
+		if presentValue(mid) > released {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	return formatRate(math.Pow(1+low, 12) - 1)
+}
+
+// instalmentDueDate returns the due date of the i-th instalment starting from
+// zero.
+// Instalments due monthly or less often keep the day of the first one, or fall
+// on the last day of months that are shorter, instead of rolling into the next
+// month.
+func (t Terms) instalmentDueDate(i int) timex.Date {
+	months, days := t.InstalmentPeriodicity.step()
+	first := t.FirstInstalmentDueDate
+	if months == 0 {
+		return timex.NewDate(first.AddDate(0, 0, i*days))
+	}
+
+	year, month, day := first.Date()
+	monthStart := time.Date(year, month+time.Month(i*months), 1, 0, 0, 0, 0, first.Location())
+	lastDay := monthStart.AddDate(0, 1, -1).Day()
+	return timex.NewDate(monthStart.AddDate(0, 0, min(day, lastDay)-1))
+}
+
+// periodRate returns the interest rate for the period between two
+// instalments.
+func (t Terms) periodRate() float64 {
+	months, days := t.InstalmentPeriodicity.step()
+	return t.rateFor(months*30 + days)
+}
+
+// rateFor returns the interest rate for a period of days in the 30/360
+// convention.
+func (t Terms) rateFor(days int) float64 {
+	if t.InterestRate.InterestRateType == InterestRateTypeSimple {
+		return t.monthlyRate() * float64(days) / 30
+	}
+	return math.Pow(1+t.monthlyRate(), float64(days)/30) - 1
+}
+
+// monthlyRate returns the effective monthly interest rate of the contract.
+func (t Terms) monthlyRate() float64 {
+	rate, _ := strconv.ParseFloat(t.InterestRate.PreFixedRate, 64)
+	if t.InterestRate.TaxPeriodicity == TaxPeriodicityMonthly {
+		return rate
+	}
+
+	if t.InterestRate.TaxType == TaxTypeNominal {
+		return rate / 12
+	}
+	return math.Pow(1+rate, 1.0/12) - 1
+}
+
+// step returns the time between two instalments. Irregular periodicities are
+// treated as monthly.
+func (p Periodicity) step() (months, days int) {
+	switch p {
+	case PeriodicityWeekly:
+		return 0, 7
+	case PeriodicityFortnightly:
+		return 0, 15
+	case PeriodicityBimonthly:
+		return 2, 0
+	case PeriodicityQuarterly:
+		return 3, 0
+	case PeriodicitySemiannual:
+		return 6, 0
+	case PeriodicityAnnual:
+		return 12, 0
+	default:
+		return 1, 0
+	}
+}
+
+// days360 counts the days from one date to another as if every month had 30
+// days.
+func days360(from, to timex.Date) int {
+	fromDay, toDay := min(from.Day(), 30), min(to.Day(), 30)
+	return (to.Year()-from.Year())*360 + (int(to.Month())-int(from.Month()))*30 + toDay - fromDay
+}
+
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', 4, 64)
+}
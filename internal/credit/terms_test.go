@@ -0,0 +1,315 @@
+package credit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+func TestSchedule(t *testing.T) {
+	testCases := []struct {
+		name  string
+		terms Terms
+		// want holds the amount, principal and interest of each instalment.
+		want [][3]string
+	}{
+		{
+			// The classic PRICE table of 1000.00 at 1% a month in 3
+			// instalments, with the last one absorbing the rounding.
+			name:  "PRICE",
+			terms: monthlyTerms("1000.00", 3, AmortizationSchedulePRICE, "0.01"),
+			want: [][3]string{
+				{"340.02", "330.02", "10.00"},
+				{"340.02", "333.32", "6.70"},
+				{"340.03", "336.66", "3.37"},
+			},
+		},
+		{
+			name:  "SAC",
+			terms: monthlyTerms("1200.00", 3, AmortizationScheduleSAC, "0.01"),
+			want: [][3]string{
+				{"412.00", "400.00", "12.00"},
+				{"408.00", "400.00", "8.00"},
+				{"404.00", "400.00", "4.00"},
+			},
+		},
+		{
+			// SAM amortizes the average of the PRICE amortizations (396.03,
+			// 399.99, 403.98) and the SAC ones (400.00).
+			name:  "SAM",
+			terms: monthlyTerms("1200.00", 3, AmortizationScheduleSAM, "0.01"),
+			want: [][3]string{
+				{"410.02", "398.02", "12.00"},
+				{"408.02", "400.00", "8.02"},
+				{"406.00", "401.98", "4.02"},
+			},
+		},
+		{
+			// Without an amortization system, the instalments pay only the
+			// interest and the last one pays the principal as well.
+			name:  "bullet with monthly interest",
+			terms: monthlyTerms("1000.00", 3, AmortizationScheduleNone, "0.02"),
+			want: [][3]string{
+				{"20.00", "0.00", "20.00"},
+				{"20.00", "0.00", "20.00"},
+				{"1020.00", "1000.00", "20.00"},
+			},
+		},
+		{
+			// The interest is compounded for the 60 days until the single
+			// instalment.
+			name: "bullet",
+			terms: Terms{
+				Amount:                 "1000.00",
+				Date:                   date(t, "2024-01-15"),
+				InstalmentNumber:       1,
+				InstalmentPeriodicity:  PeriodicityIrregular,
+				FirstInstalmentDueDate: date(t, "2024-03-15"),
+				AmortizationSchedule:   AmortizationScheduleNone,
+				InterestRate:           monthlyRate("0.02"),
+			},
+			want: [][3]string{
+				{"1040.40", "1000.00", "40.40"},
+			},
+		},
+		{
+			name: "no schedule",
+			terms: Terms{
+				Amount:                "1000.00",
+				Date:                  date(t, "2024-01-15"),
+				InstalmentPeriodicity: PeriodicityIrregular,
+				AmortizationSchedule:  AmortizationScheduleNone,
+				InterestRate:          monthlyRate("0.08"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got [][3]string
+			for _, inst := range tc.terms.Schedule() {
+				got = append(got, [3]string{inst.Amount, inst.Principal, inst.Interest})
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSchedule_DueDates(t *testing.T) {
+	testCases := []struct {
+		name        string
+		periodicity Periodicity
+		first       string
+		want        []string
+	}{
+		{
+			name:        "monthly from the last day of the month",
+			periodicity: PeriodicityMonthly,
+			first:       "2024-01-31",
+			want:        []string{"2024-01-31", "2024-02-29", "2024-03-31", "2024-04-30"},
+		},
+		{
+			name:        "monthly from the 29th in a common year",
+			periodicity: PeriodicityMonthly,
+			first:       "2023-01-29",
+			want:        []string{"2023-01-29", "2023-02-28", "2023-03-29", "2023-04-29"},
+		},
+		{
+			name:        "monthly across the year",
+			periodicity: PeriodicityMonthly,
+			first:       "2024-11-30",
+			want:        []string{"2024-11-30", "2024-12-30", "2025-01-30", "2025-02-28"},
+		},
+		{
+			name:        "quarterly",
+			periodicity: PeriodicityQuarterly,
+			first:       "2023-11-30",
+			want:        []string{"2023-11-30", "2024-02-29", "2024-05-30", "2024-08-30"},
+		},
+		{
+			name:        "weekly",
+			periodicity: PeriodicityWeekly,
+			first:       "2024-02-22",
+			want:        []string{"2024-02-22", "2024-02-29", "2024-03-07", "2024-03-14"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			terms := Terms{
+				Amount:                 "1000.00",
+				Date:                   date(t, tc.first),
+				InstalmentNumber:       len(tc.want),
+				InstalmentPeriodicity:  tc.periodicity,
+				FirstInstalmentDueDate: date(t, tc.first),
+				AmortizationSchedule:   AmortizationScheduleSAC,
+				InterestRate:           monthlyRate("0.01"),
+			}
+
+			var got []string
+			for _, inst := range terms.Schedule() {
+				got = append(got, inst.DueDate.String())
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+
+			if due := terms.DueDate().String(); due != tc.want[len(tc.want)-1] {
+				t.Errorf("got due date %s, want %s", due, tc.want[len(tc.want)-1])
+			}
+		})
+	}
+}
+
+func TestCET(t *testing.T) {
+	testCases := []struct {
+		name  string
+		terms Terms
+		want  string
+	}{
+		{
+			// Without fees, the CET is the effective yearly interest rate,
+			// i.e. 1.01^12 - 1.
+			name:  "without fees",
+			terms: monthlyTerms("1000.00", 12, AmortizationSchedulePRICE, "0.01"),
+			want:  "0.1268",
+		},
+		{
+			// The single instalment of 1010.00 discounted to the 950.00
+			// released, i.e. (1010 / 950)^12 - 1.
+			name: "with a single fee",
+			terms: withFees(monthlyTerms("1000.00", 1, AmortizationSchedulePRICE, "0.01"), Fee{
+				ChargeType: FeeChargeTypeSingle,
+				Charge:     FeeChargeFixed,
+				Amount:     "50.00",
+			}),
+			want: "1.0853",
+		},
+		{
+			// A 5% fee over the principal is the same as the 50.00 above.
+			name: "with a percentage fee",
+			terms: withFees(monthlyTerms("1000.00", 1, AmortizationSchedulePRICE, "0.01"), Fee{
+				ChargeType: FeeChargeTypeSingle,
+				Charge:     FeeChargePercentage,
+				Rate:       "0.05",
+			}),
+			want: "1.0853",
+		},
+		{
+			// The 12 instalments of 88.85 plus 2.00 discounted to 1000.00.
+			name: "with a fee per instalment",
+			terms: withFees(monthlyTerms("1000.00", 12, AmortizationSchedulePRICE, "0.01"), Fee{
+				ChargeType: FeeChargeTypePerInstalment,
+				Charge:     FeeChargeFixed,
+				Amount:     "2.00",
+			}),
+			want: "0.1752",
+		},
+		{
+			// Contracts without a schedule inform the effective yearly rate,
+			// i.e. 1.08^12 - 1.
+			name: "no schedule",
+			terms: Terms{
+				Amount:                "1000.00",
+				Date:                  date(t, "2024-01-15"),
+				InstalmentPeriodicity: PeriodicityIrregular,
+				AmortizationSchedule:  AmortizationScheduleNone,
+				InterestRate:          monthlyRate("0.08"),
+			},
+			want: "1.5182",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.terms.CET(); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMonthlyRate(t *testing.T) {
+	testCases := []struct {
+		name string
+		rate InterestRate
+		want float64
+	}{
+		{
+			name: "monthly",
+			rate: monthlyRate("0.01"),
+			want: 0.01,
+		},
+		{
+			name: "effective yearly",
+			rate: InterestRate{
+				TaxType:        TaxTypeEffective,
+				TaxPeriodicity: TaxPeriodicityYearly,
+				PreFixedRate:   "0.126825",
+			},
+			want: 0.01,
+		},
+		{
+			name: "nominal yearly",
+			rate: InterestRate{
+				TaxType:        TaxTypeNominal,
+				TaxPeriodicity: TaxPeriodicityYearly,
+				PreFixedRate:   "0.12",
+			},
+			want: 0.01,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Terms{InterestRate: tc.rate}.monthlyRate()
+			if diff := got - tc.want; diff > 1e-6 || diff < -1e-6 {
+				t.Errorf("got %f, want %f", got, tc.want)
+			}
+		})
+	}
+}
+
+// monthlyTerms returns the terms of a contract with monthly instalments, the
+// first one due a month after the contract.
+func monthlyTerms(amount string, n int, schedule AmortizationSchedule, rate string) Terms {
+	contractDate, _ := timex.ParseDate("2024-01-10")
+	firstDueDate, _ := timex.ParseDate("2024-02-10")
+	return Terms{
+		Amount:                 amount,
+		Date:                   contractDate,
+		InstalmentNumber:       n,
+		InstalmentPeriodicity:  PeriodicityMonthly,
+		FirstInstalmentDueDate: firstDueDate,
+		AmortizationSchedule:   schedule,
+		InterestRate:           monthlyRate(rate),
+	}
+}
+
+func withFees(terms Terms, fees ...Fee) Terms {
+	terms.Fees = fees
+	return terms
+}
+
+func monthlyRate(rate string) InterestRate {
+	return InterestRate{
+		TaxType:          TaxTypeEffective,
+		InterestRateType: InterestRateTypeCompound,
+		TaxPeriodicity:   TaxPeriodicityMonthly,
+		PreFixedRate:     rate,
+	}
+}
+
+func date(t *testing.T, s string) timex.Date {
+	t.Helper()
+	d, err := timex.ParseDate(s)
+	if err != nil {
+		t.Fatalf("invalid date %s: %v", s, err)
+	}
+	return d
+}
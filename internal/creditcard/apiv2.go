@@ -1,7 +1,10 @@
 package creditcard
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/luikyv/go-oidc/pkg/goidc"
 	"github.com/luikyv/go-oidc/pkg/provider"
@@ -10,6 +13,7 @@ import (
 	"github.com/luikyv/go-open-finance/internal/consent"
 	"github.com/luikyv/go-open-finance/internal/mock"
 	"github.com/luikyv/go-open-finance/internal/page"
+	"github.com/luikyv/go-open-finance/internal/timex"
 )
 
 type APIRouterV2 struct {
@@ -43,6 +47,36 @@ func (router APIRouterV2) Register(mux *http.ServeMux) {
 	handler = middleware.FAPIIDWithPagination(handler)
 	creditCardMux.Handle("GET /open-banking/credit-cards-accounts/v2/accounts/{id}", handler)
 
+	handler = router.getAccountLimitsHandler()
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, consent.PermissionCreditCardsAccountsLimitsRead)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	creditCardMux.Handle("GET /open-banking/credit-cards-accounts/v2/accounts/{id}/limits", handler)
+
+	handler = router.getAccountTransactionsHandler(false)
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, consent.PermissionCreditCardsAccountsTransactionsRead)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	creditCardMux.Handle("GET /open-banking/credit-cards-accounts/v2/accounts/{id}/transactions", handler)
+
+	handler = router.getAccountTransactionsHandler(true)
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, consent.PermissionCreditCardsAccountsTransactionsRead)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	creditCardMux.Handle("GET /open-banking/credit-cards-accounts/v2/accounts/{id}/transactions-current", handler)
+
+	handler = router.getAccountBillsHandler()
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, consent.PermissionCreditCardsAccountsBillsRead)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	creditCardMux.Handle("GET /open-banking/credit-cards-accounts/v2/accounts/{id}/bills", handler)
+
+	handler = router.getAccountBillTransactionsHandler()
+	handler = consent.PermissionMiddlewareWithPagination(handler, router.consentService, consent.PermissionCreditCardsAccountsBillsTransactionsRead)
+	handler = middleware.AuthScopesWithPagination(handler, router.op, goidc.ScopeOpenID, consent.ScopeID)
+	handler = middleware.FAPIIDWithPagination(handler)
+	creditCardMux.Handle("GET /open-banking/credit-cards-accounts/v2/accounts/{id}/bills/{billId}/transactions", handler)
+
 	handler = creditCardMux
 	handler = middleware.Meta(handler, router.host)
 	mux.Handle("/open-banking/credit-cards-accounts/v2/", handler)
@@ -86,22 +120,112 @@ func (router APIRouterV2) getAccountHandler() http.Handler {
 	})
 }
 
-// func (router APIRouterV2) getAccountLimitsHandler() http.Handler {
-// 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-// 		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
-// 		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
-// 		cardID := r.PathValue("id")
+func (router APIRouterV2) getAccountLimitsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
+		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
+		cardID := r.PathValue("id")
+		pag, err := api.NewPagination(r)
+		if err != nil {
+			writeErrorV2(w, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, err.Error()), true)
+			return
+		}
+
+		limits, err := router.service.limits(r.Context(), cardID, consentID, pag)
+		if err != nil {
+			writeErrorV2(w, err, true)
+			return
+		}
+
+		resp := toAccountLimitsResponseV2(limits, reqURL)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV2) getAccountTransactionsHandler(current bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
+		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
+		cardID := r.PathValue("id")
+		pag, err := api.NewPagination(r)
+		if err != nil {
+			writeErrorV2(w, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, err.Error()), true)
+			return
+		}
+
+		filter, err := newTransactionFilter(r, current)
+		if err != nil {
+			writeErrorV2(w, err, true)
+			return
+		}
+
+		trs, err := router.service.transactions(r.Context(), cardID, consentID, filter, pag)
+		if err != nil {
+			writeErrorV2(w, err, true)
+			return
+		}
+
+		resp := toTransactionsResponseV2(trs, reqURL)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV2) getAccountBillsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
+		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
+		cardID := r.PathValue("id")
+		pag, err := api.NewPagination(r)
+		if err != nil {
+			writeErrorV2(w, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, err.Error()), true)
+			return
+		}
 
-// 		acc, err := router.service.account(r.Context(), cardID, consentID)
-// 		if err != nil {
-// 			writeErrorV2(w, err, true)
-// 			return
-// 		}
+		filter, err := newBillFilter(r)
+		if err != nil {
+			writeErrorV2(w, err, true)
+			return
+		}
 
-// 		resp := toAccountLimitsResponseV2(acc, reqURL)
-// 		api.WriteJSON(w, resp, http.StatusOK)
-// 	})
-// }
+		bills, err := router.service.bills(r.Context(), cardID, consentID, filter, pag)
+		if err != nil {
+			writeErrorV2(w, err, true)
+			return
+		}
+
+		resp := toBillsResponseV2(bills, reqURL)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV2) getAccountBillTransactionsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consentID := r.Context().Value(api.CtxKeyConsentID).(string)
+		reqURL := r.Context().Value(api.CtxKeyRequestURL).(string)
+		cardID := r.PathValue("id")
+		billID := r.PathValue("billId")
+		pag, err := api.NewPagination(r)
+		if err != nil {
+			writeErrorV2(w, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, err.Error()), true)
+			return
+		}
+
+		filter, err := newBillTransactionFilter(r)
+		if err != nil {
+			writeErrorV2(w, err, true)
+			return
+		}
+
+		trs, err := router.service.billTransactions(r.Context(), cardID, billID, consentID, filter, pag)
+		if err != nil {
+			writeErrorV2(w, err, true)
+			return
+		}
+
+		resp := toTransactionsResponseV2(trs, reqURL)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
 
 type accountsResponseV2 struct {
 	Data  []accountV2 `json:"data"`
@@ -188,64 +312,338 @@ func toAccountResponseV2(acc Account, reqURL string) accountResponseV2 {
 	return resp
 }
 
-// type accountLimitsResponseV2 struct {
-// 	Data  []accountLimitV2 `json:"data"`
-// 	Meta  api.Meta         `json:"meta"`
-// 	Links api.Links        `json:"links"`
-// }
-
-// type accountLimitV2 struct {
-// 	CreditLineLimitType  string           `json:"creditLineLimitType"`
-// 	ConsolidationType    string           `json:"consolidationType"`
-// 	IdentificationNumber string           `json:"identificationNumber"`
-// 	IsLimitFlexible      bool             `json:"isLimitFlexible"`
-// 	LimitAmount          amountResponseV2 `json:"limitAmount"`
-// 	UsedAmount           amountResponseV2 `json:"usedAmount"`
-// 	AvailableAmount      amountResponseV2 `json:"availableAmount"`
-// }
-
-// type amountResponseV2 struct {
-// 	Amount   string `json:"amount"`
-// 	Currency string `json:"currency"`
-// }
-
-// func toAccountLimitsResponseV2(acc Account, reqURL string) accountLimitsResponseV2 {
-// 	resp := accountLimitsResponseV2{
-// 		Meta: api.NewSingleRecordMeta(),
-// 		Links: api.Links{
-// 			Self: reqURL,
-// 		},
-// 	}
-
-// 	cards := append([]Card{acc.MainCard}, acc.AdditionalCards...)
-// 	for _, card := range cards {
-// 		resp.Data = append(resp.Data, accountLimitV2{
-// 			CreditLineLimitType:  "LIMITE_CREDITO_TOTAL",
-// 			ConsolidationType:    "CONSOLIDADO",
-// 			IdentificationNumber: last4Digits(card.Number),
-// 			LimitAmount: amountResponseV2{
-// 				Amount:   acc.LimitAmount,
-// 				Currency: defaultCurrency,
-// 			},
-// 			UsedAmount: amountResponseV2{
-// 				Amount:   acc.UsedAmount,
-// 				Currency: defaultCurrency,
-// 			},
-// 			AvailableAmount: amountResponseV2{
-// 				Amount:   acc.LimitAmount,
-// 				Currency: defaultCurrency,
-// 			},
-// 		})
-// 	}
-
-// 	return resp
-// }
-
-func writeErrorV2(w http.ResponseWriter, err error, _ bool) {
-	api.WriteError(w, err)
+type accountLimitsResponseV2 struct {
+	Data  []accountLimitV2 `json:"data"`
+	Meta  api.Meta         `json:"meta"`
+	Links api.Links        `json:"links"`
+}
+
+type accountLimitV2 struct {
+	CreditLineLimitType    LimitType         `json:"creditLineLimitType"`
+	ConsolidationType      ConsolidationType `json:"consolidationType"`
+	IdentificationNumber   string            `json:"identificationNumber"`
+	LineName               LineName          `json:"lineName,omitempty"`
+	LineNameAdditionalInfo string            `json:"lineNameAdditionalInfo,omitempty"`
+	IsLimitFlexible        bool              `json:"isLimitFlexible"`
+	LimitAmount            *amountResponseV2 `json:"limitAmount,omitempty"`
+	UsedAmount             amountResponseV2  `json:"usedAmount"`
+	AvailableAmount        *amountResponseV2 `json:"availableAmount,omitempty"`
 }
 
-// func last4Digits(cardNumber string) string {
-// 	length := len(cardNumber)
-// 	return cardNumber[length-4 : length-1]
-// }
+type amountResponseV2 struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func toAccountLimitsResponseV2(limits page.Page[Limit], reqURL string) accountLimitsResponseV2 {
+	resp := accountLimitsResponseV2{
+		Data: []accountLimitV2{},
+		Meta: api.NewPaginatedMeta(limits),
+		Links: api.Links{
+			Self: reqURL,
+		},
+	}
+
+	for _, limit := range limits.Records {
+		data := accountLimitV2{
+			CreditLineLimitType:    limit.Type,
+			ConsolidationType:      limit.ConsolidationType,
+			IdentificationNumber:   limit.Card.IdentificationNumber(),
+			LineName:               limit.LineName,
+			LineNameAdditionalInfo: limit.LineNameAdditionalInfo,
+			IsLimitFlexible:        limit.IsFlexible,
+			UsedAmount: amountResponseV2{
+				Amount:   limit.UsedAmount,
+				Currency: defaultCurrency,
+			},
+		}
+
+		// Flexible limits may not have a defined amount.
+		if limit.LimitAmount != "" {
+			data.LimitAmount = &amountResponseV2{
+				Amount:   limit.LimitAmount,
+				Currency: defaultCurrency,
+			}
+			data.AvailableAmount = &amountResponseV2{
+				Amount:   limit.AvailableAmount(),
+				Currency: defaultCurrency,
+			}
+		}
+
+		resp.Data = append(resp.Data, data)
+	}
+
+	return resp
+}
+
+type billsResponseV2 struct {
+	Data  []billV2  `json:"data"`
+	Meta  api.Meta  `json:"meta"`
+	Links api.Links `json:"links"`
+}
+
+type billV2 struct {
+	ID             string            `json:"billId"`
+	DueDate        timex.Date        `json:"dueDate"`
+	TotalAmount    amountResponseV2  `json:"billTotalAmount"`
+	MinimumAmount  amountResponseV2  `json:"billMinimumAmount"`
+	IsInstalment   bool              `json:"isInstalment"`
+	FinanceCharges []financeChargeV2 `json:"financeCharges"`
+	Payments       []billPaymentV2   `json:"payments"`
+}
+
+type financeChargeV2 struct {
+	Type           FinanceChargeType `json:"type"`
+	AdditionalInfo string            `json:"additionalInfo,omitempty"`
+	Amount         string            `json:"amount"`
+	Currency       string            `json:"currency"`
+}
+
+type billPaymentV2 struct {
+	ValueType PaymentValueType `json:"valueType"`
+	Date      timex.Date       `json:"paymentDate"`
+	Mode      PaymentMode      `json:"paymentMode"`
+	Amount    string           `json:"amount"`
+	Currency  string           `json:"currency"`
+}
+
+func toBillsResponseV2(bills page.Page[Bill], reqURL string) billsResponseV2 {
+	resp := billsResponseV2{
+		Data: []billV2{},
+		Meta: api.NewPaginatedMeta(bills),
+		Links: api.Links{
+			Self: reqURL,
+		},
+	}
+
+	for _, bill := range bills.Records {
+		data := billV2{
+			ID:      bill.ID,
+			DueDate: bill.DueDate,
+			TotalAmount: amountResponseV2{
+				Amount:   bill.TotalAmount,
+				Currency: defaultCurrency,
+			},
+			MinimumAmount: amountResponseV2{
+				Amount:   bill.MinimumAmount,
+				Currency: defaultCurrency,
+			},
+			IsInstalment:   bill.IsInstalment,
+			FinanceCharges: []financeChargeV2{},
+			Payments:       []billPaymentV2{},
+		}
+
+		for _, charge := range bill.FinanceCharges {
+			data.FinanceCharges = append(data.FinanceCharges, financeChargeV2{
+				Type:           charge.Type,
+				AdditionalInfo: charge.AdditionalInfo,
+				Amount:         charge.Amount,
+				Currency:       defaultCurrency,
+			})
+		}
+
+		for _, payment := range bill.Payments {
+			data.Payments = append(data.Payments, billPaymentV2{
+				ValueType: payment.ValueType,
+				Date:      payment.Date,
+				Mode:      payment.Mode,
+				Amount:    payment.Amount,
+				Currency:  defaultCurrency,
+			})
+		}
+
+		resp.Data = append(resp.Data, data)
+	}
+
+	return resp
+}
+
+type transactionsResponseV2 struct {
+	Data  []transactionV2 `json:"data"`
+	Meta  api.Meta        `json:"meta"`
+	Links api.Links       `json:"links"`
+}
+
+type transactionV2 struct {
+	ID                   string                 `json:"transactionId"`
+	IdentificationNumber string                 `json:"identificationNumber"`
+	Name                 string                 `json:"transactionName"`
+	BillID               string                 `json:"billId,omitempty"`
+	CreditDebitType      CreditDebitType        `json:"creditDebitType"`
+	Type                 TransactionType        `json:"transactionType"`
+	AdditionalInfo       string                 `json:"transactionalAdditionalInfo,omitempty"`
+	PaymentType          TransactionPaymentType `json:"paymentType,omitempty"`
+	FeeType              FeeType                `json:"feeType,omitempty"`
+	BrazilianAmount      amountResponseV2       `json:"brazilianAmount"`
+	Amount               amountResponseV2       `json:"amount"`
+	ExchangeRate         string                 `json:"exchangeRate,omitempty"`
+	DateTime             timex.DateTime         `json:"transactionDateTime"`
+	BillPostDate         timex.Date             `json:"billPostDate"`
+	PayeeMCC             int                    `json:"payeeMCC,omitempty"`
+	ChargeIdentificator  string                 `json:"chargeIdentificator,omitempty"`
+	ChargeNumber         int                    `json:"chargeNumber,omitempty"`
+}
+
+func toTransactionsResponseV2(trs page.Page[Transaction], reqURL string) transactionsResponseV2 {
+	resp := transactionsResponseV2{
+		Data: []transactionV2{},
+		Meta: api.NewPaginatedMeta(trs),
+		Links: api.Links{
+			Self: reqURL,
+		},
+	}
+
+	for _, tr := range trs.Records {
+		data := transactionV2{
+			ID:                   tr.ID,
+			IdentificationNumber: tr.Card.IdentificationNumber(),
+			Name:                 tr.Name,
+			BillID:               tr.BillID,
+			CreditDebitType:      tr.CreditDebitType,
+			Type:                 tr.Type,
+			AdditionalInfo:       tr.AdditionalInfo,
+			PaymentType:          tr.PaymentType,
+			FeeType:              tr.FeeType,
+			BrazilianAmount: amountResponseV2{
+				Amount:   tr.Amount,
+				Currency: defaultCurrency,
+			},
+			Amount: amountResponseV2{
+				Amount:   tr.Amount,
+				Currency: defaultCurrency,
+			},
+			DateTime:            tr.DateTime,
+			BillPostDate:        tr.PostDate,
+			PayeeMCC:            tr.PayeeMCC,
+			ChargeIdentificator: tr.ChargeIdentificator,
+			ChargeNumber:        tr.ChargeNumber,
+		}
+
+		// International transactions inform the amount in the currency they
+		// were made in and the rate it was converted to BRL with.
+		if tr.IsInternational() {
+			data.Amount = amountResponseV2{
+				Amount:   tr.ForeignAmount,
+				Currency: tr.Currency,
+			}
+			data.ExchangeRate = tr.ExchangeRate
+		}
+
+		resp.Data = append(resp.Data, data)
+	}
+
+	return resp
+}
+
+func newBillFilter(r *http.Request) (billFilter, error) {
+	from, to, err := parseDateRange(r, "fromDueDate", "toDueDate")
+	if err != nil {
+		return billFilter{}, err
+	}
+
+	return billFilter{
+		fromDueDate: from,
+		toDueDate:   to,
+	}, nil
+}
+
+func newTransactionFilter(r *http.Request, current bool) (transactionFilter, error) {
+	dates, err := api.NewTransactionDateRange(r, "fromTransactionDate", "toTransactionDate", current)
+	if err != nil {
+		return transactionFilter{}, err
+	}
+
+	return parseTransactionFilter(r, transactionFilter{
+		from: &dates.From,
+		to:   &dates.To,
+	})
+}
+
+// newBillTransactionFilter parses the filter of the transactions of a bill.
+// Unlike the transactions of the account, all the transactions of the bill
+// are returned if no dates are informed.
+func newBillTransactionFilter(r *http.Request) (transactionFilter, error) {
+	from, to, err := parseDateRange(r, "fromTransactionDate", "toTransactionDate")
+	if err != nil {
+		return transactionFilter{}, err
+	}
+
+	return parseTransactionFilter(r, transactionFilter{
+		from: from,
+		to:   to,
+	})
+}
+
+// parseTransactionFilter completes the filter with the optional query
+// parameters shared by all the transaction endpoints.
+func parseTransactionFilter(r *http.Request, filter transactionFilter) (transactionFilter, error) {
+	query := r.URL.Query()
+	filter.transactionType = TransactionType(query.Get("transactionType"))
+	// identificationNumber filters the transactions made with one of the
+	// cards of the account, e.g. an additional card.
+	filter.identificationNumber = query.Get("identificationNumber")
+
+	if mcc := query.Get("payeeMCC"); mcc != "" {
+		payeeMCC, err := strconv.Atoi(mcc)
+		if err != nil {
+			return transactionFilter{}, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, "invalid payeeMCC")
+		}
+		filter.payeeMCC = payeeMCC
+	}
+
+	return filter, nil
+}
+
+// parseDateRange parses the optional date range informed by the query
+// parameters fromParam and toParam. Either both or none must be informed.
+func parseDateRange(r *http.Request, fromParam, toParam string) (*timex.Date, *timex.Date, error) {
+	from := r.URL.Query().Get(fromParam)
+	to := r.URL.Query().Get(toParam)
+	if from == "" && to == "" {
+		return nil, nil, nil
+	}
+
+	if from == "" || to == "" {
+		return nil, nil, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity,
+			fmt.Sprintf("%s and %s must be informed together", fromParam, toParam))
+	}
+
+	fromDate, err := timex.ParseDate(from)
+	if err != nil {
+		return nil, nil, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, "invalid "+fromParam)
+	}
+
+	toDate, err := timex.ParseDate(to)
+	if err != nil {
+		return nil, nil, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity, "invalid "+toParam)
+	}
+
+	if toDate.Before(fromDate.Time) {
+		return nil, nil, api.NewError("INVALID_PARAMETER", http.StatusUnprocessableEntity,
+			fmt.Sprintf("%s must not be before %s", toParam, fromParam))
+	}
+
+	return &fromDate, &toDate, nil
+}
+
+func writeErrorV2(w http.ResponseWriter, err error, pagination bool) {
+	if errors.Is(err, errAccountNotAllowed) {
+		err := api.NewError("FORBIDDEN", http.StatusForbidden, errAccountNotAllowed.Error())
+		if pagination {
+			err = err.WithPagination()
+		}
+		api.WriteError(w, err)
+		return
+	}
+
+	if errors.Is(err, errBillNotFound) {
+		err := api.NewError("NOT_FOUND", http.StatusNotFound, errBillNotFound.Error())
+		if pagination {
+			err = err.WithPagination()
+		}
+		api.WriteError(w, err)
+		return
+	}
+
+	api.WriteError(w, err)
+}
@@ -0,0 +1,185 @@
+package creditcard
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luikyv/go-open-finance/internal/money"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+const (
+	// billDueDays is how many days after closing a bill is due. Bills due on
+	// non business days are due on the next business day.
+	billDueDays = 10
+	// minimumPaymentPercentage is the percentage of the total amount of a bill
+	// that must be paid to avoid being in arrears.
+	minimumPaymentPercentage = 15
+)
+
+// bills generates the closed bills of the account from its transactions, from
+// the most recent to the oldest.
+// The transactions are grouped by the closing date of the cycle they were
+// posted to. The payments of a bill are the bill payment transactions posted
+// to the following cycle, so they are not considered in its total amount.
+func (acc Account) bills() ([]Bill, error) {
+	today := timex.DateNow()
+	billsMap := map[time.Time]*Bill{}
+	bill := func(closingDate timex.Date) *Bill {
+		if b, ok := billsMap[closingDate.Time]; ok {
+			return b
+		}
+
+		b := &Bill{
+			ID:          acc.billID(closingDate),
+			ClosingDate: closingDate,
+			DueDate:     billDueDate(closingDate),
+		}
+		billsMap[closingDate.Time] = b
+		return b
+	}
+
+	for _, tr := range acc.postedTransactions() {
+		closingDate := acc.closingDate(tr.PostDate)
+		if tr.IsBillPayment() {
+			b := bill(timex.NewDate(closingDate.AddDate(0, -1, 0)))
+			b.Payments = append(b.Payments, newPayment(tr))
+		}
+		b := bill(closingDate)
+		b.Transactions = append(b.Transactions, tr)
+	}
+
+	var bills []Bill
+	for _, b := range billsMap {
+		// Only closed cycles have a bill.
+		if !b.ClosingDate.Before(today.Time) {
+			continue
+		}
+		consolidated, err := b.consolidate()
+		if err != nil {
+			return nil, err
+		}
+		bills = append(bills, consolidated)
+	}
+
+	slices.SortFunc(bills, func(b1, b2 Bill) int {
+		return b2.ClosingDate.Compare(b1.ClosingDate.Time)
+	})
+	return bills, nil
+}
+
+// postedTransactions returns the transactions of the account informing the
+// bill they were posted to, if it is already closed.
+func (acc Account) postedTransactions() []Transaction {
+	today := timex.DateNow()
+	var trs []Transaction
+	for _, tr := range acc.Transactions {
+		if closingDate := acc.closingDate(tr.PostDate); closingDate.Before(today.Time) {
+			tr.BillID = acc.billID(closingDate)
+		}
+		trs = append(trs, tr)
+	}
+	return trs
+}
+
+// bill returns the closed bill identified by id.
+func (acc Account) bill(id string) (Bill, error) {
+	bills, err := acc.bills()
+	if err != nil {
+		return Bill{}, err
+	}
+
+	for _, b := range bills {
+		if b.ID == id {
+			return b, nil
+		}
+	}
+	return Bill{}, errBillNotFound
+}
+
+// closingDate returns the closing date of the cycle the date belongs to.
+func (acc Account) closingDate(d timex.Date) timex.Date {
+	closing := time.Date(d.Year(), d.Month(), acc.ClosingDay, 0, 0, 0, 0, time.UTC)
+	if d.After(closing) {
+		closing = closing.AddDate(0, 1, 0)
+	}
+	return timex.NewDate(closing)
+}
+
+// billID generates a stable ID for the bill closed at closingDate, so the same
+// bill keeps its ID every time it is generated.
+func (acc Account) billID(closingDate timex.Date) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(acc.ID+"/"+closingDate.String())).String()
+}
+
+func billDueDate(closingDate timex.Date) timex.Date {
+	due := timex.NewDate(closingDate.AddDate(0, 0, billDueDays))
+	if !timex.IsBusinessDay(due) {
+		due = timex.NextBusinessDay(due)
+	}
+	return due
+}
+
+// consolidate calculates the amounts of the bill from its transactions.
+func (b Bill) consolidate() (Bill, error) {
+	var total int64
+	charges := map[FinanceChargeType]int64{}
+	for _, tr := range b.Transactions {
+		if tr.IsBillPayment() {
+			continue
+		}
+
+		amount, err := money.Parse(tr.Amount)
+		if err != nil {
+			return Bill{}, err
+		}
+		if tr.CreditDebitType == CreditDebitTypeCredit {
+			amount = -amount
+		}
+		total += amount
+
+		if tr.FinanceChargeType != "" {
+			charges[tr.FinanceChargeType] += amount
+		}
+	}
+
+	total = max(total, 0)
+	b.TotalAmount = money.Format(total)
+	b.MinimumAmount = money.Format(total * minimumPaymentPercentage / 100)
+
+	for chargeType, amount := range charges {
+		b.FinanceCharges = append(b.FinanceCharges, FinanceCharge{
+			Type:   chargeType,
+			Amount: money.Format(amount),
+		})
+	}
+	slices.SortFunc(b.FinanceCharges, func(c1, c2 FinanceCharge) int {
+		return cmp.Compare(c1.Type, c2.Type)
+	})
+	if len(b.FinanceCharges) == 0 {
+		b.FinanceCharges = []FinanceCharge{{Type: FinanceChargeTypeNone, Amount: money.Format(0)}}
+	}
+
+	for _, p := range b.Payments {
+		if p.ValueType == PaymentValueTypeInstalment {
+			b.IsInstalment = true
+		}
+	}
+
+	return b, nil
+}
+
+func newPayment(tr Transaction) Payment {
+	p := Payment{
+		ValueType: PaymentValueTypeFull,
+		Date:      tr.DateTime.ToDate(),
+		Mode:      tr.PaymentMode,
+		Amount:    tr.Amount,
+	}
+	if tr.PaymentType == TransactionPaymentTypeInstalment {
+		p.ValueType = PaymentValueTypeInstalment
+	}
+	return p
+}
@@ -0,0 +1,269 @@
+package creditcard
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+func TestBills(t *testing.T) {
+	acc := Account{ID: "account", ClosingDay: 10}
+	// The bills depend on the current date, so the transactions are posted
+	// relative to the cycle that is still open.
+	open := acc.closingDate(timex.DateNow())
+	last := monthsBefore(open, 1)
+	secondToLast := monthsBefore(open, 2)
+	acc.Transactions = []Transaction{
+		{
+			Name:            "purchase",
+			CreditDebitType: CreditDebitTypeDebit,
+			Type:            TransactionTypeOthers,
+			Amount:          "100.00",
+			PostDate:        secondToLast,
+		},
+		{
+			Name:              "iof",
+			CreditDebitType:   CreditDebitTypeDebit,
+			Type:              TransactionTypeOthers,
+			FinanceChargeType: FinanceChargeTypeIOF,
+			Amount:            "3.00",
+			PostDate:          timex.NewDate(secondToLast.AddDate(0, 0, -5)),
+		},
+		{
+			Name:              "interest",
+			CreditDebitType:   CreditDebitTypeDebit,
+			Type:              TransactionTypeOthers,
+			FinanceChargeType: FinanceChargeTypeLatePaymentInterest,
+			Amount:            "10.00",
+			PostDate:          secondToLast,
+		},
+		{
+			Name:            "reversal",
+			CreditDebitType: CreditDebitTypeCredit,
+			Type:            TransactionTypeReversal,
+			Amount:          "20.00",
+			PostDate:        secondToLast,
+		},
+		{
+			// The payment is posted to the last cycle, but it pays the bill
+			// of the second to last one.
+			Name:            "bill payment",
+			CreditDebitType: CreditDebitTypeCredit,
+			Type:            TransactionTypePayment,
+			PaymentMode:     PaymentModePix,
+			Amount:          "93.00",
+			PostDate:        timex.NewDate(last.AddDate(0, 0, -1)),
+		},
+		{
+			Name:            "purchase",
+			CreditDebitType: CreditDebitTypeDebit,
+			Type:            TransactionTypeOthers,
+			Amount:          "50.00",
+			PostDate:        last,
+		},
+		{
+			// The open cycle has no bill yet.
+			Name:            "purchase",
+			CreditDebitType: CreditDebitTypeDebit,
+			Type:            TransactionTypeOthers,
+			Amount:          "30.00",
+			PostDate:        open,
+		},
+	}
+
+	bills, err := acc.bills()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var closingDates []timex.Date
+	for _, b := range bills {
+		closingDates = append(closingDates, b.ClosingDate)
+	}
+	if want := []timex.Date{last, secondToLast}; !reflect.DeepEqual(closingDates, want) {
+		t.Fatalf("got bills closing at %v, want %v", closingDates, want)
+	}
+
+	testCases := []struct {
+		name             string
+		bill             Bill
+		wantTotal        string
+		wantMinimum      string
+		wantCharges      []FinanceCharge
+		wantPayments     []string
+		wantTransactions []string
+	}{
+		{
+			// The payment of the previous bill is not considered in the
+			// total amount.
+			name:        "last",
+			bill:        bills[0],
+			wantTotal:   "50.00",
+			wantMinimum: "7.50",
+			wantCharges: []FinanceCharge{
+				{Type: FinanceChargeTypeNone, Amount: "0.00"},
+			},
+			wantTransactions: []string{"bill payment", "purchase"},
+		},
+		{
+			name:        "second to last",
+			bill:        bills[1],
+			wantTotal:   "93.00",
+			wantMinimum: "13.95",
+			wantCharges: []FinanceCharge{
+				{Type: FinanceChargeTypeIOF, Amount: "3.00"},
+				{Type: FinanceChargeTypeLatePaymentInterest, Amount: "10.00"},
+			},
+			wantPayments:     []string{"93.00"},
+			wantTransactions: []string{"purchase", "iof", "interest", "reversal"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.bill.TotalAmount != tc.wantTotal {
+				t.Errorf("got total amount %s, want %s", tc.bill.TotalAmount, tc.wantTotal)
+			}
+
+			if tc.bill.MinimumAmount != tc.wantMinimum {
+				t.Errorf("got minimum amount %s, want %s", tc.bill.MinimumAmount, tc.wantMinimum)
+			}
+
+			if !reflect.DeepEqual(tc.bill.FinanceCharges, tc.wantCharges) {
+				t.Errorf("got finance charges %v, want %v", tc.bill.FinanceCharges, tc.wantCharges)
+			}
+
+			var payments []string
+			for _, p := range tc.bill.Payments {
+				payments = append(payments, p.Amount)
+			}
+			if !reflect.DeepEqual(payments, tc.wantPayments) {
+				t.Errorf("got payments %v, want %v", payments, tc.wantPayments)
+			}
+
+			var trs []string
+			for _, tr := range tc.bill.Transactions {
+				if tr.BillID != tc.bill.ID {
+					t.Errorf("got transaction %s posted to bill %s, want %s", tr.Name, tr.BillID, tc.bill.ID)
+				}
+				trs = append(trs, tr.Name)
+			}
+			if !reflect.DeepEqual(trs, tc.wantTransactions) {
+				t.Errorf("got transactions %v, want %v", trs, tc.wantTransactions)
+			}
+
+			if b, err := acc.bill(tc.bill.ID); err != nil || b.ID != tc.bill.ID {
+				t.Errorf("got bill %s and error %v, want bill %s", b.ID, err, tc.bill.ID)
+			}
+		})
+	}
+
+	if _, err := acc.bill(acc.billID(open)); !errors.Is(err, errBillNotFound) {
+		t.Errorf("got error %v for the open cycle, want %v", err, errBillNotFound)
+	}
+}
+
+func TestBills_InvalidAmount(t *testing.T) {
+	acc := Account{
+		ID:         "account",
+		ClosingDay: 10,
+		Transactions: []Transaction{
+			{
+				CreditDebitType: CreditDebitTypeDebit,
+				Type:            TransactionTypeOthers,
+				Amount:          "ten",
+				PostDate:        date(t, "2024-01-05"),
+			},
+		},
+	}
+
+	if _, err := acc.bills(); err == nil {
+		t.Error("expected an error for an invalid amount")
+	}
+}
+
+func TestClosingDate(t *testing.T) {
+	testCases := []struct {
+		name string
+		date string
+		want string
+	}{
+		{
+			name: "before the closing day",
+			date: "2024-03-05",
+			want: "2024-03-10",
+		},
+		{
+			name: "on the closing day",
+			date: "2024-03-10",
+			want: "2024-03-10",
+		},
+		{
+			name: "after the closing day",
+			date: "2024-03-11",
+			want: "2024-04-10",
+		},
+		{
+			name: "after the closing day of december",
+			date: "2024-12-20",
+			want: "2025-01-10",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			acc := Account{ClosingDay: 10}
+			if got := acc.closingDate(date(t, tc.date)).String(); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBillDueDate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		closingDate string
+		want        string
+	}{
+		{
+			name:        "business day",
+			closingDate: "2024-07-01",
+			want:        "2024-07-11",
+		},
+		{
+			// 2024-03-30 is a Saturday and 2024-03-31 is Easter.
+			name:        "weekend",
+			closingDate: "2024-03-20",
+			want:        "2024-04-01",
+		},
+		{
+			name:        "holiday",
+			closingDate: "2024-12-15",
+			want:        "2024-12-26",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := billDueDate(date(t, tc.closingDate)).String(); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func monthsBefore(d timex.Date, n int) timex.Date {
+	return timex.NewDate(d.AddDate(0, -n, 0))
+}
+
+func date(t *testing.T, s string) timex.Date {
+	t.Helper()
+	d, err := timex.ParseDate(s)
+	if err != nil {
+		t.Fatalf("invalid date %s: %v", s, err)
+	}
+	return d
+}
@@ -1,7 +1,13 @@
 package creditcard
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
 	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/money"
 	"github.com/luikyv/go-open-finance/internal/timex"
 )
 
@@ -10,7 +16,10 @@ var (
 )
 
 const (
-// defaultCurrency string = "BRL"
+	defaultCurrency string = "BRL"
+	// cardIdentificationLength is how many of the last digits of a card
+	// number identify it.
+	cardIdentificationLength = 4
 )
 
 type Account struct {
@@ -21,15 +30,88 @@ type Account struct {
 	Network         Network
 	MainCard        Card
 	AdditionalCards []Card
-	LimitAmount     string
-	UsedAmount      string
-	Biils           []Bill
+	Limits          []Limit
+	// ClosingDay is the day of the month the bills of the account close. It
+	// must not be greater than 28, so every month has a closing date.
+	ClosingDay   int
+	Transactions []Transaction
 }
 
 type Card struct {
 	Number string
 }
 
+// IdentificationNumber returns the last digits of the card number, which are
+// used to identify the card.
+func (c Card) IdentificationNumber() string {
+	if len(c.Number) < cardIdentificationLength {
+		return c.Number
+	}
+	return c.Number[len(c.Number)-cardIdentificationLength:]
+}
+
+// Limit is a credit limit of the account. The total limit applies to all the
+// lines of business, which may also have their own limits.
+type Limit struct {
+	Type              LimitType
+	ConsolidationType ConsolidationType
+	// Card is the card the limit applies to. For consolidated limits, it is
+	// the main card of the account.
+	Card Card
+	// LineName is informed when the limit is of a line of business.
+	LineName LineName
+	// LineNameAdditionalInfo describes the line of business when its name is
+	// [LineNameOthers].
+	LineNameAdditionalInfo string
+	// IsFlexible indicates whether the limit can be exceeded, in which case
+	// the limit amount may not be informed.
+	IsFlexible  bool
+	LimitAmount string
+	UsedAmount  string
+}
+
+// AvailableAmount returns how much of the limit can still be used.
+func (l Limit) AvailableAmount() string {
+	if l.LimitAmount == "" {
+		return ""
+	}
+
+	limit, err := money.Parse(l.LimitAmount)
+	if err != nil {
+		return ""
+	}
+	used, err := money.Parse(l.UsedAmount)
+	if err != nil {
+		return ""
+	}
+	return money.Format(max(limit-used, 0))
+}
+
+type LimitType string
+
+const (
+	LimitTypeTotal    LimitType = "LIMITE_CREDITO_TOTAL"
+	LimitTypeBusiness LimitType = "LIMITE_CREDITO_MODALIDADE_OPERACAO"
+)
+
+type ConsolidationType string
+
+const (
+	ConsolidationTypeConsolidated ConsolidationType = "CONSOLIDADO"
+	ConsolidationTypeIndividual   ConsolidationType = "INDIVIDUAL"
+)
+
+type LineName string
+
+const (
+	LineNameCashCredit              LineName = "CREDITO_A_VISTA"
+	LineNameInstalmentCredit        LineName = "CREDITO_PARCELADO"
+	LineNameDomesticWithdrawal      LineName = "SAQUE_CREDITO_BRASIL"
+	LineNameInternationalWithdrawal LineName = "SAQUE_CREDITO_EXTERIOR"
+	LineNamePayrollLoan             LineName = "EMPRESTIMO_CARTAO_CONSIGNADO"
+	LineNameOthers                  LineName = "OUTROS"
+)
+
 type Type string
 
 const (
@@ -75,18 +157,53 @@ const (
 	NetworkOthers          Network = "OUTRAS"
 )
 
+// Bill groups the transactions posted between two closing dates.
 type Bill struct {
 	ID          string
+	ClosingDate timex.Date
 	DueDate     timex.Date
-	TotalAmount string
+	// TotalAmount is the sum of the debits minus the credits of the bill, not
+	// considering the payments.
+	TotalAmount    string
+	MinimumAmount  string
+	IsInstalment   bool
+	FinanceCharges []FinanceCharge
+	Payments       []Payment
+	Transactions   []Transaction
 }
 
+type FinanceCharge struct {
+	Type           FinanceChargeType
+	AdditionalInfo string
+	Amount         string
+}
+
+type FinanceChargeType string
+
+const (
+	FinanceChargeTypeLatePaymentInterest FinanceChargeType = "JUROS_REMUNERATORIOS_ATRASO_PAGAMENTO_FATURA"
+	FinanceChargeTypeLatePaymentFine     FinanceChargeType = "MULTA_ATRASO_PAGAMENTO_FATURA"
+	FinanceChargeTypeLatePaymentDefault  FinanceChargeType = "JUROS_MORA_ATRASO_PAGAMENTO_FATURA"
+	FinanceChargeTypeIOF                 FinanceChargeType = "IOF"
+	FinanceChargeTypeNone                FinanceChargeType = "SEM_ENCARGO"
+	FinanceChargeTypeOthers              FinanceChargeType = "OUTROS"
+)
+
 type Payment struct {
-	Date   timex.Date
-	Mode   PaymentMode
-	Amount string
+	ValueType PaymentValueType
+	Date      timex.Date
+	Mode      PaymentMode
+	Amount    string
 }
 
+type PaymentValueType string
+
+const (
+	PaymentValueTypeInstalment PaymentValueType = "VALOR_PAGAMENTO_FATURA_PARCELADO"
+	PaymentValueTypeFull       PaymentValueType = "VALOR_PAGAMENTO_FATURA_REALIZADO"
+	PaymentValueTypeOthers     PaymentValueType = "OUTRO_VALOR_PAGO_FATURA"
+)
+
 type PaymentMode string
 
 const (
@@ -95,3 +212,193 @@ const (
 	PaymentModePayrollDeduction    PaymentMode = "AVERBACAO_FOLHA"
 	PaymentModePix                 PaymentMode = "PIX"
 )
+
+type Transaction struct {
+	ID string
+	// Card is the card the transaction was made with.
+	Card            Card
+	Name            string
+	CreditDebitType CreditDebitType
+	Type            TransactionType
+	AdditionalInfo  string
+	PaymentType     TransactionPaymentType
+	// FeeType is informed when the transaction is a fee.
+	FeeType FeeType
+	// FinanceChargeType is informed when the transaction is a finance charge
+	// of the bill, e.g. interest or IOF.
+	FinanceChargeType FinanceChargeType
+	// PaymentMode is informed when the transaction is the payment of a bill.
+	PaymentMode PaymentMode
+	// Amount is always in BRL. The amount of international transactions is
+	// converted from ForeignAmount.
+	Amount string
+	// Currency, ForeignAmount and ExchangeRate are informed by international
+	// transactions.
+	Currency      string
+	ForeignAmount string
+	ExchangeRate  string
+	DateTime      timex.DateTime
+	// PostDate is when the transaction is posted to a bill.
+	PostDate timex.Date
+	// BillID is informed once the bill the transaction was posted to closes.
+	BillID   string
+	PayeeMCC int
+	// ChargeIdentificator and ChargeNumber identify each of the instalments
+	// of a purchase.
+	ChargeIdentificator string
+	ChargeNumber        int
+}
+
+// NewInternationalTransaction creates a transaction made in a foreign currency
+// whose amount in BRL is converted with the exchange rate.
+func NewInternationalTransaction(tr Transaction, currency, amount, exchangeRate string) (Transaction, error) {
+	cents, err := money.Parse(amount)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	rate, err := strconv.ParseFloat(exchangeRate, 64)
+	if err != nil || rate <= 0 {
+		return Transaction{}, fmt.Errorf("invalid exchange rate %s", exchangeRate)
+	}
+
+	tr.Currency = currency
+	tr.ForeignAmount = amount
+	tr.ExchangeRate = exchangeRate
+	tr.Amount = money.Format(int64(math.Round(float64(cents) * rate)))
+	return tr, nil
+}
+
+// IsInternational returns true if the transaction was made in a foreign
+// currency.
+func (t Transaction) IsInternational() bool {
+	return t.Currency != "" && t.Currency != defaultCurrency
+}
+
+// Instalments splits a purchase into n instalments, each posted to the bill
+// of a consecutive month starting on the date of the purchase. The first
+// instalment carries what remains of the division of the amount.
+// Purchases made at the end of the month post their instalments on the last
+// day of shorter months, so no month is skipped.
+func (t Transaction) Instalments(n int) ([]Transaction, error) {
+	cents, err := money.Parse(t.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid number of instalments %d", n)
+	}
+
+	postDate := t.PostDate
+	if postDate.IsZero() {
+		postDate = t.DateTime.ToDate()
+	}
+
+	instalment := cents / int64(n)
+	var trs []Transaction
+	for i := range n {
+		tr := t
+		tr.PaymentType = TransactionPaymentTypeInstalment
+		tr.ChargeIdentificator = t.Name
+		tr.ChargeNumber = i + 1
+		tr.PostDate = addMonths(postDate, i)
+		tr.Amount = money.Format(instalment)
+		if i == 0 {
+			tr.Amount = money.Format(cents - instalment*int64(n-1))
+		}
+		trs = append(trs, tr)
+	}
+
+	return trs, nil
+}
+
+// addMonths adds n months to d, keeping it on the last day of the month if the
+// resulting month is shorter than the day of d.
+func addMonths(d timex.Date, n int) timex.Date {
+	year, month, day := d.Date()
+	monthStart := time.Date(year, month+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := monthStart.AddDate(0, 1, -1).Day()
+	return timex.NewDate(monthStart.AddDate(0, 0, min(day, lastDay)-1))
+}
+
+// IsBillPayment returns true if the transaction pays a bill.
+func (t Transaction) IsBillPayment() bool {
+	return t.Type == TransactionTypePayment && t.CreditDebitType == CreditDebitTypeCredit
+}
+
+type CreditDebitType string
+
+const (
+	CreditDebitTypeCredit CreditDebitType = "CREDITO"
+	CreditDebitTypeDebit  CreditDebitType = "DEBITO"
+)
+
+type TransactionType string
+
+const (
+	TransactionTypePayment         TransactionType = "PAGAMENTO"
+	TransactionTypeFee             TransactionType = "TARIFA"
+	TransactionTypeCreditOperation TransactionType = "OPERACOES_CREDITO_CONTRATADAS_CARTAO"
+	TransactionTypeReversal        TransactionType = "ESTORNO"
+	TransactionTypeCashback        TransactionType = "CASHBACK"
+	TransactionTypeOthers          TransactionType = "OUTROS"
+)
+
+type TransactionPaymentType string
+
+const (
+	TransactionPaymentTypeCash       TransactionPaymentType = "A_VISTA"
+	TransactionPaymentTypeInstalment TransactionPaymentType = "A_PRAZO"
+)
+
+type FeeType string
+
+const (
+	FeeTypeAnnual                  FeeType = "ANUIDADE"
+	FeeTypeDomesticWithdrawal      FeeType = "SAQUE_CARTAO_BRASIL"
+	FeeTypeInternationalWithdrawal FeeType = "SAQUE_CARTAO_EXTERIOR"
+	FeeTypeEmergencyCredit         FeeType = "AVALIACAO_EMERGENCIAL_CREDITO"
+	FeeTypeCardReplacement         FeeType = "EMISSAO_SEGUNDA_VIA"
+	FeeTypeBillPayment             FeeType = "TARIFA_PAGAMENTO_CONTAS"
+	FeeTypeSMS                     FeeType = "SMS"
+	FeeTypeOthers                  FeeType = "OUTRA"
+)
+
+type billFilter struct {
+	fromDueDate *timex.Date
+	toDueDate   *timex.Date
+}
+
+type transactionFilter struct {
+	from                 *timex.Date
+	to                   *timex.Date
+	transactionType      TransactionType
+	payeeMCC             int
+	identificationNumber string
+}
+
+func (f transactionFilter) matches(tr Transaction) bool {
+	date := tr.DateTime.ToDate()
+	if f.from != nil && date.Before(f.from.Time) {
+		return false
+	}
+
+	if f.to != nil && date.After(f.to.Time) {
+		return false
+	}
+
+	if f.transactionType != "" && tr.Type != f.transactionType {
+		return false
+	}
+
+	if f.payeeMCC != 0 && tr.PayeeMCC != f.payeeMCC {
+		return false
+	}
+
+	if f.identificationNumber != "" && tr.Card.IdentificationNumber() != f.identificationNumber {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,194 @@
+package creditcard
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+func TestInstalments(t *testing.T) {
+	testCases := []struct {
+		name string
+		tr   Transaction
+		n    int
+		// want holds the amount and the post date of each instalment.
+		want [][2]string
+	}{
+		{
+			// The first instalment carries the cent that remains of the
+			// division.
+			name: "remainder",
+			tr: Transaction{
+				Amount:   "100.00",
+				PostDate: date(t, "2024-03-15"),
+			},
+			n: 3,
+			want: [][2]string{
+				{"33.34", "2024-03-15"},
+				{"33.33", "2024-04-15"},
+				{"33.33", "2024-05-15"},
+			},
+		},
+		{
+			name: "end of the month",
+			tr: Transaction{
+				Amount:   "90.00",
+				PostDate: date(t, "2024-01-31"),
+			},
+			n: 3,
+			want: [][2]string{
+				{"30.00", "2024-01-31"},
+				{"30.00", "2024-02-29"},
+				{"30.00", "2024-03-31"},
+			},
+		},
+		{
+			// Without a post date, the instalments start on the date of the
+			// purchase.
+			name: "across the year",
+			tr: Transaction{
+				Amount:   "30.00",
+				DateTime: timex.NewDateTime(time.Date(2024, time.November, 30, 15, 0, 0, 0, time.UTC)),
+			},
+			n: 3,
+			want: [][2]string{
+				{"10.00", "2024-11-30"},
+				{"10.00", "2024-12-30"},
+				{"10.00", "2025-01-30"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.tr.Name = "purchase"
+			trs, err := tc.tr.Instalments(tc.n)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got [][2]string
+			for i, tr := range trs {
+				got = append(got, [2]string{tr.Amount, tr.PostDate.String()})
+
+				if tr.ChargeNumber != i+1 {
+					t.Errorf("got charge number %d, want %d", tr.ChargeNumber, i+1)
+				}
+
+				if tr.ChargeIdentificator != "purchase" {
+					t.Errorf("got charge identificator %s, want purchase", tr.ChargeIdentificator)
+				}
+
+				if tr.PaymentType != TransactionPaymentTypeInstalment {
+					t.Errorf("got payment type %s, want %s", tr.PaymentType, TransactionPaymentTypeInstalment)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInstalments_Invalid(t *testing.T) {
+	testCases := []struct {
+		name   string
+		amount string
+		n      int
+	}{
+		{
+			name:   "no instalments",
+			amount: "100.00",
+			n:      0,
+		},
+		{
+			name:   "invalid amount",
+			amount: "one hundred",
+			n:      2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := Transaction{Amount: tc.amount, PostDate: date(t, "2024-03-15")}
+			if _, err := tr.Instalments(tc.n); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestNewInternationalTransaction(t *testing.T) {
+	testCases := []struct {
+		name         string
+		amount       string
+		exchangeRate string
+		want         string
+		wantErr      bool
+	}{
+		{
+			// 1001 cents times 5.4321 are 5437.5321 cents.
+			name:         "rounded up",
+			amount:       "10.01",
+			exchangeRate: "5.4321",
+			want:         "54.38",
+		},
+		{
+			// 100 cents times 5.0049 are 500.49 cents.
+			name:         "rounded down",
+			amount:       "1.00",
+			exchangeRate: "5.0049",
+			want:         "5.00",
+		},
+		{
+			name:         "invalid exchange rate",
+			amount:       "10.00",
+			exchangeRate: "five",
+			wantErr:      true,
+		},
+		{
+			name:         "zero exchange rate",
+			amount:       "10.00",
+			exchangeRate: "0",
+			wantErr:      true,
+		},
+		{
+			name:         "invalid amount",
+			amount:       "ten",
+			exchangeRate: "5.00",
+			wantErr:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tr, err := NewInternationalTransaction(Transaction{}, "USD", tc.amount, tc.exchangeRate)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tr.Amount != tc.want {
+				t.Errorf("got amount %s, want %s", tr.Amount, tc.want)
+			}
+
+			if tr.ForeignAmount != tc.amount || tr.Currency != "USD" || tr.ExchangeRate != tc.exchangeRate {
+				t.Errorf("got foreign amount %s %s at %s, want %s USD at %s",
+					tr.Currency, tr.ForeignAmount, tr.ExchangeRate, tc.amount, tc.exchangeRate)
+			}
+
+			if !tr.IsInternational() {
+				t.Error("expected the transaction to be international")
+			}
+		})
+	}
+}
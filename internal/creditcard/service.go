@@ -10,6 +10,7 @@ import (
 
 var (
 	errAccountNotAllowed = errors.New("the account was not consented")
+	errBillNotFound      = errors.New("the bill was not found")
 )
 
 type Service struct {
@@ -50,3 +51,88 @@ func (s Service) account(ctx context.Context, id, consentID string) (Account, er
 
 	return s.storage.account(id), nil
 }
+
+func (s Service) limits(ctx context.Context, id, consentID string, pag page.Pagination) (page.Page[Limit], error) {
+	acc, err := s.account(ctx, id, consentID)
+	if err != nil {
+		return page.Page[Limit]{}, err
+	}
+
+	return page.Paginate(acc.Limits, pag), nil
+}
+
+func (s Service) bills(ctx context.Context, id, consentID string, filter billFilter, pag page.Pagination) (page.Page[Bill], error) {
+	acc, err := s.account(ctx, id, consentID)
+	if err != nil {
+		return page.Page[Bill]{}, err
+	}
+
+	accBills, err := acc.bills()
+	if err != nil {
+		return page.Page[Bill]{}, err
+	}
+
+	var bills []Bill
+	for _, b := range accBills {
+		if filter.fromDueDate != nil && b.DueDate.Before(filter.fromDueDate.Time) {
+			continue
+		}
+
+		if filter.toDueDate != nil && b.DueDate.After(filter.toDueDate.Time) {
+			continue
+		}
+
+		bills = append(bills, b)
+	}
+
+	return page.Paginate(bills, pag), nil
+}
+
+func (s Service) billTransactions(
+	ctx context.Context,
+	id, billID, consentID string,
+	filter transactionFilter,
+	pag page.Pagination,
+) (
+	page.Page[Transaction],
+	error,
+) {
+	acc, err := s.account(ctx, id, consentID)
+	if err != nil {
+		return page.Page[Transaction]{}, err
+	}
+
+	bill, err := acc.bill(billID)
+	if err != nil {
+		return page.Page[Transaction]{}, err
+	}
+
+	return page.Paginate(filterTransactions(bill.Transactions, filter), pag), nil
+}
+
+func (s Service) transactions(
+	ctx context.Context,
+	id, consentID string,
+	filter transactionFilter,
+	pag page.Pagination,
+) (
+	page.Page[Transaction],
+	error,
+) {
+	acc, err := s.account(ctx, id, consentID)
+	if err != nil {
+		return page.Page[Transaction]{}, err
+	}
+
+	return page.Paginate(filterTransactions(acc.postedTransactions(), filter), pag), nil
+}
+
+func filterTransactions(trs []Transaction, filter transactionFilter) []Transaction {
+	var filtered []Transaction
+	for _, tr := range trs {
+		if filter.matches(tr) {
+			filtered = append(filtered, tr)
+		}
+	}
+	return filtered
+}
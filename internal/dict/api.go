@@ -0,0 +1,86 @@
+package dict
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/luikyv/go-open-finance/internal/api"
+)
+
+// PathPrefixDICT is where the directory lookup is served.
+const PathPrefixDICT = "/dict"
+
+// APIRouter serves a lookup of the keys in the directory, so initiators can
+// find the creditor account of a Pix key while testing.
+type APIRouter struct {
+	service Service
+}
+
+func NewAPIRouter(service Service) APIRouter {
+	return APIRouter{
+		service: service,
+	}
+}
+
+func (router APIRouter) Register(mux *http.ServeMux) {
+	mux.Handle("GET "+PathPrefixDICT+"/entries/{key}", router.keyHandler())
+}
+
+func (router APIRouter) keyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := router.service.Key(r.Context(), r.PathValue("key"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		api.WriteJSON(w, toKeyResponse(key), http.StatusOK)
+	})
+}
+
+type keyResponse struct {
+	Key     string  `json:"key"`
+	KeyType KeyType `json:"keyType"`
+	Account struct {
+		Participant   string      `json:"participant"`
+		Branch        string      `json:"branch,omitempty"`
+		AccountNumber string      `json:"accountNumber"`
+		AccountType   AccountType `json:"accountType"`
+	} `json:"account"`
+	Owner struct {
+		Type        OwnerType `json:"type"`
+		TaxIDNumber string    `json:"taxIdNumber"`
+		Name        string    `json:"name"`
+	} `json:"owner"`
+	CreationDateTime string `json:"creationDate"`
+}
+
+func toKeyResponse(key Key) keyResponse {
+	resp := keyResponse{
+		Key:              key.Value,
+		KeyType:          key.Type,
+		CreationDateTime: key.CreationDateTime.String(),
+	}
+	resp.Account.Participant = key.Account.Participant
+	resp.Account.Branch = key.Account.Branch
+	resp.Account.AccountNumber = key.Account.Number
+	resp.Account.AccountType = key.Account.Type
+	resp.Owner.Type = key.Owner.Type
+	resp.Owner.TaxIDNumber = key.Owner.TaxIDNumber
+	resp.Owner.Name = key.Owner.Name
+	return resp
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrKeyNotFound) {
+		api.WriteError(w, api.NewError("NOT_FOUND", http.StatusNotFound, ErrKeyNotFound.Error()))
+		return
+	}
+
+	if errors.Is(err, ErrInvalidKey) {
+		api.WriteError(w, api.NewError("INVALID_KEY", http.StatusBadRequest, ErrInvalidKey.Error()))
+		return
+	}
+
+	api.WriteError(w, err)
+}
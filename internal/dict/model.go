@@ -0,0 +1,82 @@
+package dict
+
+import (
+	"regexp"
+
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+// Key is an entry of the Pix key directory (DICT), which maps a Pix key to
+// the account of its owner.
+type Key struct {
+	Value            string
+	Type             KeyType
+	Account          Account
+	Owner            Owner
+	CreationDateTime timex.DateTime
+}
+
+type KeyType string
+
+const (
+	KeyTypeCPF   KeyType = "CPF"
+	KeyTypeCNPJ  KeyType = "CNPJ"
+	KeyTypePhone KeyType = "PHONE"
+	KeyTypeEmail KeyType = "EMAIL"
+	// KeyTypeEVP is a random key generated by the DICT.
+	KeyTypeEVP KeyType = "EVP"
+)
+
+var keyTypePatterns = map[KeyType]*regexp.Regexp{
+	KeyTypeCPF:   regexp.MustCompile(`^\d{11}$`),
+	KeyTypeCNPJ:  regexp.MustCompile(`^\d{14}$`),
+	KeyTypePhone: regexp.MustCompile(`^\+[1-9]\d{1,14}$`),
+	KeyTypeEmail: regexp.MustCompile(`^[a-z0-9.!#$&'*+/=?^_{|}~-]+@[a-z0-9-]+(\.[a-z0-9-]+)*$`),
+	KeyTypeEVP:   regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`),
+}
+
+const maxEmailKeyLength = 77
+
+// isValid returns true if value is formatted as a key of type t.
+func (t KeyType) isValid(value string) bool {
+	pattern, ok := keyTypePatterns[t]
+	if !ok {
+		return false
+	}
+
+	if t == KeyTypeEmail && len(value) > maxEmailKeyLength {
+		return false
+	}
+
+	return pattern.MatchString(value)
+}
+
+type Account struct {
+	// Participant is the ISPB of the institution holding the account.
+	Participant string
+	Branch      string
+	Number      string
+	Type        AccountType
+}
+
+type AccountType string
+
+const (
+	AccountTypeCACC AccountType = "CACC"
+	AccountTypeSVGS AccountType = "SVGS"
+	AccountTypeSLRY AccountType = "SLRY"
+	AccountTypeTRAN AccountType = "TRAN"
+)
+
+type Owner struct {
+	Type        OwnerType
+	TaxIDNumber string
+	Name        string
+}
+
+type OwnerType string
+
+const (
+	OwnerTypeNaturalPerson OwnerType = "NATURAL_PERSON"
+	OwnerTypeLegalPerson   OwnerType = "LEGAL_PERSON"
+)
@@ -0,0 +1,60 @@
+package dict
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+var (
+	ErrKeyNotFound = errors.New("the pix key is not registered")
+	ErrInvalidKey  = errors.New("the pix key is invalid")
+)
+
+type Service struct {
+	storage *Storage
+}
+
+func NewService(st *Storage) Service {
+	return Service{
+		storage: st,
+	}
+}
+
+// Add registers the key in the directory. If the key is already registered,
+// it is moved to the new account.
+func (s Service) Add(_ context.Context, key Key) error {
+	if !key.Type.isValid(key.Value) {
+		return fmt.Errorf("%w: the value is not a valid %s key", ErrInvalidKey, key.Type)
+	}
+
+	s.storage.save(key)
+	return nil
+}
+
+// Key looks up the entry of the Pix key.
+func (s Service) Key(ctx context.Context, value string) (Key, error) {
+	if keyType(value) == "" {
+		return Key{}, ErrInvalidKey
+	}
+
+	key, ok := s.storage.key(value)
+	if !ok {
+		slog.DebugContext(ctx, "pix key not found in the directory")
+		return Key{}, ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// keyType infers the type of the key from its format. An empty type is
+// returned if the format is not valid for any type.
+func keyType(value string) KeyType {
+	for _, t := range []KeyType{KeyTypeCPF, KeyTypeCNPJ, KeyTypePhone, KeyTypeEmail, KeyTypeEVP} {
+		if t.isValid(value) {
+			return t
+		}
+	}
+	return ""
+}
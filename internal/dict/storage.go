@@ -0,0 +1,20 @@
+package dict
+
+type Storage struct {
+	keysMap map[string]Key
+}
+
+func NewStorage() *Storage {
+	return &Storage{
+		keysMap: map[string]Key{},
+	}
+}
+
+func (s *Storage) save(key Key) {
+	s.keysMap[key.Value] = key
+}
+
+func (s *Storage) key(value string) (Key, bool) {
+	key, ok := s.keysMap[value]
+	return key, ok
+}
@@ -0,0 +1,748 @@
+package enrollment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-oidc/pkg/provider"
+	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/api/middleware"
+	"github.com/luikyv/go-open-finance/internal/idempotency"
+	"github.com/luikyv/go-open-finance/internal/payment"
+	"github.com/luikyv/go-open-finance/internal/timex"
+	"github.com/luikyv/go-open-finance/internal/webauthn"
+)
+
+var (
+	errBadRequest = api.NewError("PARAMETRO_INVALIDO", http.StatusBadRequest, "invalid request")
+)
+
+type APIRouterV2 struct {
+	host               string
+	service            Service
+	op                 *provider.Provider
+	signer             api.JWTSigner
+	httpClient         *http.Client
+	idempotencyStorage idempotency.Storage
+}
+
+func NewAPIRouterV2(
+	host string,
+	service Service,
+	op *provider.Provider,
+	signer api.JWTSigner,
+	httpClient *http.Client,
+	idempotencyStorage idempotency.Storage,
+) APIRouterV2 {
+	return APIRouterV2{
+		host:               host,
+		service:            service,
+		op:                 op,
+		signer:             signer,
+		httpClient:         httpClient,
+		idempotencyStorage: idempotencyStorage,
+	}
+}
+
+func (router APIRouterV2) Register(mux *http.ServeMux) {
+	enrollmentMux := http.NewServeMux()
+
+	handler := router.createEnrollmentHandler()
+	handler = idempotency.Middleware(handler, router.idempotencyStorage)
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, payment.Scope)
+	enrollmentMux.Handle("POST /open-banking/enrollments/v2/enrollments", handler)
+
+	handler = router.getEnrollmentHandler()
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, payment.Scope)
+	enrollmentMux.Handle("GET /open-banking/enrollments/v2/enrollments/{id}", handler)
+
+	handler = router.cancelEnrollmentHandler()
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, payment.Scope)
+	enrollmentMux.Handle("PATCH /open-banking/enrollments/v2/enrollments/{id}", handler)
+
+	handler = router.riskSignalsHandler()
+	handler = idempotency.Middleware(handler, router.idempotencyStorage)
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, payment.Scope)
+	enrollmentMux.Handle("POST /open-banking/enrollments/v2/enrollments/{id}/risk-signals", handler)
+
+	handler = router.registrationOptionsHandler()
+	handler = idempotency.Middleware(handler, router.idempotencyStorage)
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, goidc.ScopeOpenID, ScopeID, Scope)
+	enrollmentMux.Handle("POST /open-banking/enrollments/v2/enrollments/{id}/fido-registration-options", handler)
+
+	handler = router.registrationHandler()
+	handler = idempotency.Middleware(handler, router.idempotencyStorage)
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, goidc.ScopeOpenID, ScopeID, Scope)
+	enrollmentMux.Handle("POST /open-banking/enrollments/v2/enrollments/{id}/fido-registration", handler)
+
+	handler = router.signOptionsHandler()
+	handler = idempotency.Middleware(handler, router.idempotencyStorage)
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, payment.Scope)
+	enrollmentMux.Handle("POST /open-banking/enrollments/v2/enrollments/{id}/fido-sign-options", handler)
+
+	handler = router.authorizeConsentHandler()
+	handler = idempotency.Middleware(handler, router.idempotencyStorage)
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, payment.Scope)
+	enrollmentMux.Handle("POST /open-banking/enrollments/v2/consents/{id}/authorise", handler)
+
+	handler = enrollmentMux
+	handler = middleware.FAPIID(handler)
+	handler = middleware.Meta(handler, router.host)
+	mux.Handle("/open-banking/enrollments/v2/", handler)
+}
+
+func (router APIRouterV2) createEnrollmentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req createEnrollmentRequestV2
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.WriteError(w, errBadRequest)
+			return
+		}
+
+		if err := req.validate(); err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		e := req.toEnrollment(r.Context())
+		if err := router.service.create(r.Context(), e); err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		resp := toEnrollmentResponseV2(e, router.host)
+		api.WriteJSON(w, resp, http.StatusCreated)
+	})
+}
+
+func (router APIRouterV2) getEnrollmentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e, err := router.service.Enrollment(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		resp := toEnrollmentResponseV2(e, router.host)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV2) cancelEnrollmentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req cancelEnrollmentRequestV2
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.WriteError(w, errBadRequest)
+			return
+		}
+
+		if err := router.service.cancel(r.Context(), r.PathValue("id"), req.toCancellation()); err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (router APIRouterV2) riskSignalsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req riskSignalsRequestV2
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Data) == 0 {
+			api.WriteError(w, errBadRequest)
+			return
+		}
+
+		if err := router.service.registerRiskSignals(r.Context(), r.PathValue("id")); err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (router APIRouterV2) registrationOptionsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req registrationOptionsRequestV2
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.WriteError(w, errBadRequest)
+			return
+		}
+
+		id := r.PathValue("id")
+		if err := req.validate(r.Context(), id); err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		e, err := router.service.registrationOptions(r.Context(), id, req.Data.RelyingParty)
+		if err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		resp := toRegistrationOptionsResponseV2(e)
+		api.WriteJSON(w, resp, http.StatusCreated)
+	})
+}
+
+func (router APIRouterV2) registrationHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req registrationRequestV2
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.WriteError(w, errBadRequest)
+			return
+		}
+
+		id := r.PathValue("id")
+		if err := req.validate(r.Context(), id); err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		resp, err := req.toAttestationResponse()
+		if err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		if err := router.service.register(r.Context(), id, req.ID, resp); err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (router APIRouterV2) signOptionsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req signOptionsRequestV2
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.WriteError(w, errBadRequest)
+			return
+		}
+
+		if err := req.validate(); err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		e, err := router.service.signOptions(r.Context(), r.PathValue("id"), req.Data.RelyingParty, req.Data.ConsentID)
+		if err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		resp := toSignOptionsResponseV2(e)
+		api.WriteJSON(w, resp, http.StatusCreated)
+	})
+}
+
+func (router APIRouterV2) authorizeConsentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req authorizeConsentRequestV2
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.WriteError(w, errBadRequest)
+			return
+		}
+
+		if err := req.validate(); err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		resp, err := req.toAssertionResponse()
+		if err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		if err := router.service.authorizeConsent(
+			r.Context(),
+			r.PathValue("id"),
+			req.Data.EnrollmentID,
+			req.Data.FIDOAssertion.ID,
+			resp,
+		); err != nil {
+			writeErrorV2(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+type createEnrollmentRequestV2 struct {
+	Data struct {
+		LoggedUser     entityV2     `json:"loggedUser"`
+		BusinessEntity *entityV2    `json:"businessEntity,omitempty"`
+		Permissions    []Permission `json:"permissions"`
+		DebtorAccount  *accountV2   `json:"debtorAccount,omitempty"`
+		EnrollmentName string       `json:"enrollmentName,omitempty"`
+	} `json:"data"`
+}
+
+type entityV2 struct {
+	Document documentV2 `json:"document"`
+}
+
+type documentV2 struct {
+	Identification string `json:"identification"`
+	Relation       string `json:"rel"`
+}
+
+type accountV2 struct {
+	ISPB   string              `json:"ispb"`
+	Issuer string              `json:"issuer,omitempty"`
+	Number string              `json:"number"`
+	Type   payment.AccountType `json:"accountType"`
+}
+
+func (req createEnrollmentRequestV2) validate() error {
+	if req.Data.LoggedUser.Document.Identification == "" ||
+		req.Data.LoggedUser.Document.Relation != defaultUserDocumentRelation {
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid logged user")
+	}
+
+	if req.Data.BusinessEntity != nil && req.Data.BusinessEntity.Document.Relation != defaultBusinessDocumentRelation {
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid business entity")
+	}
+
+	if len(req.Data.Permissions) == 0 {
+		return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "permissions are missing")
+	}
+
+	for _, p := range req.Data.Permissions {
+		if p != PermissionPaymentsInitiate {
+			return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid permission")
+		}
+	}
+
+	return nil
+}
+
+func (req createEnrollmentRequestV2) toEnrollment(ctx context.Context) Enrollment {
+	now := timex.DateTimeNow()
+	e := Enrollment{
+		ID:                   enrollmentID(),
+		Status:               StatusAwaitingRiskSignals,
+		UserCPF:              req.Data.LoggedUser.Document.Identification,
+		Permissions:          req.Data.Permissions,
+		Name:                 req.Data.EnrollmentName,
+		ClientID:             ctx.Value(api.CtxKeyClientID).(string),
+		CreationDateTime:     now,
+		StatusUpdateDateTime: now,
+	}
+
+	if req.Data.BusinessEntity != nil {
+		e.BusinessCNPJ = req.Data.BusinessEntity.Document.Identification
+	}
+
+	if req.Data.DebtorAccount != nil {
+		acc := payment.Account(*req.Data.DebtorAccount)
+		e.DebtorAccount = &acc
+	}
+
+	return e
+}
+
+type enrollmentResponseV2 struct {
+	Data struct {
+		ID                   string          `json:"enrollmentId"`
+		Status               Status          `json:"status"`
+		CreationDateTime     timex.DateTime  `json:"creationDateTime"`
+		StatusUpdateDateTime timex.DateTime  `json:"statusUpdateDateTime"`
+		Permissions          []Permission    `json:"permissions"`
+		LoggedUser           entityV2        `json:"loggedUser"`
+		BusinessEntity       *entityV2       `json:"businessEntity,omitempty"`
+		DebtorAccount        *accountV2      `json:"debtorAccount,omitempty"`
+		EnrollmentName       string          `json:"enrollmentName,omitempty"`
+		Cancellation         *cancellationV2 `json:"cancellation,omitempty"`
+	} `json:"data"`
+	Links api.Links `json:"links"`
+	Meta  api.Meta  `json:"meta"`
+}
+
+type cancellationV2 struct {
+	CancelledBy *struct {
+		Document documentV2 `json:"document"`
+	} `json:"cancelledBy,omitempty"`
+	Reason struct {
+		RejectionReason  RejectionReason  `json:"rejectionReason,omitempty"`
+		RevocationReason RevocationReason `json:"revocationReason,omitempty"`
+	} `json:"reason"`
+	CancelledFrom         payment.CancelledFrom `json:"cancelledFrom"`
+	RejectedAt            *timex.DateTime       `json:"rejectedAt,omitempty"`
+	RevokedAt             *timex.DateTime       `json:"revokedAt,omitempty"`
+	AdditionalInformation string                `json:"additionalInformation,omitempty"`
+}
+
+func toEnrollmentResponseV2(e Enrollment, host string) enrollmentResponseV2 {
+	resp := enrollmentResponseV2{
+		Links: api.NewLinks(host + "/open-banking/enrollments/v2/enrollments/" + e.ID),
+		Meta:  api.NewMeta(),
+	}
+	resp.Data.ID = e.ID
+	resp.Data.Status = e.Status
+	resp.Data.CreationDateTime = e.CreationDateTime
+	resp.Data.StatusUpdateDateTime = e.StatusUpdateDateTime
+	resp.Data.Permissions = e.Permissions
+	resp.Data.EnrollmentName = e.Name
+	resp.Data.LoggedUser = entityV2{
+		Document: documentV2{
+			Identification: e.UserCPF,
+			Relation:       defaultUserDocumentRelation,
+		},
+	}
+	if e.BusinessCNPJ != "" {
+		resp.Data.BusinessEntity = &entityV2{
+			Document: documentV2{
+				Identification: e.BusinessCNPJ,
+				Relation:       defaultBusinessDocumentRelation,
+			},
+		}
+	}
+	if e.DebtorAccount != nil {
+		acc := accountV2(*e.DebtorAccount)
+		resp.Data.DebtorAccount = &acc
+	}
+	if c := e.Cancellation; c != nil {
+		cancellation := &cancellationV2{
+			CancelledFrom:         c.From,
+			AdditionalInformation: c.AdditionalInformation,
+		}
+		cancellation.Reason.RejectionReason = c.RejectionReason
+		cancellation.Reason.RevocationReason = c.RevocationReason
+		if c.ByDocument != "" {
+			cancellation.CancelledBy = &struct {
+				Document documentV2 `json:"document"`
+			}{
+				Document: documentV2{
+					Identification: c.ByDocument,
+					Relation:       defaultUserDocumentRelation,
+				},
+			}
+		}
+		if c.RejectionReason != "" {
+			cancellation.RejectedAt = &c.At
+		} else {
+			cancellation.RevokedAt = &c.At
+		}
+		resp.Data.Cancellation = cancellation
+	}
+
+	return resp
+}
+
+type cancelEnrollmentRequestV2 struct {
+	Data struct {
+		Cancellation struct {
+			CancelledBy struct {
+				Document documentV2 `json:"document"`
+			} `json:"cancelledBy"`
+			Reason struct {
+				RejectionReason  RejectionReason  `json:"rejectionReason,omitempty"`
+				RevocationReason RevocationReason `json:"revocationReason,omitempty"`
+			} `json:"reason"`
+			AdditionalInformation string `json:"additionalInformation,omitempty"`
+		} `json:"cancellation"`
+	} `json:"data"`
+}
+
+func (req cancelEnrollmentRequestV2) toCancellation() Cancellation {
+	c := req.Data.Cancellation
+	return Cancellation{
+		ByDocument:            c.CancelledBy.Document.Identification,
+		From:                  payment.CancelledFromInitiator,
+		RejectionReason:       c.Reason.RejectionReason,
+		RevocationReason:      c.Reason.RevocationReason,
+		AdditionalInformation: c.AdditionalInformation,
+	}
+}
+
+// riskSignalsRequestV2 holds the signals of the user's device. They are not
+// evaluated, but must be informed.
+type riskSignalsRequestV2 struct {
+	Data map[string]any `json:"data"`
+}
+
+type registrationOptionsRequestV2 struct {
+	Data struct {
+		RelyingParty string   `json:"rp"`
+		Platform     Platform `json:"platform"`
+	} `json:"data"`
+}
+
+func (req registrationOptionsRequestV2) validate(ctx context.Context, id string) error {
+	if err := validateTokenEnrollment(ctx, id); err != nil {
+		return err
+	}
+
+	if req.Data.RelyingParty == "" {
+		return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "rp is missing")
+	}
+
+	return validatePlatform(req.Data.Platform)
+}
+
+type registrationOptionsResponseV2 struct {
+	Data struct {
+		EnrollmentID string `json:"enrollmentId"`
+		Challenge    string `json:"challenge"`
+		RelyingParty struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"rp"`
+		User struct {
+			ID          string `json:"id"`
+			Name        string `json:"name"`
+			DisplayName string `json:"displayName"`
+		} `json:"user"`
+		PubKeyCredParams []pubKeyCredParamV2 `json:"pubKeyCredParams"`
+		Timeout          int                 `json:"timeout"`
+		Attestation      string              `json:"attestation"`
+	} `json:"data"`
+}
+
+type pubKeyCredParamV2 struct {
+	Alg  int64  `json:"alg"`
+	Type string `json:"type"`
+}
+
+func toRegistrationOptionsResponseV2(e Enrollment) registrationOptionsResponseV2 {
+	var resp registrationOptionsResponseV2
+	resp.Data.EnrollmentID = e.ID
+	resp.Data.Challenge = e.Challenge
+	resp.Data.RelyingParty.ID = e.RelyingParty
+	resp.Data.RelyingParty.Name = e.RelyingParty
+	resp.Data.User.ID = e.ID
+	resp.Data.User.Name = e.UserCPF
+	resp.Data.User.DisplayName = e.UserCPF
+	for _, alg := range webauthn.SupportedAlgs {
+		resp.Data.PubKeyCredParams = append(resp.Data.PubKeyCredParams, pubKeyCredParamV2{
+			Alg:  alg,
+			Type: "public-key",
+		})
+	}
+	resp.Data.Timeout = fidoTimeoutMillis
+	resp.Data.Attestation = "none"
+	return resp
+}
+
+// registrationRequestV2 is the public key credential created by the
+// authenticator as defined by the WebAuthn specification.
+type registrationRequestV2 struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"`
+	} `json:"response"`
+}
+
+func (req registrationRequestV2) validate(ctx context.Context, id string) error {
+	if err := validateTokenEnrollment(ctx, id); err != nil {
+		return err
+	}
+
+	if req.ID == "" || req.Type != "public-key" {
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid credential")
+	}
+
+	return nil
+}
+
+func (req registrationRequestV2) toAttestationResponse() (webauthn.AttestationResponse, error) {
+	clientData, err := webauthn.DecodeBase64URL(req.Response.ClientDataJSON)
+	if err != nil {
+		return webauthn.AttestationResponse{}, errInvalidCredential
+	}
+
+	attestationObject, err := webauthn.DecodeBase64URL(req.Response.AttestationObject)
+	if err != nil {
+		return webauthn.AttestationResponse{}, errInvalidCredential
+	}
+
+	return webauthn.AttestationResponse{
+		ClientDataJSON:    clientData,
+		AttestationObject: attestationObject,
+	}, nil
+}
+
+type signOptionsRequestV2 struct {
+	Data struct {
+		RelyingParty string   `json:"rp"`
+		Platform     Platform `json:"platform"`
+		ConsentID    string   `json:"consentId"`
+	} `json:"data"`
+}
+
+func (req signOptionsRequestV2) validate() error {
+	if req.Data.RelyingParty == "" || req.Data.ConsentID == "" {
+		return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "rp and consentId are required")
+	}
+
+	return validatePlatform(req.Data.Platform)
+}
+
+type signOptionsResponseV2 struct {
+	Data struct {
+		Challenge        string `json:"challenge"`
+		Timeout          int    `json:"timeout"`
+		RelyingPartyID   string `json:"rpId"`
+		AllowCredentials []struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"allowCredentials"`
+	} `json:"data"`
+}
+
+func toSignOptionsResponseV2(e Enrollment) signOptionsResponseV2 {
+	var resp signOptionsResponseV2
+	resp.Data.Challenge = e.Challenge
+	resp.Data.Timeout = fidoTimeoutMillis
+	resp.Data.RelyingPartyID = e.RelyingParty
+	resp.Data.AllowCredentials = append(resp.Data.AllowCredentials, struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}{
+		ID:   e.Credential.ID,
+		Type: "public-key",
+	})
+	return resp
+}
+
+type authorizeConsentRequestV2 struct {
+	Data struct {
+		EnrollmentID  string         `json:"enrollmentId"`
+		RiskSignals   map[string]any `json:"riskSignals"`
+		FIDOAssertion struct {
+			ID       string `json:"id"`
+			RawID    string `json:"rawId"`
+			Type     string `json:"type"`
+			Response struct {
+				ClientDataJSON    string `json:"clientDataJSON"`
+				AuthenticatorData string `json:"authenticatorData"`
+				Signature         string `json:"signature"`
+				UserHandle        string `json:"userHandle,omitempty"`
+			} `json:"response"`
+		} `json:"fidoAssertion"`
+	} `json:"data"`
+}
+
+func (req authorizeConsentRequestV2) validate() error {
+	if req.Data.EnrollmentID == "" {
+		return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "enrollmentId is missing")
+	}
+
+	if len(req.Data.RiskSignals) == 0 {
+		return api.NewError("FALTAM_SINAIS_OBRIGATORIOS_DA_PLATAFORMA", http.StatusUnprocessableEntity, "risk signals are missing")
+	}
+
+	if req.Data.FIDOAssertion.ID == "" || req.Data.FIDOAssertion.Type != "public-key" {
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid fido assertion")
+	}
+
+	return nil
+}
+
+func (req authorizeConsentRequestV2) toAssertionResponse() (webauthn.AssertionResponse, error) {
+	assertion := req.Data.FIDOAssertion.Response
+	clientData, err := webauthn.DecodeBase64URL(assertion.ClientDataJSON)
+	if err != nil {
+		return webauthn.AssertionResponse{}, errInvalidAssertion
+	}
+
+	authData, err := webauthn.DecodeBase64URL(assertion.AuthenticatorData)
+	if err != nil {
+		return webauthn.AssertionResponse{}, errInvalidAssertion
+	}
+
+	sig, err := webauthn.DecodeBase64URL(assertion.Signature)
+	if err != nil {
+		return webauthn.AssertionResponse{}, errInvalidAssertion
+	}
+
+	return webauthn.AssertionResponse{
+		ClientDataJSON:    clientData,
+		AuthenticatorData: authData,
+		Signature:         sig,
+	}, nil
+}
+
+// validateTokenEnrollment verifies the token used in the request was issued
+// for the enrollment id.
+func validateTokenEnrollment(ctx context.Context, id string) error {
+	if enrollmentID, _ := ID(ctx.Value(api.CtxKeyScopes).(string)); enrollmentID != id {
+		return api.NewError("FORBIDDEN", http.StatusForbidden, "the token was not issued for the enrollment")
+	}
+	return nil
+}
+
+func validatePlatform(p Platform) error {
+	switch p {
+	case PlatformAndroid, PlatformIOS, PlatformBrowser:
+		return nil
+	default:
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid platform")
+	}
+}
+
+func writeErrorV2(w http.ResponseWriter, err error) {
+	if errors.Is(err, errAccessNotAllowed) {
+		api.WriteError(w, api.NewError("FORBIDDEN", http.StatusForbidden, errAccessNotAllowed.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidStatus) {
+		api.WriteError(w, api.NewError("STATUS_VINCULO_INVALIDO", http.StatusUnprocessableEntity, errInvalidStatus.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidRelyingParty) {
+		api.WriteError(w, api.NewError("RP_INVALIDA", http.StatusUnprocessableEntity, errInvalidRelyingParty.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidCredential) {
+		api.WriteError(w, api.NewError("PUBLIC_KEY_INVALIDA", http.StatusUnprocessableEntity, err.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidAssertion) || errors.Is(err, errDivergentUser) {
+		api.WriteError(w, api.NewError("RISCO", http.StatusUnprocessableEntity, err.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidConsentStatus) {
+		api.WriteError(w, api.NewError("STATUS_CONSENTIMENTO_INVALIDO", http.StatusUnprocessableEntity, errInvalidConsentStatus.Error()))
+		return
+	}
+
+	if errors.Is(err, errDivergentDebtorAccount) || errors.Is(err, errInvalidDebtorAccount) {
+		api.WriteError(w, api.NewError("CONTA_DEBITO_DIVERGENTE_CONSENTIMENTO_VINCULO", http.StatusUnprocessableEntity, err.Error()))
+		return
+	}
+
+	var apiErr api.Error
+	if errors.As(err, &apiErr) {
+		api.WriteError(w, apiErr)
+		return
+	}
+
+	api.WriteError(w, errBadRequest)
+}
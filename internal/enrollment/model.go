@@ -0,0 +1,145 @@
+package enrollment
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/payment"
+	"github.com/luikyv/go-open-finance/internal/timex"
+	"github.com/luikyv/go-open-finance/internal/webauthn"
+)
+
+const (
+	// maxTimeAwaitingSecs is how long an enrollment can stay in each of the
+	// statuses that wait for an action of the initiator or the user.
+	maxTimeAwaitingSecs             = 300
+	defaultUserDocumentRelation     = "CPF"
+	defaultBusinessDocumentRelation = "CNPJ"
+	// fidoTimeoutMillis is how long the user's device has to sign a
+	// challenge.
+	fidoTimeoutMillis = 300000
+)
+
+var (
+	ScopeID = goidc.NewDynamicScope("enrollment", func(requestedScope string) bool {
+		return strings.HasPrefix(requestedScope, "enrollment:")
+	})
+	// Scope is requested along with [ScopeID] when redirecting the user to
+	// validate an enrollment.
+	Scope = goidc.NewScope("nrp-consents")
+)
+
+// Enrollment links a FIDO credential of the user's device to an initiator,
+// so payment consents can be authorized without redirecting the user to the
+// account holder (Jornada Sem Redirecionamento).
+type Enrollment struct {
+	ID            string           `bson:"_id"`
+	Status        Status           `bson:"status"`
+	UserCPF       string           `bson:"user_cpf"`
+	BusinessCNPJ  string           `bson:"business_cnpj,omitempty"`
+	Permissions   []Permission     `bson:"permissions"`
+	DebtorAccount *payment.Account `bson:"debtor_account,omitempty"`
+	Name          string           `bson:"name,omitempty"`
+	Cancellation  *Cancellation    `bson:"cancellation,omitempty"`
+	// RelyingParty is the domain of the initiator, informed when requesting
+	// the FIDO registration options.
+	RelyingParty string               `bson:"relying_party,omitempty"`
+	Challenge    string               `bson:"challenge,omitempty"`
+	Credential   *webauthn.Credential `bson:"credential,omitempty"`
+	// SignConsentID is the payment consent the current challenge was issued
+	// for.
+	SignConsentID string `bson:"sign_consent_id,omitempty"`
+
+	ClientID             string         `bson:"client_id"`
+	CreationDateTime     timex.DateTime `bson:"created_at"`
+	StatusUpdateDateTime timex.DateTime `bson:"status_updated_at"`
+}
+
+// HasExpired returns true if the enrollment was waiting for an action for
+// longer than allowed.
+func (e Enrollment) HasExpired() bool {
+	if _, ok := expirationReasons[e.Status]; !ok {
+		return false
+	}
+	return timex.Now().After(e.StatusUpdateDateTime.Add(timex.Second * maxTimeAwaitingSecs))
+}
+
+func (e Enrollment) IsAuthorized() bool {
+	return e.Status == StatusAuthorized
+}
+
+// CanBeCancelled returns true if the enrollment can still be rejected or
+// revoked.
+func (e Enrollment) CanBeCancelled() bool {
+	return e.Status != StatusRejected && e.Status != StatusRevoked
+}
+
+type Status string
+
+const (
+	StatusAwaitingRiskSignals             Status = "AWAITING_RISK_SIGNALS"
+	StatusAwaitingAccountHolderValidation Status = "AWAITING_ACCOUNT_HOLDER_VALIDATION"
+	StatusAwaitingEnrollment              Status = "AWAITING_ENROLLMENT"
+	StatusAuthorized                      Status = "AUTHORISED"
+	StatusRejected                        Status = "REJECTED"
+	StatusRevoked                         Status = "REVOKED"
+)
+
+// expirationReasons maps the statuses that expire to the reason the
+// enrollment is rejected with.
+var expirationReasons = map[Status]RejectionReason{
+	StatusAwaitingRiskSignals:             RejectionReasonRiskSignalsTimeExpired,
+	StatusAwaitingAccountHolderValidation: RejectionReasonAccountHolderValidationTimeExpired,
+	StatusAwaitingEnrollment:              RejectionReasonEnrollmentTimeExpired,
+}
+
+type Permission string
+
+const (
+	PermissionPaymentsInitiate Permission = "PAYMENTS_INITIATE"
+)
+
+// Cancellation holds the information about the rejection or revocation of an
+// enrollment. Only one of the reasons is informed.
+type Cancellation struct {
+	ByDocument            string                `bson:"by_document,omitempty"`
+	From                  payment.CancelledFrom `bson:"from"`
+	RejectionReason       RejectionReason       `bson:"rejection_reason,omitempty"`
+	RevocationReason      RevocationReason      `bson:"revocation_reason,omitempty"`
+	AdditionalInformation string                `bson:"additional_information,omitempty"`
+	At                    timex.DateTime        `bson:"at"`
+}
+
+type RejectionReason string
+
+const (
+	RejectionReasonRiskSignalsTimeExpired             RejectionReason = "REJEITADO_TEMPO_EXPIRADO_RISK_SIGNALS"
+	RejectionReasonAccountHolderValidationTimeExpired RejectionReason = "REJEITADO_TEMPO_EXPIRADO_ACCOUNT_HOLDER_VALIDATION"
+	RejectionReasonEnrollmentTimeExpired              RejectionReason = "REJEITADO_TEMPO_EXPIRADO_ENROLLMENT"
+	RejectionReasonManuallyRejected                   RejectionReason = "REJEITADO_MANUALMENTE"
+	RejectionReasonFIDOFailure                        RejectionReason = "REJEITADO_FALHA_FIDO"
+	RejectionReasonOther                              RejectionReason = "REJEITADO_OUTRO"
+)
+
+type RevocationReason string
+
+const (
+	RevocationReasonManuallyRevoked RevocationReason = "REVOGADO_MANUALMENTE"
+	RevocationReasonExpired         RevocationReason = "REVOGADO_VALIDADE_EXPIRADA"
+	RevocationReasonOther           RevocationReason = "REVOGADO_OUTRO"
+)
+
+// Platform is the kind of device the FIDO credential lives in.
+type Platform string
+
+const (
+	PlatformAndroid Platform = "ANDROID"
+	PlatformIOS     Platform = "IOS"
+	PlatformBrowser Platform = "BROWSER"
+)
+
+func enrollmentID() string {
+	return fmt.Sprintf("urn:mockbank:%s", uuid.NewString())
+}
@@ -0,0 +1,319 @@
+package enrollment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/payment"
+	"github.com/luikyv/go-open-finance/internal/timex"
+	"github.com/luikyv/go-open-finance/internal/webauthn"
+)
+
+var (
+	errAccessNotAllowed       = errors.New("access to enrollment is not allowed")
+	errInvalidStatus          = errors.New("the enrollment status does not allow the operation")
+	errInvalidRelyingParty    = errors.New("the relying party is invalid")
+	errInvalidCredential      = errors.New("the fido credential is invalid")
+	errInvalidAssertion       = errors.New("the fido assertion is invalid")
+	errInvalidConsentStatus   = errors.New("the payment consent is not awaiting authorization")
+	errDivergentUser          = errors.New("the payment consent was not created for the user of the enrollment")
+	errDivergentDebtorAccount = errors.New("the debtor account of the payment consent diverges from the enrollment")
+	errInvalidDebtorAccount   = errors.New("the debtor account does not belong to the user")
+)
+
+// ID extracts the enrollment ID from the scopes.
+func ID(scopes string) (string, bool) {
+	for _, s := range strings.Split(scopes, " ") {
+		if ScopeID.Matches(s) {
+			return strings.Replace(s, "enrollment:", "", 1), true
+		}
+	}
+	return "", false
+}
+
+type Service struct {
+	storage        Storage
+	paymentService payment.Service
+}
+
+func NewService(st Storage, paymentService payment.Service) Service {
+	return Service{
+		storage:        st,
+		paymentService: paymentService,
+	}
+}
+
+// Authorize registers the enrollment was validated by the account holder, so
+// the initiator can proceed with the registration of the FIDO credential.
+func (s Service) Authorize(ctx context.Context, e Enrollment) error {
+
+	slog.DebugContext(ctx, "trying to authorize enrollment", slog.String("enrollment_id", e.ID))
+
+	if e.Status != StatusAwaitingAccountHolderValidation {
+		slog.DebugContext(ctx, "cannot authorize an enrollment that is not awaiting account holder validation", slog.Any("status", e.Status))
+		return errInvalidStatus
+	}
+
+	slog.InfoContext(ctx, "enrollment validated by the account holder", slog.String("enrollment_id", e.ID))
+	return s.updateStatus(ctx, e, StatusAwaitingEnrollment)
+}
+
+func (s Service) Enrollment(ctx context.Context, id string) (Enrollment, error) {
+	e, err := s.storage.enrollment(ctx, id)
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	if ctx.Value(api.CtxKeyClientID) != nil && ctx.Value(api.CtxKeyClientID) != e.ClientID {
+		return Enrollment{}, errAccessNotAllowed
+	}
+
+	if err := s.modify(ctx, &e); err != nil {
+		return Enrollment{}, err
+	}
+
+	return e, nil
+}
+
+// Reject rejects the enrollment on behalf of the account holder.
+func (s Service) Reject(ctx context.Context, id string, reason RejectionReason) error {
+	return s.cancel(ctx, id, Cancellation{
+		From:            payment.CancelledFromHolder,
+		RejectionReason: reason,
+	})
+}
+
+func (s Service) create(ctx context.Context, e Enrollment) error {
+	return s.save(ctx, e)
+}
+
+// registerRiskSignals moves the enrollment forward once the initiator informs
+// the signals of the user's device.
+func (s Service) registerRiskSignals(ctx context.Context, id string) error {
+	e, err := s.Enrollment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if e.Status != StatusAwaitingRiskSignals {
+		return errInvalidStatus
+	}
+
+	return s.updateStatus(ctx, e, StatusAwaitingAccountHolderValidation)
+}
+
+// registrationOptions issues the challenge the user's device must sign when
+// creating the FIDO credential.
+func (s Service) registrationOptions(ctx context.Context, id, rp string) (Enrollment, error) {
+	e, err := s.Enrollment(ctx, id)
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	if e.Status != StatusAwaitingEnrollment {
+		return Enrollment{}, errInvalidStatus
+	}
+
+	e.RelyingParty = rp
+	e.Challenge = webauthn.NewChallenge()
+	if err := s.save(ctx, e); err != nil {
+		return Enrollment{}, err
+	}
+
+	return e, nil
+}
+
+// register verifies the attestation of the FIDO credential created by the
+// user's device and authorizes the enrollment.
+func (s Service) register(ctx context.Context, id, credentialID string, resp webauthn.AttestationResponse) error {
+	e, err := s.Enrollment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if e.Status != StatusAwaitingEnrollment || e.Challenge == "" {
+		return errInvalidStatus
+	}
+
+	cred, err := webauthn.VerifyRegistration(e.RelyingParty, e.Challenge, resp)
+	if errors.Is(err, webauthn.ErrInvalidRelyingParty) {
+		return errInvalidRelyingParty
+	}
+	if err != nil {
+		slog.DebugContext(ctx, "invalid fido registration", slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %w", errInvalidCredential, err)
+	}
+
+	if cred.ID != strings.TrimRight(credentialID, "=") {
+		return errInvalidCredential
+	}
+
+	slog.InfoContext(ctx, "fido credential registered", slog.String("enrollment_id", e.ID))
+	e.Credential = &cred
+	e.Challenge = ""
+	return s.updateStatus(ctx, e, StatusAuthorized)
+}
+
+// signOptions issues the challenge the user's device must sign to authorize
+// the payment consent.
+func (s Service) signOptions(ctx context.Context, id, rp, consentID string) (Enrollment, error) {
+	e, err := s.Enrollment(ctx, id)
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	if !e.IsAuthorized() {
+		return Enrollment{}, errInvalidStatus
+	}
+
+	if rp != e.RelyingParty {
+		return Enrollment{}, errInvalidRelyingParty
+	}
+
+	c, err := s.paymentService.Consent(ctx, consentID)
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	if !c.IsAwaitingAuthorization() {
+		return Enrollment{}, errInvalidConsentStatus
+	}
+
+	e.Challenge = webauthn.NewChallenge()
+	e.SignConsentID = consentID
+	if err := s.save(ctx, e); err != nil {
+		return Enrollment{}, err
+	}
+
+	return e, nil
+}
+
+// authorizeConsent verifies the FIDO assertion generated for the payment
+// consent and authorizes it without redirecting the user.
+func (s Service) authorizeConsent(ctx context.Context, consentID, id, credentialID string, resp webauthn.AssertionResponse) error {
+	e, err := s.Enrollment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !e.IsAuthorized() || e.Credential == nil {
+		return errInvalidStatus
+	}
+
+	c, err := s.paymentService.Consent(ctx, consentID)
+	if err != nil {
+		return err
+	}
+
+	if !c.IsAwaitingAuthorization() {
+		return errInvalidConsentStatus
+	}
+
+	if c.UserCPF != e.UserCPF || c.BusinessCNPJ != e.BusinessCNPJ {
+		return errDivergentUser
+	}
+
+	if e.DebtorAccount != nil {
+		if c.DebtorAccount != nil && *c.DebtorAccount != *e.DebtorAccount {
+			return errDivergentDebtorAccount
+		}
+		c.DebtorAccount = e.DebtorAccount
+	}
+
+	// The challenge can only be used for the consent it was issued for.
+	if e.Challenge == "" || e.SignConsentID != consentID ||
+		strings.TrimRight(credentialID, "=") != e.Credential.ID {
+		return errInvalidAssertion
+	}
+
+	signCount, err := webauthn.VerifyAssertion(e.RelyingParty, e.Challenge, *e.Credential, resp)
+	if err != nil {
+		slog.DebugContext(ctx, "invalid fido assertion", slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %w", errInvalidAssertion, err)
+	}
+
+	e.Challenge = ""
+	e.SignConsentID = ""
+	e.Credential.SignCount = signCount
+	if err := s.save(ctx, e); err != nil {
+		return err
+	}
+
+	if c.DebtorAccount != nil && !s.paymentService.IsDebtorAccount(c.UserCPF, *c.DebtorAccount) {
+		_ = s.paymentService.Reject(ctx, c.ID, payment.RejectionReason{
+			Code:   payment.RejectionReasonCodeAccountDoesNotAllowPayment,
+			Detail: errInvalidDebtorAccount.Error(),
+		})
+		return errInvalidDebtorAccount
+	}
+
+	c.EnrollmentID = e.ID
+	slog.InfoContext(ctx, "authorizing payment consent with enrollment", slog.String("enrollment_id", e.ID))
+	return s.paymentService.Authorize(ctx, c)
+}
+
+// cancel rejects the enrollment if it was not authorized yet, otherwise it is
+// revoked.
+func (s Service) cancel(ctx context.Context, id string, cancellation Cancellation) error {
+	e, err := s.Enrollment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !e.CanBeCancelled() {
+		return errInvalidStatus
+	}
+
+	cancellation.At = timex.DateTimeNow()
+	status := StatusRejected
+	if e.IsAuthorized() {
+		status = StatusRevoked
+		cancellation.RejectionReason = ""
+		if cancellation.RevocationReason == "" {
+			cancellation.RevocationReason = RevocationReasonOther
+		}
+	} else {
+		cancellation.RevocationReason = ""
+		if cancellation.RejectionReason == "" {
+			cancellation.RejectionReason = RejectionReasonOther
+		}
+	}
+
+	e.Cancellation = &cancellation
+	e.Challenge = ""
+	e.SignConsentID = ""
+	return s.updateStatus(ctx, e, status)
+}
+
+// modify will evaluate the enrollment information and modify it to be
+// compliant.
+func (s Service) modify(ctx context.Context, e *Enrollment) error {
+	if e.HasExpired() {
+		slog.DebugContext(ctx, "enrollment waiting for too long, moving to rejected", slog.Any("status", e.Status))
+		now := timex.DateTimeNow()
+		e.Cancellation = &Cancellation{
+			From:            payment.CancelledFromHolder,
+			RejectionReason: expirationReasons[e.Status],
+			At:              now,
+		}
+		e.Status = StatusRejected
+		e.StatusUpdateDateTime = now
+		return s.save(ctx, *e)
+	}
+
+	return nil
+}
+
+func (s Service) updateStatus(ctx context.Context, e Enrollment, status Status) error {
+	e.Status = status
+	e.StatusUpdateDateTime = timex.DateTimeNow()
+	return s.save(ctx, e)
+}
+
+func (s Service) save(ctx context.Context, e Enrollment) error {
+	return s.storage.save(ctx, e)
+}
@@ -0,0 +1,46 @@
+package enrollment
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Storage struct {
+	collection *mongo.Collection
+}
+
+func NewStorage(db *mongo.Database) Storage {
+	return Storage{
+		collection: db.Collection("enrollments"),
+	}
+}
+
+func (st Storage) save(ctx context.Context, e Enrollment) error {
+	shouldUpsert := true
+	filter := bson.D{{Key: "_id", Value: e.ID}}
+	if _, err := st.collection.ReplaceOne(ctx, filter, e, &options.ReplaceOptions{
+		Upsert: &shouldUpsert,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (st Storage) enrollment(ctx context.Context, id string) (Enrollment, error) {
+	filter := bson.D{{Key: "_id", Value: id}}
+	result := st.collection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		return Enrollment{}, result.Err()
+	}
+
+	var e Enrollment
+	if err := result.Decode(&e); err != nil {
+		return Enrollment{}, err
+	}
+
+	return e, nil
+}
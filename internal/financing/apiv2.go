@@ -0,0 +1,16 @@
+package financing
+
+import (
+	"github.com/luikyv/go-oidc/pkg/provider"
+	"github.com/luikyv/go-open-finance/internal/consent"
+	"github.com/luikyv/go-open-finance/internal/credit"
+)
+
+func NewAPIRouterV2(host string, service Service, consentService consent.Service, op *provider.Provider) credit.APIRouterV2 {
+	return credit.NewAPIRouterV2(host, "/open-banking/financings/v2", credit.PermissionsV2{
+		Read:                     consent.PermissionFinancingsRead,
+		WarrantiesRead:           consent.PermissionFinancingsWarrantiesRead,
+		ScheduledInstalmentsRead: consent.PermissionFinancingsScheduledInstalmentsRead,
+		PaymentsRead:             consent.PermissionFinancingsPaymentsRead,
+	}, service, consentService, op)
+}
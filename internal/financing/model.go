@@ -0,0 +1,31 @@
+package financing
+
+import (
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/credit"
+)
+
+var (
+	Scope = goidc.NewScope("financings")
+)
+
+type Contract struct {
+	credit.Contract
+	UserID string
+}
+
+const (
+	ProductTypeFinancing credit.ProductType = "FINANCIAMENTOS"
+)
+
+const (
+	ProductSubTypeVehicles          credit.ProductSubType = "AQUISICAO_BENS_VEICULOS_AUTOMOTORES"
+	ProductSubTypeOtherGoods        credit.ProductSubType = "AQUISICAO_BENS_OUTROS_BENS"
+	ProductSubTypeMicrocredit       credit.ProductSubType = "MICROCREDITO"
+	ProductSubTypeCosting           credit.ProductSubType = "CUSTEIO"
+	ProductSubTypeInvestment        credit.ProductSubType = "INVESTIMENTO"
+	ProductSubTypeIndustrialization credit.ProductSubType = "INDUSTRIALIZACAO"
+	ProductSubTypeCommercialization credit.ProductSubType = "COMERCIALIZACAO"
+	ProductSubTypeHousingSFH        credit.ProductSubType = "FINANCIAMENTO_HABITACIONAL_SFH"
+	ProductSubTypeHousingExceptSFH  credit.ProductSubType = "FINANCIAMENTO_HABITACIONAL_EXCETO_SFH"
+)
@@ -0,0 +1,54 @@
+package financing
+
+import (
+	"context"
+	"slices"
+
+	"github.com/luikyv/go-open-finance/internal/consent"
+	"github.com/luikyv/go-open-finance/internal/credit"
+	"github.com/luikyv/go-open-finance/internal/page"
+)
+
+type Service struct {
+	storage        *Storage
+	consentService consent.Service
+}
+
+func NewService(storage *Storage, consentService consent.Service) Service {
+	return Service{
+		storage:        storage,
+		consentService: consentService,
+	}
+}
+
+func (s Service) Add(userID string, contract Contract) {
+	contract.UserID = userID
+	s.storage.save(contract)
+}
+
+func (s Service) Contracts(ctx context.Context, consentID string, pag page.Pagination) (page.Page[credit.Contract], error) {
+	c, err := s.consentService.Consent(ctx, consentID)
+	if err != nil {
+		return page.Page[credit.Contract]{}, err
+	}
+
+	var contracts []credit.Contract
+	for _, id := range c.FinancingIDs {
+		contracts = append(contracts, s.storage.contract(id).Contract)
+	}
+
+	return page.Paginate(contracts, pag), nil
+}
+
+func (s Service) Contract(ctx context.Context, id, consentID string) (credit.Contract, error) {
+	c, err := s.consentService.Consent(ctx, consentID)
+	if err != nil {
+		return credit.Contract{}, err
+	}
+
+	if !slices.Contains(c.FinancingIDs, id) {
+		return credit.Contract{}, credit.ErrContractNotAllowed
+	}
+
+	return s.storage.contract(id).Contract, nil
+}
@@ -0,0 +1,19 @@
+package financing
+
+type Storage struct {
+	contractsMap map[string]Contract
+}
+
+func NewStorage() *Storage {
+	return &Storage{
+		contractsMap: map[string]Contract{},
+	}
+}
+
+func (s *Storage) save(contract Contract) {
+	s.contractsMap[contract.ID] = contract
+}
+
+func (s *Storage) contract(id string) Contract {
+	return s.contractsMap[id]
+}
@@ -0,0 +1,158 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+const headerIdempotencyKey = "X-Idempotency-Key"
+
+var (
+	errMissingKey      = api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusBadRequest, "the idempotency key was not informed")
+	errKeyReused       = api.NewError("ERRO_IDEMPOTENCIA", http.StatusUnprocessableEntity, "the idempotency key was already used with a different request")
+	errKeyInUse        = api.NewError("ERRO_IDEMPOTENCIA", http.StatusUnprocessableEntity, "the request with the idempotency key is still being processed")
+	errRequestNotRead  = api.NewError("PARAMETRO_INVALIDO", http.StatusBadRequest, "could not read the request body")
+	errRecordNotLoaded = api.NewError("INTERNAL_ERROR", http.StatusInternalServerError, "could not verify the idempotency key")
+)
+
+// Middleware makes the requests handled by next idempotent based on the
+// x-idempotency-key header.
+// The first response returned for a key is stored and replayed whenever the
+// same request is retried with that key. Reusing the key with a different
+// request results in an error, as does retrying it while the first request is
+// still being handled.
+// This middleware must run after the client was identified.
+func Middleware(next http.Handler, st Storage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		key := r.Header.Get(headerIdempotencyKey)
+		if key == "" {
+			api.WriteError(w, errMissingKey)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			api.WriteError(w, errRequestNotRead)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		id := recordID(ctx.Value(api.CtxKeyClientID).(string), key)
+		hash := requestHash(r, body)
+
+		// The key is claimed before the request is handled, so concurrent
+		// requests with the same key are not handled twice.
+		now := timex.Now()
+		err = st.claim(ctx, Record{
+			ID:           id,
+			RequestHash:  hash,
+			Pending:      true,
+			PendingUntil: now.Add(pendingLifetime),
+			CreatedAt:    now,
+		})
+		if errors.Is(err, errRecordExists) {
+			replay(w, r, st, id, hash)
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "could not claim idempotency key", slog.String("error", err.Error()))
+			api.WriteError(w, errRecordNotLoaded)
+			return
+		}
+
+		rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		// Server errors are not stored so the request can be retried.
+		if rw.status >= http.StatusInternalServerError {
+			if err := st.delete(ctx, id); err != nil {
+				slog.ErrorContext(ctx, "could not release idempotency key", slog.String("error", err.Error()))
+			}
+			return
+		}
+
+		if err := st.save(ctx, Record{
+			ID:          id,
+			RequestHash: hash,
+			StatusCode:  rw.status,
+			ContentType: w.Header().Get("Content-Type"),
+			Body:        rw.body.Bytes(),
+			CreatedAt:   timex.Now(),
+		}); err != nil {
+			slog.ErrorContext(ctx, "could not save idempotency record", slog.String("error", err.Error()))
+		}
+	})
+}
+
+// replay writes the response stored for the key of a request that was already
+// handled. Requests whose key is still being handled are refused, since their
+// response is not known yet. Once the claim of the key expires, retrying the
+// request handles it again.
+func replay(w http.ResponseWriter, r *http.Request, st Storage, id, hash string) {
+	ctx := r.Context()
+	rec, err := st.record(ctx, id)
+	// The record is missing if the request that claimed the key failed and
+	// released it in the meantime.
+	if errors.Is(err, errRecordNotFound) {
+		api.WriteError(w, errKeyInUse)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "could not load idempotency record", slog.String("error", err.Error()))
+		api.WriteError(w, errRecordNotLoaded)
+		return
+	}
+
+	if rec.RequestHash != hash {
+		slog.DebugContext(ctx, "idempotency key reused with a different request", slog.String("id", id))
+		api.WriteError(w, errKeyReused)
+		return
+	}
+
+	if rec.Pending {
+		slog.DebugContext(ctx, "idempotency key is still in use", slog.String("id", id))
+		api.WriteError(w, errKeyInUse)
+		return
+	}
+
+	slog.DebugContext(ctx, "replaying response for idempotency key", slog.String("id", id))
+	if rec.ContentType != "" {
+		w.Header().Set("Content-Type", rec.ContentType)
+	}
+	w.WriteHeader(rec.StatusCode)
+	_, _ = w.Write(rec.Body)
+}
+
+// requestHash identifies a request by its method, path and body.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder writes the response while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *responseRecorder) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
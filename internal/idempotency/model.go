@@ -0,0 +1,36 @@
+package idempotency
+
+import (
+	"time"
+)
+
+const (
+	// recordLifetime is for how long the responses are kept to be replayed.
+	recordLifetime = 24 * time.Hour
+	// pendingLifetime is for how long a key is claimed by a request that is
+	// being handled. After that, the key is considered free, so it is not
+	// claimed forever if the request never finishes, e.g. the server stopped
+	// while handling it.
+	pendingLifetime = time.Minute
+)
+
+// Record holds the response returned for a request identified by an
+// idempotency key, so the same response can be replayed when the request is
+// retried.
+// The record is created as pending before the request is handled, so the key
+// is claimed by a single request, and the response is filled in afterwards.
+type Record struct {
+	ID          string `bson:"_id"`
+	RequestHash string `bson:"request_hash"`
+	Pending     bool   `bson:"pending"`
+	// PendingUntil is when the claim of a pending record expires.
+	PendingUntil time.Time `bson:"pending_until,omitempty"`
+	StatusCode   int       `bson:"status_code"`
+	ContentType  string    `bson:"content_type,omitempty"`
+	Body         []byte    `bson:"body,omitempty"`
+	CreatedAt    time.Time `bson:"created_at"`
+}
+
+func recordID(clientID, key string) string {
+	return clientID + ":" + key
+}
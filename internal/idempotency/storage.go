@@ -0,0 +1,99 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+
+	"github.com/luikyv/go-open-finance/internal/timex"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	errRecordNotFound = errors.New("idempotency record not found")
+	errRecordExists   = errors.New("idempotency record already exists")
+)
+
+type Storage struct {
+	collection *mongo.Collection
+}
+
+func NewStorage(db *mongo.Database) Storage {
+	return Storage{
+		collection: db.Collection("idempotency_records"),
+	}
+}
+
+// CreateIndexes creates the TTL index responsible for removing the records
+// after their lifetime.
+func (st Storage) CreateIndexes(ctx context.Context) error {
+	_, err := st.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(recordLifetime.Seconds())),
+	})
+	return err
+}
+
+// claim creates the record if no other record exists for its key or if the
+// existing one is pending and its claim expired. Otherwise, errRecordExists
+// is returned.
+func (st Storage) claim(ctx context.Context, rec Record) error {
+	// If no expired pending record matches, the upsert tries to insert a new
+	// one, which fails if another record exists for the key.
+	shouldUpsert := true
+	filter := bson.D{
+		{Key: "_id", Value: rec.ID},
+		{Key: "pending", Value: true},
+		{Key: "pending_until", Value: bson.D{{Key: "$lt", Value: timex.Now()}}},
+	}
+	if _, err := st.collection.ReplaceOne(ctx, filter, rec, &options.ReplaceOptions{
+		Upsert: &shouldUpsert,
+	}); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errRecordExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (st Storage) save(ctx context.Context, rec Record) error {
+	shouldUpsert := true
+	filter := bson.D{{Key: "_id", Value: rec.ID}}
+	if _, err := st.collection.ReplaceOne(ctx, filter, rec, &options.ReplaceOptions{
+		Upsert: &shouldUpsert,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (st Storage) record(ctx context.Context, id string) (Record, error) {
+	filter := bson.D{{Key: "_id", Value: id}}
+	result := st.collection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return Record{}, errRecordNotFound
+		}
+		return Record{}, result.Err()
+	}
+
+	var rec Record
+	if err := result.Decode(&rec); err != nil {
+		return Record{}, err
+	}
+
+	return rec, nil
+}
+
+func (st Storage) delete(ctx context.Context, id string) error {
+	filter := bson.D{{Key: "_id", Value: id}}
+	if _, err := st.collection.DeleteOne(ctx, filter); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package invoicefinancing
+
+import (
+	"github.com/luikyv/go-oidc/pkg/provider"
+	"github.com/luikyv/go-open-finance/internal/consent"
+	"github.com/luikyv/go-open-finance/internal/credit"
+)
+
+func NewAPIRouterV2(host string, service Service, consentService consent.Service, op *provider.Provider) credit.APIRouterV2 {
+	return credit.NewAPIRouterV2(host, "/open-banking/invoice-financings/v2", credit.PermissionsV2{
+		Read:                     consent.PermissionInvoiceFinancingsRead,
+		WarrantiesRead:           consent.PermissionInvoiceFinancingsWarrantiesRead,
+		ScheduledInstalmentsRead: consent.PermissionInvoiceFinancingsScheduledInstalmentsRead,
+		PaymentsRead:             consent.PermissionInvoiceFinancingsPaymentsRead,
+	}, service, consentService, op)
+}
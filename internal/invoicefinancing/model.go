@@ -0,0 +1,31 @@
+package invoicefinancing
+
+import (
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/credit"
+)
+
+var (
+	Scope = goidc.NewScope("invoice-financings")
+)
+
+// Contract is an invoice financing contract, i.e. the discount of receivables
+// of a company such as trade bills and cheques.
+type Contract struct {
+	credit.Contract
+	// CompanyCNPJ identifies the company that holds the contract. Invoice
+	// financings are only available to business customers.
+	CompanyCNPJ string
+}
+
+const (
+	ProductTypeDiscountedCreditRights credit.ProductType = "DIREITOS_CREDITORIOS_DESCONTADOS"
+)
+
+const (
+	ProductSubTypeTradeBillsDiscount          credit.ProductSubType = "DESCONTO_DUPLICATAS"
+	ProductSubTypeChequesDiscount             credit.ProductSubType = "DESCONTO_CHEQUES"
+	ProductSubTypeCreditCardInvoiceAdvance    credit.ProductSubType = "ANTECIPACAO_FATURA_CARTAO_CREDITO"
+	ProductSubTypeOtherDiscountedCreditRights credit.ProductSubType = "OUTROS_DIREITOS_CREDITORIOS_DESCONTADOS"
+	ProductSubTypeOtherDiscountedBills        credit.ProductSubType = "OUTROS_TITULOS_DESCONTADOS"
+)
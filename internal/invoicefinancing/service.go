@@ -0,0 +1,63 @@
+package invoicefinancing
+
+import (
+	"context"
+	"slices"
+
+	"github.com/luikyv/go-open-finance/internal/consent"
+	"github.com/luikyv/go-open-finance/internal/credit"
+	"github.com/luikyv/go-open-finance/internal/page"
+)
+
+type Service struct {
+	storage        *Storage
+	consentService consent.Service
+}
+
+func NewService(storage *Storage, consentService consent.Service) Service {
+	return Service{
+		storage:        storage,
+		consentService: consentService,
+	}
+}
+
+func (s Service) Add(companyCNPJ string, contract Contract) {
+	contract.CompanyCNPJ = companyCNPJ
+	s.storage.save(contract)
+}
+
+func (s Service) Contracts(ctx context.Context, consentID string, pag page.Pagination) (page.Page[credit.Contract], error) {
+	c, err := s.consentService.Consent(ctx, consentID)
+	if err != nil {
+		return page.Page[credit.Contract]{}, err
+	}
+
+	var contracts []credit.Contract
+	for _, id := range c.InvoiceFinancingIDs {
+		if contract := s.storage.contract(id); contract.CompanyCNPJ == c.BusinessCNPJ {
+			contracts = append(contracts, contract.Contract)
+		}
+	}
+
+	return page.Paginate(contracts, pag), nil
+}
+
+func (s Service) Contract(ctx context.Context, id, consentID string) (credit.Contract, error) {
+	c, err := s.consentService.Consent(ctx, consentID)
+	if err != nil {
+		return credit.Contract{}, err
+	}
+
+	if !slices.Contains(c.InvoiceFinancingIDs, id) {
+		return credit.Contract{}, credit.ErrContractNotAllowed
+	}
+
+	// Only consents created for the company holding the contract give access
+	// to it.
+	contract := s.storage.contract(id)
+	if c.BusinessCNPJ == "" || contract.CompanyCNPJ != c.BusinessCNPJ {
+		return credit.Contract{}, credit.ErrContractNotAllowed
+	}
+
+	return contract.Contract, nil
+}
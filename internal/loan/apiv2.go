@@ -0,0 +1,16 @@
+package loan
+
+import (
+	"github.com/luikyv/go-oidc/pkg/provider"
+	"github.com/luikyv/go-open-finance/internal/consent"
+	"github.com/luikyv/go-open-finance/internal/credit"
+)
+
+func NewAPIRouterV2(host string, service Service, consentService consent.Service, op *provider.Provider) credit.APIRouterV2 {
+	return credit.NewAPIRouterV2(host, "/open-banking/loans/v2", credit.PermissionsV2{
+		Read:                     consent.PermissionLoansRead,
+		WarrantiesRead:           consent.PermissionLoansWarrantiesRead,
+		ScheduledInstalmentsRead: consent.PermissionLoansScheduledInstalmentsRead,
+		PaymentsRead:             consent.PermissionLoansPaymentsRead,
+	}, service, consentService, op)
+}
@@ -0,0 +1,32 @@
+package loan
+
+import (
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/credit"
+)
+
+var (
+	Scope = goidc.NewScope("loans")
+)
+
+type Contract struct {
+	credit.Contract
+	UserID string
+}
+
+const (
+	ProductTypeLoan credit.ProductType = "EMPRESTIMOS"
+)
+
+const (
+	ProductSubTypeHomeEquity              credit.ProductSubType = "HOME_EQUITY"
+	ProductSubTypeOverdraft               credit.ProductSubType = "CHEQUE_ESPECIAL"
+	ProductSubTypeGuaranteedAccount       credit.ProductSubType = "CONTA_GARANTIDA"
+	ProductSubTypeRevolvingWorkingCapital credit.ProductSubType = "CAPITAL_GIRO_TETO_ROTATIVO"
+	ProductSubTypePersonalLoan            credit.ProductSubType = "CREDITO_PESSOAL_SEM_CONSIGNACAO"
+	ProductSubTypePayrollLoan             credit.ProductSubType = "CREDITO_PESSOAL_COM_CONSIGNACAO"
+	ProductSubTypeMicrocredit             credit.ProductSubType = "MICROCREDITO_PRODUTIVO_ORIENTADO"
+	ProductSubTypeShortTermWorkingCapital credit.ProductSubType = "CAPITAL_GIRO_PRAZO_VENCIMENTO_ATE_365_DIAS"
+	ProductSubTypeLongTermWorkingCapital  credit.ProductSubType = "CAPITAL_GIRO_PRAZO_VENCIMENTO_SUPERIOR_365_DIAS"
+	ProductSubTypeOthers                  credit.ProductSubType = "OUTROS"
+)
@@ -10,6 +10,10 @@ const (
 	CPFWithJointAccount string = "96362357086"
 	MockBankBrand       string = "MockBank"
 	MockBankCNPJ        string = "58540569000120"
+	// MockBankISPB identifies MockBank in the Pix arrangement. It is the root
+	// of its CNPJ.
+	MockBankISPB  string = "58540569"
+	MockBankOrgID string = "76b370e3-def5-4798-8b6a-915cb5d6dd74"
 )
 
 func IsJointAccountPendingAuth(consentAuthorizedAt timex.DateTime) bool {
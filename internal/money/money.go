@@ -0,0 +1,41 @@
+// Package money handles the amounts exchanged by the APIs, which are
+// represented as strings with two decimal places, e.g. "1500.25".
+package money
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount = errors.New("invalid amount")
+
+	amountPattern = regexp.MustCompile(`^-?\d{1,16}\.\d{2}$`)
+)
+
+// Parse converts an amount to cents.
+func Parse(amount string) (int64, error) {
+	if !amountPattern.MatchString(amount) {
+		return 0, ErrInvalidAmount
+	}
+
+	cents, err := strconv.ParseInt(strings.Replace(amount, ".", "", 1), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+
+	return cents, nil
+}
+
+// Format converts cents to an amount.
+func Format(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
@@ -10,7 +10,10 @@ import (
 	"strings"
 
 	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/autopayment"
 	"github.com/luikyv/go-open-finance/internal/consent"
+	"github.com/luikyv/go-open-finance/internal/enrollment"
+	"github.com/luikyv/go-open-finance/internal/payment"
 	"github.com/luikyv/go-open-finance/internal/timex"
 	"github.com/luikyv/go-open-finance/internal/user"
 )
@@ -19,6 +22,9 @@ func Policy(
 	templatesDir, baseURL string,
 	userService user.Service,
 	consentService consent.Service,
+	paymentService payment.Service,
+	autoPaymentService autopayment.Service,
+	enrollmentService enrollment.Service,
 ) goidc.AuthnPolicy {
 
 	loginTemplate := filepath.Join(templatesDir, "/login.html")
@@ -29,10 +35,13 @@ func Policy(
 	}
 
 	authenticator := authenticator{
-		tmpl:           tmpl,
-		baseURL:        baseURL,
-		userService:    userService,
-		consentService: consentService,
+		tmpl:               tmpl,
+		baseURL:            baseURL,
+		userService:        userService,
+		consentService:     consentService,
+		paymentService:     paymentService,
+		autoPaymentService: autoPaymentService,
+		enrollmentService:  enrollmentService,
 	}
 	return goidc.NewPolicy(
 		"main",
@@ -61,23 +70,46 @@ const (
 	passwordFormParam = "password"
 	loginFormParam    = "login"
 	consentFormParam  = "consent"
+	// debtorAccountFormParam is the number of the account the user selected
+	// to be debited by the payments of the consent.
+	debtorAccountFormParam = "debtor_account"
 
 	correctPassword = "pass"
 )
 
 type authnPage struct {
-	CallbackID   string
-	UserCPF      string
-	BusinessCNPJ string
-	Permissions  []consent.Permission
-	Error        string
+	CallbackID         string
+	UserCPF            string
+	BusinessCNPJ       string
+	Permissions        []consent.Permission
+	PaymentAmount      string
+	CreditorName       string
+	Sweeping           bool
+	TotalAllowedAmount string
+	Automatic          bool
+	Interval           string
+	Enrollment         bool
+	EnrollmentName     string
+	// DebtorAccounts are the accounts the user can choose from when the
+	// payment consent doesn't inform a debtor account.
+	DebtorAccounts []debtorAccountOption
+	Error          string
+}
+
+type debtorAccountOption struct {
+	Number          string
+	Type            string
+	AvailableAmount string
 }
 
 type authenticator struct {
-	tmpl           *template.Template
-	baseURL        string
-	userService    user.Service
-	consentService consent.Service
+	tmpl               *template.Template
+	baseURL            string
+	userService        user.Service
+	consentService     consent.Service
+	paymentService     payment.Service
+	autoPaymentService autopayment.Service
+	enrollmentService  enrollment.Service
 }
 
 func (a authenticator) authenticate(w http.ResponseWriter, r *http.Request, session *goidc.AuthnSession) (goidc.AuthnStatus, error) {
@@ -110,11 +142,23 @@ func (a authenticator) authenticate(w http.ResponseWriter, r *http.Request, sess
 }
 
 func (a authenticator) setUp(r *http.Request, session *goidc.AuthnSession) (goidc.AuthnStatus, error) {
+	if isEnrollmentFlow(session) {
+		return a.setUpEnrollment(r, session)
+	}
+
+	if isRecurringPaymentFlow(session) {
+		return a.setUpRecurringPayment(r, session)
+	}
+
 	consentID, ok := consent.ID(session.Scopes)
 	if !ok {
 		return goidc.StatusFailure, errors.New("missing consent ID")
 	}
 
+	if isPaymentFlow(session) {
+		return a.setUpPayment(r, session, consentID)
+	}
+
 	consent, err := a.consentService.Consent(r.Context(), consentID)
 	if err != nil {
 		return goidc.StatusFailure, err
@@ -151,6 +195,97 @@ func (a authenticator) setUp(r *http.Request, session *goidc.AuthnSession) (goid
 	return goidc.StatusSuccess, nil
 }
 
+func (a authenticator) setUpPayment(r *http.Request, session *goidc.AuthnSession, consentID string) (goidc.AuthnStatus, error) {
+	c, err := a.paymentService.Consent(r.Context(), consentID)
+	if err != nil {
+		return goidc.StatusFailure, err
+	}
+
+	if !c.IsAwaitingAuthorization() {
+		return goidc.StatusFailure, errors.New("payment consent is not awaiting authorization")
+	}
+
+	user, err := a.userService.UserByCPF(c.UserCPF)
+	if err != nil {
+		return goidc.StatusFailure, errors.New("the payment consent was created for an user that does not exist")
+	}
+
+	if c.BusinessCNPJ != "" && !user.OwnsCompany(c.BusinessCNPJ) {
+		return goidc.StatusFailure, errors.New("the payment consent was created for a business that is not available to the logged user")
+	}
+
+	session.StoreParameter(paramConsentID, c.ID)
+	session.StoreParameter(paramConsentCPF, c.UserCPF)
+	if c.BusinessCNPJ != "" {
+		session.StoreParameter(paramConsentCNPJ, c.BusinessCNPJ)
+	}
+	return goidc.StatusSuccess, nil
+}
+
+func (a authenticator) setUpRecurringPayment(r *http.Request, session *goidc.AuthnSession) (goidc.AuthnStatus, error) {
+	consentID, ok := autopayment.ConsentID(session.Scopes)
+	if !ok {
+		return goidc.StatusFailure, errors.New("missing recurring consent ID")
+	}
+
+	c, err := a.autoPaymentService.Consent(r.Context(), consentID)
+	if err != nil {
+		return goidc.StatusFailure, err
+	}
+
+	if !c.IsAwaitingAuthorization() {
+		return goidc.StatusFailure, errors.New("recurring consent is not awaiting authorization")
+	}
+
+	user, err := a.userService.UserByCPF(c.UserCPF)
+	if err != nil {
+		return goidc.StatusFailure, errors.New("the recurring consent was created for an user that does not exist")
+	}
+
+	if c.BusinessCNPJ != "" && !user.OwnsCompany(c.BusinessCNPJ) {
+		return goidc.StatusFailure, errors.New("the recurring consent was created for a business that is not available to the logged user")
+	}
+
+	session.StoreParameter(paramConsentID, c.ID)
+	session.StoreParameter(paramConsentCPF, c.UserCPF)
+	if c.BusinessCNPJ != "" {
+		session.StoreParameter(paramConsentCNPJ, c.BusinessCNPJ)
+	}
+	return goidc.StatusSuccess, nil
+}
+
+func (a authenticator) setUpEnrollment(r *http.Request, session *goidc.AuthnSession) (goidc.AuthnStatus, error) {
+	enrollmentID, ok := enrollment.ID(session.Scopes)
+	if !ok {
+		return goidc.StatusFailure, errors.New("missing enrollment ID")
+	}
+
+	e, err := a.enrollmentService.Enrollment(r.Context(), enrollmentID)
+	if err != nil {
+		return goidc.StatusFailure, err
+	}
+
+	if e.Status != enrollment.StatusAwaitingAccountHolderValidation {
+		return goidc.StatusFailure, errors.New("enrollment is not awaiting account holder validation")
+	}
+
+	user, err := a.userService.UserByCPF(e.UserCPF)
+	if err != nil {
+		return goidc.StatusFailure, errors.New("the enrollment was created for an user that does not exist")
+	}
+
+	if e.BusinessCNPJ != "" && !user.OwnsCompany(e.BusinessCNPJ) {
+		return goidc.StatusFailure, errors.New("the enrollment was created for a business that is not available to the logged user")
+	}
+
+	session.StoreParameter(paramConsentID, e.ID)
+	session.StoreParameter(paramConsentCPF, e.UserCPF)
+	if e.BusinessCNPJ != "" {
+		session.StoreParameter(paramConsentCNPJ, e.BusinessCNPJ)
+	}
+	return goidc.StatusSuccess, nil
+}
+
 func (a authenticator) login(w http.ResponseWriter, r *http.Request, session *goidc.AuthnSession) (goidc.AuthnStatus, error) {
 
 	_ = r.ParseForm()
@@ -163,11 +298,7 @@ func (a authenticator) login(w http.ResponseWriter, r *http.Request, session *go
 	}
 
 	if isLogin != "true" {
-		consentID := session.StoredParameter(paramConsentID).(string)
-		_ = a.consentService.Reject(r.Context(), consentID, consent.RejectionInfo{
-			RejectedBy: consent.RejectedByUser,
-			Reason:     consent.RejectionReasonCustomerManuallyRejected,
-		})
+		a.rejectConsent(r, session)
 		return goidc.StatusFailure, errors.New("consent not granted")
 	}
 
@@ -201,6 +332,18 @@ func (a authenticator) grantConsent(
 	error,
 ) {
 
+	if isEnrollmentFlow(session) {
+		return a.grantEnrollment(w, r, session)
+	}
+
+	if isPaymentFlow(session) {
+		return a.grantPaymentConsent(w, r, session)
+	}
+
+	if isRecurringPaymentFlow(session) {
+		return a.grantRecurringPaymentConsent(w, r, session)
+	}
+
 	_ = r.ParseForm()
 
 	var permissions []consent.Permission
@@ -224,10 +367,7 @@ func (a authenticator) grantConsent(
 	consentID := session.StoredParameter(paramConsentID).(string)
 
 	if isConsented != "true" {
-		_ = a.consentService.Reject(r.Context(), consentID, consent.RejectionInfo{
-			RejectedBy: consent.RejectedByUser,
-			Reason:     consent.RejectionReasonCustomerManuallyRejected,
-		})
+		a.rejectConsent(r, session)
 		return goidc.StatusFailure, errors.New("consent not granted")
 	}
 
@@ -250,6 +390,33 @@ func (a authenticator) grantConsent(
 	}) {
 		c.CreditAccountID = u.CreditAccountID
 	}
+	if slices.ContainsFunc(c.Permissions, func(p consent.Permission) bool {
+		return strings.HasPrefix(string(p), "LOANS_")
+	}) {
+		c.LoanIDs = u.LoanIDs
+	}
+	if slices.ContainsFunc(c.Permissions, func(p consent.Permission) bool {
+		return strings.HasPrefix(string(p), "FINANCINGS_")
+	}) {
+		c.FinancingIDs = u.FinancingIDs
+	}
+	if slices.ContainsFunc(c.Permissions, func(p consent.Permission) bool {
+		return strings.HasPrefix(string(p), "UNARRANGED_ACCOUNTS_OVERDRAFT_")
+	}) {
+		c.UnarrangedOverdraftIDs = u.UnarrangedOverdraftIDs
+	}
+	// Invoice financings are only shared through consents created for a
+	// business.
+	if c.BusinessCNPJ != "" && slices.ContainsFunc(c.Permissions, func(p consent.Permission) bool {
+		return strings.HasPrefix(string(p), "INVOICE_FINANCINGS_")
+	}) {
+		c.InvoiceFinancingIDs = u.InvoiceFinancingIDs[c.BusinessCNPJ]
+	}
+	if slices.ContainsFunc(c.Permissions, func(p consent.Permission) bool {
+		return strings.HasPrefix(string(p), "BANK_FIXED_INCOMES_")
+	}) {
+		c.BankFixedIncomeIDs = u.BankFixedIncomeIDs
+	}
 
 	if err := a.consentService.Authorize(r.Context(), c); err != nil {
 		return goidc.StatusFailure, err
@@ -257,6 +424,193 @@ func (a authenticator) grantConsent(
 	return goidc.StatusSuccess, nil
 }
 
+func (a authenticator) grantPaymentConsent(
+	w http.ResponseWriter,
+	r *http.Request,
+	session *goidc.AuthnSession,
+) (
+	goidc.AuthnStatus,
+	error,
+) {
+
+	_ = r.ParseForm()
+
+	consentID := session.StoredParameter(paramConsentID).(string)
+	c, err := a.paymentService.Consent(r.Context(), consentID)
+	if err != nil {
+		return goidc.StatusFailure, err
+	}
+
+	if c.DebtorAccount != nil && !a.paymentService.IsDebtorAccount(c.UserCPF, *c.DebtorAccount) {
+		_ = a.paymentService.Reject(r.Context(), c.ID, payment.RejectionReason{
+			Code:   payment.RejectionReasonCodeAccountDoesNotAllowPayment,
+			Detail: "the debtor account does not belong to the user",
+		})
+		return goidc.StatusFailure, errors.New("the debtor account does not belong to the user")
+	}
+
+	page := authnPage{
+		CallbackID:    session.CallbackID,
+		UserCPF:       c.UserCPF,
+		BusinessCNPJ:  c.BusinessCNPJ,
+		PaymentAmount: c.Payment.Amount,
+		CreditorName:  c.Creditor.Name,
+	}
+	if c.DebtorAccount == nil {
+		for _, acc := range a.paymentService.DebtorAccounts(c.UserCPF) {
+			page.DebtorAccounts = append(page.DebtorAccounts, debtorAccountOption{
+				Number:          acc.Number,
+				Type:            string(acc.Type),
+				AvailableAmount: acc.Balance.AvailableAmount,
+			})
+		}
+	}
+
+	isConsented := r.PostFormValue(consentFormParam)
+	if isConsented == "" {
+		return a.executeTemplate(w, "consent.html", page)
+	}
+
+	if isConsented != "true" {
+		a.rejectConsent(r, session)
+		return goidc.StatusFailure, errors.New("consent not granted")
+	}
+
+	if c.DebtorAccount == nil {
+		if err := a.paymentService.SetDebtorAccount(&c, r.PostFormValue(debtorAccountFormParam)); err != nil {
+			page.Error = "select the account to be debited"
+			return a.executeTemplate(w, "consent.html", page)
+		}
+	}
+
+	if err := a.paymentService.Authorize(r.Context(), c); err != nil {
+		return goidc.StatusFailure, err
+	}
+	return goidc.StatusSuccess, nil
+}
+
+func (a authenticator) grantRecurringPaymentConsent(
+	w http.ResponseWriter,
+	r *http.Request,
+	session *goidc.AuthnSession,
+) (
+	goidc.AuthnStatus,
+	error,
+) {
+
+	_ = r.ParseForm()
+
+	consentID := session.StoredParameter(paramConsentID).(string)
+	c, err := a.autoPaymentService.Consent(r.Context(), consentID)
+	if err != nil {
+		return goidc.StatusFailure, err
+	}
+
+	isConsented := r.PostFormValue(consentFormParam)
+	if isConsented == "" {
+		page := authnPage{
+			CallbackID:   session.CallbackID,
+			UserCPF:      c.UserCPF,
+			BusinessCNPJ: c.BusinessCNPJ,
+		}
+		if c.Configuration.Sweeping != nil {
+			page.Sweeping = true
+			page.TotalAllowedAmount = c.Configuration.Sweeping.TotalAllowedAmount
+		}
+		if automatic := c.Configuration.Automatic; automatic != nil {
+			page.Automatic = true
+			page.Interval = string(automatic.Interval)
+			page.CreditorName = c.Creditors[0].Name
+			page.PaymentAmount = automatic.FixedAmount
+			if page.PaymentAmount == "" {
+				page.PaymentAmount = automatic.MaximumVariableAmount
+			}
+		}
+		return a.executeTemplate(w, "consent.html", page)
+	}
+
+	if isConsented != "true" {
+		a.rejectConsent(r, session)
+		return goidc.StatusFailure, errors.New("consent not granted")
+	}
+
+	if err := a.autoPaymentService.Authorize(r.Context(), c); err != nil {
+		return goidc.StatusFailure, err
+	}
+	return goidc.StatusSuccess, nil
+}
+
+func (a authenticator) grantEnrollment(
+	w http.ResponseWriter,
+	r *http.Request,
+	session *goidc.AuthnSession,
+) (
+	goidc.AuthnStatus,
+	error,
+) {
+
+	_ = r.ParseForm()
+
+	enrollmentID := session.StoredParameter(paramConsentID).(string)
+	e, err := a.enrollmentService.Enrollment(r.Context(), enrollmentID)
+	if err != nil {
+		return goidc.StatusFailure, err
+	}
+
+	isConsented := r.PostFormValue(consentFormParam)
+	if isConsented == "" {
+		page := authnPage{
+			CallbackID:     session.CallbackID,
+			UserCPF:        e.UserCPF,
+			BusinessCNPJ:   e.BusinessCNPJ,
+			Enrollment:     true,
+			EnrollmentName: e.Name,
+		}
+		return a.executeTemplate(w, "consent.html", page)
+	}
+
+	if isConsented != "true" {
+		a.rejectConsent(r, session)
+		return goidc.StatusFailure, errors.New("consent not granted")
+	}
+
+	if err := a.enrollmentService.Authorize(r.Context(), e); err != nil {
+		return goidc.StatusFailure, err
+	}
+	return goidc.StatusSuccess, nil
+}
+
+// rejectConsent rejects the consent being authorized in the session on behalf
+// of the user.
+func (a authenticator) rejectConsent(r *http.Request, session *goidc.AuthnSession) {
+	consentID := session.StoredParameter(paramConsentID).(string)
+	if isEnrollmentFlow(session) {
+		_ = a.enrollmentService.Reject(r.Context(), consentID, enrollment.RejectionReasonManuallyRejected)
+		return
+	}
+
+	if isPaymentFlow(session) {
+		_ = a.paymentService.Reject(r.Context(), consentID, payment.RejectionReason{
+			Code:   payment.RejectionReasonCodeRejectedByUser,
+			Detail: "the user rejected the payment consent",
+		})
+		return
+	}
+
+	if isRecurringPaymentFlow(session) {
+		_ = a.autoPaymentService.Reject(r.Context(), consentID, payment.RejectionReason{
+			Code:   payment.RejectionReasonCodeRejectedByUser,
+			Detail: "the user rejected the recurring consent",
+		})
+		return
+	}
+
+	_ = a.consentService.Reject(r.Context(), consentID, consent.RejectionInfo{
+		RejectedBy: consent.RejectedByUser,
+		Reason:     consent.RejectionReasonCustomerManuallyRejected,
+	})
+}
+
 func (a authenticator) finishFlow(session *goidc.AuthnSession) (goidc.AuthnStatus, error) {
 	session.SetUserID(session.StoredParameter(paramUserID).(string))
 	session.GrantScopes(session.Scopes)
@@ -295,3 +649,21 @@ func (a authenticator) executeTemplate(
 	})
 	return goidc.StatusInProgress, nil
 }
+
+// isPaymentFlow returns true if the session is authorizing a payment consent
+// instead of a data sharing one.
+func isPaymentFlow(session *goidc.AuthnSession) bool {
+	return slices.Contains(strings.Split(session.Scopes, " "), payment.Scope.ID)
+}
+
+// isRecurringPaymentFlow returns true if the session is authorizing a
+// recurring consent.
+func isRecurringPaymentFlow(session *goidc.AuthnSession) bool {
+	return slices.Contains(strings.Split(session.Scopes, " "), autopayment.Scope.ID)
+}
+
+// isEnrollmentFlow returns true if the session is validating an enrollment
+// for payments without redirection.
+func isEnrollmentFlow(session *goidc.AuthnSession) bool {
+	return slices.Contains(strings.Split(session.Scopes, " "), enrollment.Scope.ID)
+}
@@ -13,20 +13,68 @@ import (
 	"strings"
 
 	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/autopayment"
 	"github.com/luikyv/go-open-finance/internal/consent"
+	"github.com/luikyv/go-open-finance/internal/enrollment"
+	"github.com/luikyv/go-open-finance/internal/payment"
+	"github.com/luikyv/go-open-finance/internal/webhook"
 )
 
 const (
 	HeaderClientCert = "X-Client-Cert"
 )
 
-func HandleGrantFunc(consentService consent.Service) goidc.HandleGrantFunc {
+func HandleGrantFunc(
+	consentService consent.Service,
+	paymentService payment.Service,
+	autoPaymentService autopayment.Service,
+	enrollmentService enrollment.Service,
+) goidc.HandleGrantFunc {
 	return func(r *http.Request, gi *goidc.GrantInfo) error {
+		if enrollmentID, ok := enrollment.ID(gi.ActiveScopes); ok {
+			e, err := enrollmentService.Enrollment(r.Context(), enrollmentID)
+			if err != nil {
+				return err
+			}
+
+			if e.Status != enrollment.StatusAwaitingEnrollment && !e.IsAuthorized() {
+				return goidc.NewError(goidc.ErrorCodeInvalidGrant, "enrollment was not validated by the account holder")
+			}
+
+			return nil
+		}
+
+		if recurringConsentID, ok := autopayment.ConsentID(gi.ActiveScopes); ok {
+			c, err := autoPaymentService.Consent(r.Context(), recurringConsentID)
+			if err != nil {
+				return err
+			}
+
+			if !c.IsAuthorized() {
+				return goidc.NewError(goidc.ErrorCodeInvalidGrant, "recurring consent is not authorized")
+			}
+
+			return nil
+		}
+
 		consentID, ok := consent.ID(gi.ActiveScopes)
 		if !ok {
 			return nil
 		}
 
+		if slices.Contains(strings.Split(gi.ActiveScopes, " "), payment.Scope.ID) {
+			c, err := paymentService.Consent(r.Context(), consentID)
+			if err != nil {
+				return err
+			}
+
+			if !c.IsAuthorized() {
+				return goidc.NewError(goidc.ErrorCodeInvalidGrant, "payment consent is not authorized")
+			}
+
+			return nil
+		}
+
 		consent, err := consentService.Consent(r.Context(), consentID)
 		if err != nil {
 			return err
@@ -88,6 +136,12 @@ func DCRFunc(scopes []goidc.Scope) goidc.HandleDynamicClientFunc {
 	scopeIDsStr := strings.Join(scopeIDs, " ")
 	return func(r *http.Request, _ string, c *goidc.ClientMeta) error {
 		c.ScopeIDs = scopeIDsStr
+
+		// The webhook URIs must be allowed by the software statement.
+		if err := webhook.ValidateURIs(c.CustomAttributes); err != nil {
+			return goidc.NewError("invalid_webhook_uris", err.Error())
+		}
+
 		return nil
 	}
 }
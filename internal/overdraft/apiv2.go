@@ -0,0 +1,16 @@
+package overdraft
+
+import (
+	"github.com/luikyv/go-oidc/pkg/provider"
+	"github.com/luikyv/go-open-finance/internal/consent"
+	"github.com/luikyv/go-open-finance/internal/credit"
+)
+
+func NewAPIRouterV2(host string, service Service, consentService consent.Service, op *provider.Provider) credit.APIRouterV2 {
+	return credit.NewAPIRouterV2(host, "/open-banking/unarranged-accounts-overdraft/v2", credit.PermissionsV2{
+		Read:                     consent.PermissionUnarrangedAccountsOverdraftRead,
+		WarrantiesRead:           consent.PermissionUnarrangedAccountsOverdraftWarrantiesRead,
+		ScheduledInstalmentsRead: consent.PermissionUnarrangedAccountsOverdraftScheduledInstalmentsRead,
+		PaymentsRead:             consent.PermissionUnarrangedAccountsOverdraftPaymentsRead,
+	}, service, consentService, op)
+}
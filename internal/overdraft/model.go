@@ -0,0 +1,32 @@
+package overdraft
+
+import (
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/credit"
+)
+
+var (
+	Scope = goidc.NewScope("unarranged-accounts-overdraft")
+)
+
+// Contract is the unarranged overdraft of an account, i.e. the credit granted
+// when the account is debited beyond its balance and contracted overdraft
+// limit.
+// The unarranged overdraft has no instalments and the amount of its terms is
+// not stored, it is derived from the unarranged overdraft amount of the
+// account when the contract is read.
+type Contract struct {
+	credit.Contract
+	UserID string
+	// AccountNumber identifies the account whose unarranged overdraft is
+	// described by the contract.
+	AccountNumber string
+}
+
+const (
+	ProductTypeDepositorAdvance credit.ProductType = "ADIANTAMENTO_A_DEPOSITANTES"
+)
+
+const (
+	ProductSubTypeDepositorAdvance credit.ProductSubType = "ADIANTAMENTO_A_DEPOSITANTES"
+)
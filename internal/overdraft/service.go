@@ -0,0 +1,88 @@
+package overdraft
+
+import (
+	"context"
+	"slices"
+
+	"github.com/luikyv/go-open-finance/internal/account"
+	"github.com/luikyv/go-open-finance/internal/consent"
+	"github.com/luikyv/go-open-finance/internal/credit"
+	"github.com/luikyv/go-open-finance/internal/money"
+	"github.com/luikyv/go-open-finance/internal/page"
+)
+
+type Service struct {
+	storage        *Storage
+	consentService consent.Service
+	accountService account.Service
+}
+
+func NewService(storage *Storage, consentService consent.Service, accountService account.Service) Service {
+	return Service{
+		storage:        storage,
+		consentService: consentService,
+		accountService: accountService,
+	}
+}
+
+func (s Service) Add(userID string, contract Contract) {
+	contract.UserID = userID
+	s.storage.save(contract)
+}
+
+func (s Service) Contracts(ctx context.Context, consentID string, pag page.Pagination) (page.Page[credit.Contract], error) {
+	c, err := s.consentService.Consent(ctx, consentID)
+	if err != nil {
+		return page.Page[credit.Contract]{}, err
+	}
+
+	var contracts []credit.Contract
+	for _, id := range c.UnarrangedOverdraftIDs {
+		contract, err := s.withAccount(s.storage.contract(id))
+		if err != nil {
+			return page.Page[credit.Contract]{}, err
+		}
+		contracts = append(contracts, contract.Contract)
+	}
+
+	return page.Paginate(contracts, pag), nil
+}
+
+func (s Service) Contract(ctx context.Context, id, consentID string) (credit.Contract, error) {
+	c, err := s.consentService.Consent(ctx, consentID)
+	if err != nil {
+		return credit.Contract{}, err
+	}
+
+	if !slices.Contains(c.UnarrangedOverdraftIDs, id) {
+		return credit.Contract{}, credit.ErrContractNotAllowed
+	}
+
+	contract, err := s.withAccount(s.storage.contract(id))
+	return contract.Contract, err
+}
+
+// withAccount fills the amount of the contract from the account it belongs
+// to, so the contract is always consistent with the overdraft limits of the
+// account.
+// The unarranged overdraft amount of the account is what remains to be paid,
+// then the contract amount is that plus what the payments already amortized.
+func (s Service) withAccount(contract Contract) (Contract, error) {
+	acc, err := s.accountService.UserAccount(contract.UserID, contract.AccountNumber)
+	if err != nil {
+		return Contract{}, err
+	}
+
+	outstanding, err := money.Parse(acc.OverdraftLimit.Unarranged)
+	if err != nil {
+		return Contract{}, err
+	}
+
+	amount := outstanding
+	for _, p := range contract.Payments {
+		amount += p.Amortization()
+	}
+
+	contract.Terms.Amount = money.Format(amount)
+	return contract, nil
+}
@@ -0,0 +1,798 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/luikyv/go-oidc/pkg/provider"
+	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/api/middleware"
+	"github.com/luikyv/go-open-finance/internal/consent"
+	"github.com/luikyv/go-open-finance/internal/idempotency"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+var (
+	errBadRequest = api.NewError("PARAMETRO_INVALIDO", http.StatusBadRequest, "invalid request")
+)
+
+type APIRouterV4 struct {
+	host               string
+	service            Service
+	op                 *provider.Provider
+	signer             api.JWTSigner
+	httpClient         *http.Client
+	idempotencyStorage idempotency.Storage
+}
+
+func NewAPIRouterV4(
+	host string,
+	service Service,
+	op *provider.Provider,
+	signer api.JWTSigner,
+	httpClient *http.Client,
+	idempotencyStorage idempotency.Storage,
+) APIRouterV4 {
+	return APIRouterV4{
+		host:               host,
+		service:            service,
+		op:                 op,
+		signer:             signer,
+		httpClient:         httpClient,
+		idempotencyStorage: idempotencyStorage,
+	}
+}
+
+func (router APIRouterV4) Register(mux *http.ServeMux) {
+	paymentMux := http.NewServeMux()
+
+	handler := router.createConsentHandler()
+	handler = idempotency.Middleware(handler, router.idempotencyStorage)
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, Scope)
+	paymentMux.Handle("POST /open-banking/payments/v4/consents", handler)
+
+	handler = router.getConsentHandler()
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, Scope)
+	paymentMux.Handle("GET /open-banking/payments/v4/consents/{id}", handler)
+
+	handler = router.createPaymentHandler()
+	handler = idempotency.Middleware(handler, router.idempotencyStorage)
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	// The consent scope is not required since consents authorized through an
+	// enrollment are used with client credentials tokens.
+	handler = middleware.AuthScopes(handler, router.op, Scope)
+	paymentMux.Handle("POST /open-banking/payments/v4/pix/payments", handler)
+
+	handler = router.getPaymentHandler()
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, Scope)
+	paymentMux.Handle("GET /open-banking/payments/v4/pix/payments/{id}", handler)
+
+	handler = router.cancelPaymentHandler()
+	handler = middleware.JWS(handler, router.op, router.signer, router.httpClient)
+	handler = middleware.AuthScopes(handler, router.op, Scope)
+	paymentMux.Handle("PATCH /open-banking/payments/v4/pix/payments/{id}", handler)
+
+	handler = paymentMux
+	handler = middleware.FAPIID(handler)
+	handler = middleware.Meta(handler, router.host)
+	mux.Handle("/open-banking/payments/v4/", handler)
+}
+
+func (router APIRouterV4) createConsentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req createConsentRequestV4
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.WriteError(w, errBadRequest)
+			return
+		}
+
+		if err := req.validate(); err != nil {
+			writeErrorV4(w, err)
+			return
+		}
+
+		c := req.toConsent(r.Context())
+		if err := router.service.createConsent(r.Context(), c); err != nil {
+			writeErrorV4(w, err)
+			return
+		}
+
+		resp := toConsentResponseV4(c, router.host)
+		api.WriteJSON(w, resp, http.StatusCreated)
+	})
+}
+
+func (router APIRouterV4) getConsentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		c, err := router.service.Consent(r.Context(), id)
+		if err != nil {
+			writeErrorV4(w, err)
+			return
+		}
+
+		resp := toConsentResponseV4(c, router.host)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV4) createPaymentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req createPaymentRequestV4
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.WriteError(w, errBadRequest)
+			return
+		}
+
+		// Consents authorized without redirect (Jornada Sem Redirecionamento)
+		// never have a token issued for them, so the consent is the one
+		// informed in the payload.
+		consentID, ok := consent.ID(r.Context().Value(api.CtxKeyScopes).(string))
+		if !ok && len(req.Data) != 0 {
+			consentID = req.Data[0].ConsentID
+		}
+		if err := req.validate(consentID); err != nil {
+			writeErrorV4(w, err)
+			return
+		}
+
+		ps, err := router.service.create(r.Context(), consentID, !ok, req.toPayments(r.Context()))
+		if err != nil {
+			writeErrorV4(w, err)
+			return
+		}
+
+		resp := toPaymentsResponseV4(ps, router.host)
+		api.WriteJSON(w, resp, http.StatusCreated)
+	})
+}
+
+func (router APIRouterV4) getPaymentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		p, err := router.service.Payment(r.Context(), id)
+		if err != nil {
+			writeErrorV4(w, err)
+			return
+		}
+
+		resp := toPaymentResponseV4(p, router.host)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+func (router APIRouterV4) cancelPaymentHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req cancelPaymentRequestV4
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.WriteError(w, errBadRequest)
+			return
+		}
+
+		if err := req.validate(); err != nil {
+			writeErrorV4(w, err)
+			return
+		}
+
+		id := r.PathValue("id")
+		p, err := router.service.cancel(r.Context(), id, req.Data.Cancellation.CancelledBy.Document.Identification)
+		if err != nil {
+			writeErrorV4(w, err)
+			return
+		}
+
+		resp := toPaymentResponseV4(p, router.host)
+		api.WriteJSON(w, resp, http.StatusOK)
+	})
+}
+
+type createConsentRequestV4 struct {
+	Data struct {
+		LoggedUser     entityV4   `json:"loggedUser"`
+		BusinessEntity *entityV4  `json:"businessEntity,omitempty"`
+		Creditor       creditorV4 `json:"creditor"`
+		Payment        paymentV4  `json:"payment"`
+		DebtorAccount  *accountV4 `json:"debtorAccount,omitempty"`
+	} `json:"data"`
+}
+
+type entityV4 struct {
+	Document documentV4 `json:"document"`
+}
+
+type documentV4 struct {
+	Identification string `json:"identification"`
+	Relation       string `json:"rel"`
+}
+
+type creditorV4 struct {
+	PersonType PersonType `json:"personType"`
+	CPFCNPJ    string     `json:"cpfCnpj"`
+	Name       string     `json:"name"`
+}
+
+type paymentV4 struct {
+	Type         Type        `json:"type"`
+	Date         *timex.Date `json:"date,omitempty"`
+	Schedule     *scheduleV4 `json:"schedule,omitempty"`
+	Currency     string      `json:"currency"`
+	Amount       string      `json:"amount"`
+	IBGETownCode string      `json:"ibgeTownCode,omitempty"`
+	Details      detailsV4   `json:"details"`
+}
+
+type scheduleV4 struct {
+	Single *struct {
+		Date timex.Date `json:"date"`
+	} `json:"single,omitempty"`
+	Daily *struct {
+		StartDate timex.Date `json:"startDate"`
+		Quantity  int        `json:"quantity"`
+	} `json:"daily,omitempty"`
+	Weekly *struct {
+		DayOfWeek DayOfWeek  `json:"dayOfWeek"`
+		StartDate timex.Date `json:"startDate"`
+		Quantity  int        `json:"quantity"`
+	} `json:"weekly,omitempty"`
+	Monthly *struct {
+		DayOfMonth int        `json:"dayOfMonth"`
+		StartDate  timex.Date `json:"startDate"`
+		Quantity   int        `json:"quantity"`
+	} `json:"monthly,omitempty"`
+	Custom *struct {
+		Dates                 []timex.Date `json:"dates"`
+		AdditionalInformation string       `json:"additionalInformation,omitempty"`
+	} `json:"custom,omitempty"`
+}
+
+func (s scheduleV4) toSchedule() Schedule {
+	var schedule Schedule
+	if s.Single != nil {
+		schedule.Single = &SingleSchedule{
+			Date: s.Single.Date,
+		}
+	}
+	if s.Daily != nil {
+		schedule.Daily = &DailySchedule{
+			StartDate: s.Daily.StartDate,
+			Quantity:  s.Daily.Quantity,
+		}
+	}
+	if s.Weekly != nil {
+		schedule.Weekly = &WeeklySchedule{
+			DayOfWeek: s.Weekly.DayOfWeek,
+			StartDate: s.Weekly.StartDate,
+			Quantity:  s.Weekly.Quantity,
+		}
+	}
+	if s.Monthly != nil {
+		schedule.Monthly = &MonthlySchedule{
+			DayOfMonth: s.Monthly.DayOfMonth,
+			StartDate:  s.Monthly.StartDate,
+			Quantity:   s.Monthly.Quantity,
+		}
+	}
+	if s.Custom != nil {
+		schedule.Custom = &CustomSchedule{
+			Dates:                 s.Custom.Dates,
+			AdditionalInformation: s.Custom.AdditionalInformation,
+		}
+	}
+	return schedule
+}
+
+func toScheduleV4(schedule Schedule) *scheduleV4 {
+	var s scheduleV4
+	if schedule.Single != nil {
+		s.Single = &struct {
+			Date timex.Date `json:"date"`
+		}{
+			Date: schedule.Single.Date,
+		}
+	}
+	if schedule.Daily != nil {
+		s.Daily = &struct {
+			StartDate timex.Date `json:"startDate"`
+			Quantity  int        `json:"quantity"`
+		}{
+			StartDate: schedule.Daily.StartDate,
+			Quantity:  schedule.Daily.Quantity,
+		}
+	}
+	if schedule.Weekly != nil {
+		s.Weekly = &struct {
+			DayOfWeek DayOfWeek  `json:"dayOfWeek"`
+			StartDate timex.Date `json:"startDate"`
+			Quantity  int        `json:"quantity"`
+		}{
+			DayOfWeek: schedule.Weekly.DayOfWeek,
+			StartDate: schedule.Weekly.StartDate,
+			Quantity:  schedule.Weekly.Quantity,
+		}
+	}
+	if schedule.Monthly != nil {
+		s.Monthly = &struct {
+			DayOfMonth int        `json:"dayOfMonth"`
+			StartDate  timex.Date `json:"startDate"`
+			Quantity   int        `json:"quantity"`
+		}{
+			DayOfMonth: schedule.Monthly.DayOfMonth,
+			StartDate:  schedule.Monthly.StartDate,
+			Quantity:   schedule.Monthly.Quantity,
+		}
+	}
+	if schedule.Custom != nil {
+		s.Custom = &struct {
+			Dates                 []timex.Date `json:"dates"`
+			AdditionalInformation string       `json:"additionalInformation,omitempty"`
+		}{
+			Dates:                 schedule.Custom.Dates,
+			AdditionalInformation: schedule.Custom.AdditionalInformation,
+		}
+	}
+	return &s
+}
+
+type detailsV4 struct {
+	LocalInstrument LocalInstrument `json:"localInstrument"`
+	QRCode          string          `json:"qrCode,omitempty"`
+	Proxy           string          `json:"proxy,omitempty"`
+	CreditorAccount accountV4       `json:"creditorAccount"`
+}
+
+type accountV4 struct {
+	ISPB   string      `json:"ispb"`
+	Issuer string      `json:"issuer,omitempty"`
+	Number string      `json:"number"`
+	Type   AccountType `json:"accountType"`
+}
+
+func (a accountV4) toAccount() Account {
+	return Account(a)
+}
+
+func toAccountV4(a Account) accountV4 {
+	return accountV4(a)
+}
+
+func (req createConsentRequestV4) validate() error {
+	if req.Data.LoggedUser.Document.Identification == "" ||
+		req.Data.LoggedUser.Document.Relation != defaultUserDocumentRelation {
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid logged user")
+	}
+
+	if req.Data.BusinessEntity != nil && req.Data.BusinessEntity.Document.Relation != defaultBusinessDocumentRelation {
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid business entity")
+	}
+
+	creditor := req.Data.Creditor
+	if creditor.CPFCNPJ == "" || creditor.Name == "" {
+		return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "creditor information is missing")
+	}
+
+	if creditor.PersonType != PersonTypeNatural && creditor.PersonType != PersonTypeLegal {
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "invalid creditor person type")
+	}
+
+	if schedule := req.Data.Payment.Schedule; schedule != nil &&
+		schedule.Single == nil && schedule.Daily == nil && schedule.Weekly == nil &&
+		schedule.Monthly == nil && schedule.Custom == nil {
+		return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "schedule information is missing")
+	}
+
+	return nil
+}
+
+func (req createConsentRequestV4) toConsent(ctx context.Context) Consent {
+	now := timex.DateTimeNow()
+	c := Consent{
+		ID:                   consentID(),
+		Status:               ConsentStatusAwaitingAuthorization,
+		UserCPF:              req.Data.LoggedUser.Document.Identification,
+		Creditor:             Creditor(req.Data.Creditor),
+		ClientID:             ctx.Value(api.CtxKeyClientID).(string),
+		CreationDateTime:     now,
+		StatusUpdateDateTime: now,
+		Payment: Info{
+			Type:         req.Data.Payment.Type,
+			Date:         req.Data.Payment.Date,
+			Currency:     req.Data.Payment.Currency,
+			Amount:       req.Data.Payment.Amount,
+			IBGETownCode: req.Data.Payment.IBGETownCode,
+			Details: Details{
+				LocalInstrument: req.Data.Payment.Details.LocalInstrument,
+				QRCode:          req.Data.Payment.Details.QRCode,
+				Proxy:           req.Data.Payment.Details.Proxy,
+				CreditorAccount: req.Data.Payment.Details.CreditorAccount.toAccount(),
+			},
+		},
+	}
+
+	if req.Data.BusinessEntity != nil {
+		c.BusinessCNPJ = req.Data.BusinessEntity.Document.Identification
+	}
+
+	if req.Data.Payment.Schedule != nil {
+		schedule := req.Data.Payment.Schedule.toSchedule()
+		c.Payment.Schedule = &schedule
+	}
+
+	if req.Data.DebtorAccount != nil {
+		acc := req.Data.DebtorAccount.toAccount()
+		c.DebtorAccount = &acc
+	}
+
+	return c
+}
+
+type consentResponseV4 struct {
+	Data struct {
+		ID                   string             `json:"consentId"`
+		CreationDateTime     timex.DateTime     `json:"creationDateTime"`
+		ExpirationDateTime   timex.DateTime     `json:"expirationDateTime"`
+		StatusUpdateDateTime timex.DateTime     `json:"statusUpdateDateTime"`
+		Status               ConsentStatus      `json:"status"`
+		LoggedUser           entityV4           `json:"loggedUser"`
+		BusinessEntity       *entityV4          `json:"businessEntity,omitempty"`
+		Creditor             creditorV4         `json:"creditor"`
+		Payment              paymentV4          `json:"payment"`
+		DebtorAccount        *accountV4         `json:"debtorAccount,omitempty"`
+		RejectionReason      *rejectionReasonV4 `json:"rejectionReason,omitempty"`
+	} `json:"data"`
+	Links api.Links `json:"links"`
+	Meta  api.Meta  `json:"meta"`
+}
+
+type rejectionReasonV4 struct {
+	Code   RejectionReasonCode `json:"code"`
+	Detail string              `json:"detail"`
+}
+
+func toConsentResponseV4(c Consent, host string) consentResponseV4 {
+	resp := consentResponseV4{
+		Links: api.NewLinks(host + "/open-banking/payments/v4/consents/" + c.ID),
+		Meta:  api.NewMeta(),
+	}
+	resp.Data.ID = c.ID
+	resp.Data.CreationDateTime = c.CreationDateTime
+	resp.Data.ExpirationDateTime = c.ExpirationDateTime()
+	resp.Data.StatusUpdateDateTime = c.StatusUpdateDateTime
+	resp.Data.Status = c.Status
+	resp.Data.LoggedUser = entityV4{
+		Document: documentV4{
+			Identification: c.UserCPF,
+			Relation:       defaultUserDocumentRelation,
+		},
+	}
+	if c.BusinessCNPJ != "" {
+		resp.Data.BusinessEntity = &entityV4{
+			Document: documentV4{
+				Identification: c.BusinessCNPJ,
+				Relation:       defaultBusinessDocumentRelation,
+			},
+		}
+	}
+	resp.Data.Creditor = creditorV4(c.Creditor)
+	resp.Data.Payment = paymentV4{
+		Type:         c.Payment.Type,
+		Date:         c.Payment.Date,
+		Currency:     c.Payment.Currency,
+		Amount:       c.Payment.Amount,
+		IBGETownCode: c.Payment.IBGETownCode,
+		Details: detailsV4{
+			LocalInstrument: c.Payment.Details.LocalInstrument,
+			QRCode:          c.Payment.Details.QRCode,
+			Proxy:           c.Payment.Details.Proxy,
+			CreditorAccount: toAccountV4(c.Payment.Details.CreditorAccount),
+		},
+	}
+	if c.Payment.Schedule != nil {
+		resp.Data.Payment.Schedule = toScheduleV4(*c.Payment.Schedule)
+	}
+	if c.DebtorAccount != nil {
+		acc := toAccountV4(*c.DebtorAccount)
+		resp.Data.DebtorAccount = &acc
+	}
+	if c.RejectionReason != nil {
+		resp.Data.RejectionReason = &rejectionReasonV4{
+			Code:   c.RejectionReason.Code,
+			Detail: c.RejectionReason.Detail,
+		}
+	}
+
+	return resp
+}
+
+type createPaymentRequestV4 struct {
+	Data []paymentRequestV4 `json:"data"`
+}
+
+type paymentRequestV4 struct {
+	EndToEndID                string          `json:"endToEndId"`
+	LocalInstrument           LocalInstrument `json:"localInstrument"`
+	Payment                   amountV4        `json:"payment"`
+	CreditorAccount           accountV4       `json:"creditorAccount"`
+	RemittanceInformation     string          `json:"remittanceInformation,omitempty"`
+	QRCode                    string          `json:"qrCode,omitempty"`
+	Proxy                     string          `json:"proxy,omitempty"`
+	CNPJInitiator             string          `json:"cnpjInitiator"`
+	TransactionIdentification string          `json:"transactionIdentification,omitempty"`
+	IBGETownCode              string          `json:"ibgeTownCode,omitempty"`
+	AuthorisationFlow         string          `json:"authorisationFlow,omitempty"`
+	ConsentID                 string          `json:"consentId"`
+}
+
+type amountV4 struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func (req createPaymentRequestV4) validate(consentID string) error {
+	if len(req.Data) == 0 {
+		return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "no payment was informed")
+	}
+
+	for _, p := range req.Data {
+		if p.ConsentID != consentID {
+			return api.NewError("CONSENTIMENTO_INVALIDO", http.StatusUnprocessableEntity, "the consent id does not match the token")
+		}
+
+		if p.CNPJInitiator == "" {
+			return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "cnpjInitiator is required")
+		}
+	}
+
+	return nil
+}
+
+func (req createPaymentRequestV4) toPayments(ctx context.Context) []Payment {
+	var ps []Payment
+	for _, p := range req.Data {
+		ps = append(ps, p.toPayment(ctx))
+	}
+	return ps
+}
+
+func (req paymentRequestV4) toPayment(ctx context.Context) Payment {
+	now := timex.DateTimeNow()
+	p := Payment{
+		ID:                        paymentID(),
+		EndToEndID:                req.EndToEndID,
+		ConsentID:                 req.ConsentID,
+		Status:                    StatusRCVD,
+		Amount:                    req.Payment.Amount,
+		Currency:                  req.Payment.Currency,
+		LocalInstrument:           req.LocalInstrument,
+		CreditorAccount:           req.CreditorAccount.toAccount(),
+		Proxy:                     req.Proxy,
+		QRCode:                    req.QRCode,
+		IBGETownCode:              req.IBGETownCode,
+		CNPJInitiator:             req.CNPJInitiator,
+		TransactionIdentification: req.TransactionIdentification,
+		RemittanceInformation:     req.RemittanceInformation,
+		AuthorisationFlow:         req.AuthorisationFlow,
+		ClientID:                  ctx.Value(api.CtxKeyClientID).(string),
+		CreationDateTime:          now,
+		StatusUpdateDateTime:      now,
+	}
+
+	return p
+}
+
+type paymentsResponseV4 struct {
+	Data  []paymentResponseDataV4 `json:"data"`
+	Links api.Links               `json:"links"`
+	Meta  api.Meta                `json:"meta"`
+}
+
+type paymentResponseV4 struct {
+	Data  paymentResponseDataV4 `json:"data"`
+	Links api.Links             `json:"links"`
+	Meta  api.Meta              `json:"meta"`
+}
+
+type paymentResponseDataV4 struct {
+	ID                        string             `json:"paymentId"`
+	EndToEndID                string             `json:"endToEndId"`
+	ConsentID                 string             `json:"consentId"`
+	CreationDateTime          timex.DateTime     `json:"creationDateTime"`
+	StatusUpdateDateTime      timex.DateTime     `json:"statusUpdateDateTime"`
+	Proxy                     string             `json:"proxy,omitempty"`
+	IBGETownCode              string             `json:"ibgeTownCode,omitempty"`
+	Status                    Status             `json:"status"`
+	RejectionReason           *rejectionReasonV4 `json:"rejectionReason,omitempty"`
+	LocalInstrument           LocalInstrument    `json:"localInstrument"`
+	CNPJInitiator             string             `json:"cnpjInitiator"`
+	Payment                   amountV4           `json:"payment"`
+	TransactionIdentification string             `json:"transactionIdentification,omitempty"`
+	RemittanceInformation     string             `json:"remittanceInformation,omitempty"`
+	CreditorAccount           accountV4          `json:"creditorAccount"`
+	DebtorAccount             *accountV4         `json:"debtorAccount,omitempty"`
+	Cancellation              *cancellationV4    `json:"cancellation,omitempty"`
+	AuthorisationFlow         string             `json:"authorisationFlow,omitempty"`
+}
+
+type cancellationV4 struct {
+	Reason        CancellationReason `json:"reason"`
+	CancelledFrom CancelledFrom      `json:"cancelledFrom"`
+	CancelledAt   timex.DateTime     `json:"cancelledAt"`
+	CancelledBy   entityV4           `json:"cancelledBy"`
+}
+
+func toPaymentsResponseV4(ps []Payment, host string) paymentsResponseV4 {
+	resp := paymentsResponseV4{
+		Data:  []paymentResponseDataV4{},
+		Links: api.NewLinks(host + "/open-banking/payments/v4/pix/payments"),
+		Meta:  api.NewMeta(),
+	}
+	for _, p := range ps {
+		resp.Data = append(resp.Data, toPaymentResponseDataV4(p))
+	}
+
+	return resp
+}
+
+func toPaymentResponseV4(p Payment, host string) paymentResponseV4 {
+	return paymentResponseV4{
+		Data:  toPaymentResponseDataV4(p),
+		Links: api.NewLinks(host + "/open-banking/payments/v4/pix/payments/" + p.ID),
+		Meta:  api.NewMeta(),
+	}
+}
+
+func toPaymentResponseDataV4(p Payment) paymentResponseDataV4 {
+	data := paymentResponseDataV4{
+		ID:                   p.ID,
+		EndToEndID:           p.EndToEndID,
+		ConsentID:            p.ConsentID,
+		CreationDateTime:     p.CreationDateTime,
+		StatusUpdateDateTime: p.StatusUpdateDateTime,
+		Proxy:                p.Proxy,
+		IBGETownCode:         p.IBGETownCode,
+		Status:               p.Status,
+		LocalInstrument:      p.LocalInstrument,
+		CNPJInitiator:        p.CNPJInitiator,
+		Payment: amountV4{
+			Amount:   p.Amount,
+			Currency: p.Currency,
+		},
+		TransactionIdentification: p.TransactionIdentification,
+		RemittanceInformation:     p.RemittanceInformation,
+		CreditorAccount:           toAccountV4(p.CreditorAccount),
+		AuthorisationFlow:         p.AuthorisationFlow,
+	}
+
+	if p.DebtorAccount != nil {
+		acc := toAccountV4(*p.DebtorAccount)
+		data.DebtorAccount = &acc
+	}
+
+	if p.RejectionReason != nil {
+		data.RejectionReason = &rejectionReasonV4{
+			Code:   p.RejectionReason.Code,
+			Detail: p.RejectionReason.Detail,
+		}
+	}
+
+	if p.Cancellation != nil {
+		rel := defaultUserDocumentRelation
+		if len(p.Cancellation.ByDocument) > 11 {
+			rel = defaultBusinessDocumentRelation
+		}
+		data.Cancellation = &cancellationV4{
+			Reason:        p.Cancellation.Reason,
+			CancelledFrom: p.Cancellation.From,
+			CancelledAt:   p.Cancellation.At,
+			CancelledBy: entityV4{
+				Document: documentV4{
+					Identification: p.Cancellation.ByDocument,
+					Relation:       rel,
+				},
+			},
+		}
+	}
+
+	return data
+}
+
+type cancelPaymentRequestV4 struct {
+	Data struct {
+		Status       Status `json:"status"`
+		Cancellation struct {
+			CancelledBy entityV4 `json:"cancelledBy"`
+		} `json:"cancellation"`
+	} `json:"data"`
+}
+
+func (req cancelPaymentRequestV4) validate() error {
+	if req.Data.Status != StatusCANC {
+		return api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, "the status must be CANC")
+	}
+
+	if req.Data.Cancellation.CancelledBy.Document.Identification == "" {
+		return api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, "cancelledBy is required")
+	}
+
+	return nil
+}
+
+func writeErrorV4(w http.ResponseWriter, err error) {
+	if errors.Is(err, errAccessNotAllowed) {
+		api.WriteError(w, api.NewError("FORBIDDEN", http.StatusForbidden, errAccessNotAllowed.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidPaymentMethod) {
+		api.WriteError(w, api.NewError("FORMA_PAGAMENTO_INVALIDA", http.StatusUnprocessableEntity, errInvalidPaymentMethod.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidDate) {
+		api.WriteError(w, api.NewError("DATA_PAGAMENTO_INVALIDA", http.StatusUnprocessableEntity, errInvalidDate.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidPaymentDetail) {
+		api.WriteError(w, api.NewError("DETALHE_PAGAMENTO_INVALIDO", http.StatusUnprocessableEntity, errInvalidPaymentDetail.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidQRCode) {
+		api.WriteError(w, api.NewError("QRCODE_INVALIDO", http.StatusUnprocessableEntity, errInvalidQRCode.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidAmount) || errors.Is(err, errInvalidCurrency) || errors.Is(err, errDateAndSchedule) {
+		api.WriteError(w, api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, err.Error()))
+		return
+	}
+
+	if errors.Is(err, errConsentNotAuthorized) {
+		api.WriteError(w, api.NewError("CONSENTIMENTO_INVALIDO", http.StatusUnprocessableEntity, errConsentNotAuthorized.Error()))
+		return
+	}
+
+	if errors.Is(err, errConsentTokenRequired) {
+		api.WriteError(w, api.NewError("UNAUTHORISED", http.StatusUnauthorized, errConsentTokenRequired.Error()))
+		return
+	}
+
+	if errors.Is(err, errDivergentPayment) {
+		api.WriteError(w, api.NewError("PAGAMENTO_DIVERGENTE_CONSENTIMENTO", http.StatusUnprocessableEntity, errDivergentPayment.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidEndToEndID) {
+		api.WriteError(w, api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, errInvalidEndToEndID.Error()))
+		return
+	}
+
+	if errors.Is(err, errCancellationNotAllowed) {
+		api.WriteError(w, api.NewError("PAGAMENTO_NAO_PERMITE_CANCELAMENTO", http.StatusUnprocessableEntity, errCancellationNotAllowed.Error()))
+		return
+	}
+
+	if errors.Is(err, errInvalidSchedule) {
+		api.WriteError(w, api.NewError("PARAMETRO_INVALIDO", http.StatusUnprocessableEntity, errInvalidSchedule.Error()))
+		return
+	}
+
+	if errors.Is(err, errMissingDateAndSchedule) {
+		api.WriteError(w, api.NewError("PARAMETRO_NAO_INFORMADO", http.StatusUnprocessableEntity, errMissingDateAndSchedule.Error()))
+		return
+	}
+
+	if errors.Is(err, errNotFound) {
+		api.WriteError(w, api.NewError("RECURSO_NAO_ENCONTRADO", http.StatusNotFound, errNotFound.Error()))
+		return
+	}
+
+	// Errors not listed above are either api errors or unexpected failures,
+	// which are answered with 500.
+	api.WriteError(w, err)
+}
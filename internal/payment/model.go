@@ -0,0 +1,516 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/account"
+	"github.com/luikyv/go-open-finance/internal/mock"
+	"github.com/luikyv/go-open-finance/internal/risk"
+	"github.com/luikyv/go-open-finance/internal/spi"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+const (
+	maxTimeAwaitingAuthorizationSecs = 300
+	maxTimeAwaitingConsumptionSecs   = 3600
+	defaultUserDocumentRelation      = "CPF"
+	defaultBusinessDocumentRelation  = "CNPJ"
+	DefaultCurrency                  = "BRL"
+	// maxScheduleDays is how far in the future a payment can be scheduled.
+	maxScheduleDays            = 730
+	minScheduleQuantity        = 2
+	maxDailyScheduleQuantity   = 60
+	maxWeeklyScheduleQuantity  = 60
+	maxMonthlyScheduleQuantity = 24
+	maxCustomScheduleQuantity  = 60
+)
+
+var (
+	Scope = goidc.NewScope("payments")
+)
+
+type Consent struct {
+	ID              string           `bson:"_id"`
+	Status          ConsentStatus    `bson:"status"`
+	UserCPF         string           `bson:"user_cpf"`
+	BusinessCNPJ    string           `bson:"business_cnpj,omitempty"`
+	Creditor        Creditor         `bson:"creditor"`
+	Payment         Info             `bson:"payment"`
+	DebtorAccount   *Account         `bson:"debtor_account,omitempty"`
+	RejectionReason *RejectionReason `bson:"rejection,omitempty"`
+	// EnrollmentID is the enrollment through which the consent was authorized
+	// without redirecting the user, if it was.
+	EnrollmentID string `bson:"enrollment_id,omitempty"`
+
+	ClientID             string         `bson:"client_id"`
+	CreationDateTime     timex.DateTime `bson:"created_at"`
+	StatusUpdateDateTime timex.DateTime `bson:"status_updated_at"`
+}
+
+// ExpirationDateTime is the date time until which the consent can be
+// authorized by the user.
+func (c Consent) ExpirationDateTime() timex.DateTime {
+	return timex.NewDateTime(c.CreationDateTime.Add(timex.Second * maxTimeAwaitingAuthorizationSecs))
+}
+
+// HasAuthExpired returns true if the status is [ConsentStatusAwaitingAuthorization]
+// and the max time awaiting authorization has elapsed.
+func (c Consent) HasAuthExpired() bool {
+	return c.IsAwaitingAuthorization() && timex.Now().After(c.ExpirationDateTime().Time)
+}
+
+// HasConsumptionExpired returns true if the status is [ConsentStatusAuthorized]
+// and the consent was not used within the max time awaiting consumption.
+func (c Consent) HasConsumptionExpired() bool {
+	return c.IsAuthorized() &&
+		timex.Now().After(c.StatusUpdateDateTime.Add(timex.Second*maxTimeAwaitingConsumptionSecs))
+}
+
+func (c Consent) IsAuthorized() bool {
+	return c.Status == ConsentStatusAuthorized
+}
+
+func (c Consent) IsAwaitingAuthorization() bool {
+	return c.Status == ConsentStatusAwaitingAuthorization
+}
+
+type ConsentStatus string
+
+const (
+	ConsentStatusAwaitingAuthorization ConsentStatus = "AWAITING_AUTHORISATION"
+	ConsentStatusAuthorized            ConsentStatus = "AUTHORISED"
+	ConsentStatusConsumed              ConsentStatus = "CONSUMED"
+	ConsentStatusRejected              ConsentStatus = "REJECTED"
+)
+
+type Creditor struct {
+	PersonType PersonType `bson:"person_type"`
+	CPFCNPJ    string     `bson:"cpf_cnpj"`
+	Name       string     `bson:"name"`
+}
+
+type PersonType string
+
+const (
+	PersonTypeNatural PersonType = "PESSOA_NATURAL"
+	PersonTypeLegal   PersonType = "PESSOA_JURIDICA"
+)
+
+// Info is the payment information the user agrees with when authorizing a
+// payment consent.
+type Info struct {
+	Type         Type        `bson:"type"`
+	Date         *timex.Date `bson:"date,omitempty"`
+	Schedule     *Schedule   `bson:"schedule,omitempty"`
+	Currency     string      `bson:"currency"`
+	Amount       string      `bson:"amount"`
+	IBGETownCode string      `bson:"ibge_town_code,omitempty"`
+	Details      Details     `bson:"details"`
+}
+
+type Type string
+
+const (
+	TypePix Type = "PIX"
+)
+
+// Schedule defines the future dates in which the payments authorized by a
+// consent will be executed. Only one of its fields is informed.
+type Schedule struct {
+	Single  *SingleSchedule  `bson:"single,omitempty"`
+	Daily   *DailySchedule   `bson:"daily,omitempty"`
+	Weekly  *WeeklySchedule  `bson:"weekly,omitempty"`
+	Monthly *MonthlySchedule `bson:"monthly,omitempty"`
+	Custom  *CustomSchedule  `bson:"custom,omitempty"`
+}
+
+// Dates returns the dates of all the payments in the schedule in ascending
+// order.
+func (s Schedule) Dates() []timex.Date {
+	var dates []timex.Date
+	switch {
+	case s.Single != nil:
+		dates = append(dates, s.Single.Date)
+	case s.Daily != nil:
+		for i := range s.Daily.Quantity {
+			dates = append(dates, timex.NewDate(s.Daily.StartDate.AddDate(0, 0, i)))
+		}
+	case s.Weekly != nil:
+		// The first payment happens on the first day of the week informed
+		// that comes on or after the start date.
+		first := s.Weekly.StartDate.Time
+		for first.Weekday() != s.Weekly.DayOfWeek.weekday() {
+			first = first.AddDate(0, 0, 1)
+		}
+		for i := range s.Weekly.Quantity {
+			dates = append(dates, timex.NewDate(first.AddDate(0, 0, 7*i)))
+		}
+	case s.Monthly != nil:
+		// The first payment happens on the first day of the month informed
+		// that comes on or after the start date.
+		start := s.Monthly.StartDate.Time
+		month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if dayOfMonth(month, s.Monthly.DayOfMonth).Before(start) {
+			month = month.AddDate(0, 1, 0)
+		}
+		for i := range s.Monthly.Quantity {
+			dates = append(dates, timex.NewDate(dayOfMonth(month.AddDate(0, i, 0), s.Monthly.DayOfMonth)))
+		}
+	case s.Custom != nil:
+		dates = append(dates, s.Custom.Dates...)
+		slices.SortFunc(dates, func(a, b timex.Date) int {
+			return a.Compare(b.Time)
+		})
+	}
+
+	return dates
+}
+
+type SingleSchedule struct {
+	Date timex.Date `bson:"date"`
+}
+
+type DailySchedule struct {
+	StartDate timex.Date `bson:"start_date"`
+	Quantity  int        `bson:"quantity"`
+}
+
+type WeeklySchedule struct {
+	DayOfWeek DayOfWeek  `bson:"day_of_week"`
+	StartDate timex.Date `bson:"start_date"`
+	Quantity  int        `bson:"quantity"`
+}
+
+type MonthlySchedule struct {
+	DayOfMonth int        `bson:"day_of_month"`
+	StartDate  timex.Date `bson:"start_date"`
+	Quantity   int        `bson:"quantity"`
+}
+
+type CustomSchedule struct {
+	Dates                 []timex.Date `bson:"dates"`
+	AdditionalInformation string       `bson:"additional_information,omitempty"`
+}
+
+type DayOfWeek string
+
+const (
+	DayOfWeekMonday    DayOfWeek = "SEGUNDA_FEIRA"
+	DayOfWeekTuesday   DayOfWeek = "TERCA_FEIRA"
+	DayOfWeekWednesday DayOfWeek = "QUARTA_FEIRA"
+	DayOfWeekThursday  DayOfWeek = "QUINTA_FEIRA"
+	DayOfWeekFriday    DayOfWeek = "SEXTA_FEIRA"
+	DayOfWeekSaturday  DayOfWeek = "SABADO"
+	DayOfWeekSunday    DayOfWeek = "DOMINGO"
+)
+
+var weekdays = map[DayOfWeek]time.Weekday{
+	DayOfWeekMonday:    time.Monday,
+	DayOfWeekTuesday:   time.Tuesday,
+	DayOfWeekWednesday: time.Wednesday,
+	DayOfWeekThursday:  time.Thursday,
+	DayOfWeekFriday:    time.Friday,
+	DayOfWeekSaturday:  time.Saturday,
+	DayOfWeekSunday:    time.Sunday,
+}
+
+func (d DayOfWeek) isValid() bool {
+	_, ok := weekdays[d]
+	return ok
+}
+
+func (d DayOfWeek) weekday() time.Weekday {
+	return weekdays[d]
+}
+
+// dayOfMonth returns the given day in the month of t. If the month doesn't
+// have that many days, its last day is returned.
+func dayOfMonth(t time.Time, day int) time.Time {
+	lastDay := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return time.Date(t.Year(), t.Month(), min(day, lastDay), 0, 0, 0, 0, time.UTC)
+}
+
+type Details struct {
+	LocalInstrument LocalInstrument `bson:"local_instrument"`
+	QRCode          string          `bson:"qr_code,omitempty"`
+	Proxy           string          `bson:"proxy,omitempty"`
+	CreditorAccount Account         `bson:"creditor_account"`
+}
+
+type LocalInstrument string
+
+const (
+	LocalInstrumentMANU LocalInstrument = "MANU"
+	LocalInstrumentDICT LocalInstrument = "DICT"
+	LocalInstrumentQRDN LocalInstrument = "QRDN"
+	LocalInstrumentQRES LocalInstrument = "QRES"
+	LocalInstrumentINIC LocalInstrument = "INIC"
+)
+
+type Account struct {
+	ISPB   string      `bson:"ispb"`
+	Issuer string      `bson:"issuer,omitempty"`
+	Number string      `bson:"number"`
+	Type   AccountType `bson:"type"`
+}
+
+// debtorAccountTypes maps the types of the accounts held at MockBank to the
+// types informed in payments.
+var debtorAccountTypes = map[account.Type]AccountType{
+	account.TypeCheckingAccount: AccountTypeCACC,
+	account.TypeSavingsAccount:  AccountTypeSVGS,
+	account.TypePrepaidPayment:  AccountTypeTRAN,
+}
+
+// NewDebtorAccount converts an account held at MockBank into the account
+// informed as the debtor of payments.
+func NewDebtorAccount(acc account.Account) Account {
+	debtorAcc := Account{
+		ISPB:   mock.MockBankISPB,
+		Number: acc.Number,
+		Type:   debtorAccountTypes[acc.Type],
+	}
+	// Payment accounts are not identified by a branch.
+	if debtorAcc.Type != AccountTypeTRAN {
+		debtorAcc.Issuer = account.DefaultBranch
+	}
+	return debtorAcc
+}
+
+// TransferAccount converts the account into the one informed to the SPI.
+func TransferAccount(acc Account) spi.Account {
+	return spi.Account{
+		ISPB:   acc.ISPB,
+		Branch: acc.Issuer,
+		Number: acc.Number,
+		Type:   string(acc.Type),
+	}
+}
+
+// TransferDebtorAccount converts the debtor account into the one informed to
+// the SPI. If the account is not informed, only the MockBank participant is.
+func TransferDebtorAccount(acc *Account) spi.Account {
+	if acc == nil {
+		return spi.Account{ISPB: mock.MockBankISPB}
+	}
+	return TransferAccount(*acc)
+}
+
+type AccountType string
+
+const (
+	AccountTypeCACC AccountType = "CACC"
+	AccountTypeSVGS AccountType = "SVGS"
+	AccountTypeTRAN AccountType = "TRAN"
+)
+
+type RejectionReason struct {
+	Code   RejectionReasonCode `bson:"code"`
+	Detail string              `bson:"detail"`
+}
+
+type RejectionReasonCode string
+
+const (
+	RejectionReasonCodeAmountAboveLimit           RejectionReasonCode = "VALOR_ACIMA_LIMITE"
+	RejectionReasonCodeInvalidQRCode              RejectionReasonCode = "QRCODE_INVALIDO"
+	RejectionReasonCodeAuthorizationTimeExpired   RejectionReasonCode = "TEMPO_EXPIRADO_AUTORIZACAO"
+	RejectionReasonCodeConsumptionTimeExpired     RejectionReasonCode = "TEMPO_EXPIRADO_CONSUMO"
+	RejectionReasonCodeRejectedByUser             RejectionReasonCode = "REJEITADO_USUARIO"
+	RejectionReasonCodeSameOriginAndDestination   RejectionReasonCode = "CONTAS_ORIGEM_DESTINO_IGUAIS"
+	RejectionReasonCodeAccountDoesNotAllowPayment RejectionReasonCode = "CONTA_NAO_PERMITE_PAGAMENTO"
+	RejectionReasonCodeInsufficientBalance        RejectionReasonCode = "SALDO_INSUFICIENTE"
+	RejectionReasonCodeInfrastructureFailure      RejectionReasonCode = "FALHA_INFRAESTRUTURA"
+	RejectionReasonCodeInvalidAmount              RejectionReasonCode = "VALOR_INVALIDO"
+	RejectionReasonCodeNotInformed                RejectionReasonCode = "NAO_INFORMADO"
+	RejectionReasonCodeDivergentPayment           RejectionReasonCode = "PAGAMENTO_DIVERGENTE_CONSENTIMENTO"
+	RejectionReasonCodeInvalidPaymentDetail       RejectionReasonCode = "DETALHE_PAGAMENTO_INVALIDO"
+	RejectionReasonCodeRefusedByHolder            RejectionReasonCode = "PAGAMENTO_RECUSADO_DETENTORA"
+	RejectionReasonCodeRefusedBySPI               RejectionReasonCode = "PAGAMENTO_RECUSADO_SPI"
+	RejectionReasonCodeSPIInfrastructureFailure   RejectionReasonCode = "FALHA_INFRAESTRUTURA_SPI"
+	RejectionReasonCodeDICTInfrastructureFailure  RejectionReasonCode = "FALHA_INFRAESTRUTURA_DICT"
+	RejectionReasonCodeSchedulingFailure          RejectionReasonCode = "FALHA_AGENDAMENTO_PAGAMENTOS"
+)
+
+// mockRejections maps payment amounts to the rejection reason MockBank, as the
+// account holder, uses to refuse payments with that amount before submitting
+// them to the SPI, so each rejection scenario can be reproduced
+// deterministically. The rejections by the SPI are configured in the fake
+// receiving institution.
+var mockRejections = map[string]RejectionReasonCode{
+	"10422.00": RejectionReasonCodeInsufficientBalance,
+	"10433.00": RejectionReasonCodeAmountAboveLimit,
+	"10455.00": RejectionReasonCodeRefusedByHolder,
+}
+
+// MockRejection returns the reason payments with amount are refused with by
+// the account holder, if any.
+func MockRejection(amount string) (RejectionReasonCode, bool) {
+	code, ok := mockRejections[amount]
+	return code, ok
+}
+
+// RiskRejectionReason translates the reason the risk rules refused a payment
+// with into the reason the payment is rejected with. If err is not a refusal
+// by the risk rules, e.g. a storage failure, false is returned and the payment
+// must not be rejected.
+func RiskRejectionReason(err error) (RejectionReason, bool) {
+	var code RejectionReasonCode
+	switch {
+	case errors.Is(err, risk.ErrInsufficientBalance):
+		code = RejectionReasonCodeInsufficientBalance
+	case errors.Is(err, risk.ErrTransactionLimitExceeded), errors.Is(err, risk.ErrDailyLimitExceeded),
+		errors.Is(err, risk.ErrNightlyLimitExceeded):
+		code = RejectionReasonCodeAmountAboveLimit
+	case errors.Is(err, risk.ErrBlockedCreditor):
+		code = RejectionReasonCodeRefusedByHolder
+	case errors.Is(err, account.ErrAccountNotFound):
+		code = RejectionReasonCodeAccountDoesNotAllowPayment
+	default:
+		return RejectionReason{}, false
+	}
+	return RejectionReason{
+		Code:   code,
+		Detail: err.Error(),
+	}, true
+}
+
+// DebitRejectionReason translates the reason the account of the user could not
+// be debited with into the reason the payment is rejected with.
+func DebitRejectionReason(err error) RejectionReason {
+	code := RejectionReasonCodeRefusedByHolder
+	switch {
+	case errors.Is(err, account.ErrInsufficientBalance):
+		code = RejectionReasonCodeInsufficientBalance
+	case errors.Is(err, account.ErrAccountNotFound):
+		code = RejectionReasonCodeAccountDoesNotAllowPayment
+	}
+	return RejectionReason{
+		Code:   code,
+		Detail: err.Error(),
+	}
+}
+
+// SettlementRejectionReason translates the ISO 20022 reason a transfer was
+// rejected with by the SPI into the reason the payment is rejected with.
+func SettlementRejectionReason(s spi.Settlement) RejectionReason {
+	code := RejectionReasonCodeRefusedBySPI
+	if s.ReasonCode.IsInfrastructureFailure() {
+		code = RejectionReasonCodeSPIInfrastructureFailure
+	}
+	return RejectionReason{
+		Code:   code,
+		Detail: fmt.Sprintf("the payment was rejected during settlement with the reason %s: %s", s.ReasonCode, s.AdditionalInformation),
+	}
+}
+
+type Payment struct {
+	ID                        string           `bson:"_id"`
+	EndToEndID                string           `bson:"end_to_end_id"`
+	ConsentID                 string           `bson:"consent_id"`
+	Status                    Status           `bson:"status"`
+	UserCPF                   string           `bson:"user_cpf"`
+	BusinessCNPJ              string           `bson:"business_cnpj,omitempty"`
+	Date                      timex.Date       `bson:"date"`
+	Amount                    string           `bson:"amount"`
+	Currency                  string           `bson:"currency"`
+	LocalInstrument           LocalInstrument  `bson:"local_instrument"`
+	CreditorAccount           Account          `bson:"creditor_account"`
+	DebtorAccount             *Account         `bson:"debtor_account,omitempty"`
+	Proxy                     string           `bson:"proxy,omitempty"`
+	QRCode                    string           `bson:"qr_code,omitempty"`
+	IBGETownCode              string           `bson:"ibge_town_code,omitempty"`
+	CNPJInitiator             string           `bson:"cnpj_initiator"`
+	TransactionIdentification string           `bson:"transaction_identification,omitempty"`
+	RemittanceInformation     string           `bson:"remittance_information,omitempty"`
+	AuthorisationFlow         string           `bson:"authorisation_flow,omitempty"`
+	RejectionReason           *RejectionReason `bson:"rejection,omitempty"`
+	Cancellation              *Cancellation    `bson:"cancellation,omitempty"`
+	// Settlement is the outcome of the transfer sent to the SPI once the
+	// payment is accepted.
+	Settlement *spi.Settlement `bson:"settlement,omitempty"`
+
+	ClientID             string         `bson:"client_id"`
+	CreationDateTime     timex.DateTime `bson:"created_at"`
+	StatusUpdateDateTime timex.DateTime `bson:"status_updated_at"`
+}
+
+// IsScheduled returns true if the payment must only be executed at a future
+// date.
+func (p Payment) IsScheduled() bool {
+	return p.Date.After(timex.DateNow().Time)
+}
+
+// ExecutionDate is the date in which a scheduled payment is executed.
+// Payments scheduled for days without banking operations are executed on the
+// next business day.
+func (p Payment) ExecutionDate() timex.Date {
+	if timex.IsBusinessDay(p.Date) {
+		return p.Date
+	}
+	return timex.NextBusinessDay(p.Date)
+}
+
+// IsDue returns true if the execution date of the payment has arrived.
+func (p Payment) IsDue() bool {
+	return !p.ExecutionDate().After(timex.DateNow().Time)
+}
+
+func (p Payment) CanBeCancelled() bool {
+	return p.Status == StatusPDNG || p.Status == StatusSCHD
+}
+
+// Status is the ISO 20022 based status of a payment.
+type Status string
+
+const (
+	// StatusRCVD means the payment was received by the account holder.
+	StatusRCVD Status = "RCVD"
+	// StatusPDNG means the payment is pending further checks, e.g. the
+	// authorization of other owners of a joint account.
+	StatusPDNG Status = "PDNG"
+	// StatusSCHD means the payment is scheduled to a future date.
+	StatusSCHD Status = "SCHD"
+	// StatusACCP means all the preceding checks were successful.
+	StatusACCP Status = "ACCP"
+	// StatusACPD means the payment was sent to the settlement system.
+	StatusACPD Status = "ACPD"
+	// StatusACSC means the payment was settled in the debtor account.
+	StatusACSC Status = "ACSC"
+	StatusRJCT Status = "RJCT"
+	StatusCANC Status = "CANC"
+)
+
+type Cancellation struct {
+	Reason     CancellationReason `bson:"reason"`
+	From       CancelledFrom      `bson:"from"`
+	At         timex.DateTime     `bson:"at"`
+	ByDocument string             `bson:"by_document"`
+}
+
+type CancellationReason string
+
+const (
+	CancellationReasonPending   CancellationReason = "CANCELADO_PENDENCIA"
+	CancellationReasonScheduled CancellationReason = "CANCELADO_AGENDAMENTO"
+)
+
+type CancelledFrom string
+
+const (
+	CancelledFromInitiator CancelledFrom = "INICIADORA"
+	CancelledFromHolder    CancelledFrom = "DETENTORA"
+)
+
+func consentID() string {
+	return fmt.Sprintf("urn:mockbank:%s", uuid.NewString())
+}
+
+func paymentID() string {
+	return uuid.NewString()
+}
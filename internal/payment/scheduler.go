@@ -0,0 +1,40 @@
+package payment
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const schedulerInterval = time.Minute
+
+// Scheduler periodically executes the scheduled payments whose date has
+// arrived and moves the payments being executed to a final status, so they
+// don't depend on being fetched by the client.
+type Scheduler struct {
+	service Service
+}
+
+func NewScheduler(service Service) Scheduler {
+	return Scheduler{
+		service: service,
+	}
+}
+
+// Run executes the payments until ctx is done.
+func (s Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.service.executeScheduled(ctx); err != nil {
+			slog.ErrorContext(ctx, "could not execute scheduled payments", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,922 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/luikyv/go-open-finance/internal/account"
+	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/brcode"
+	"github.com/luikyv/go-open-finance/internal/dict"
+	"github.com/luikyv/go-open-finance/internal/mock"
+	"github.com/luikyv/go-open-finance/internal/risk"
+	"github.com/luikyv/go-open-finance/internal/spi"
+	"github.com/luikyv/go-open-finance/internal/timex"
+	"github.com/luikyv/go-open-finance/internal/webhook"
+)
+
+var (
+	errAccessNotAllowed       = errors.New("access to payment consent is not allowed")
+	errInvalidPaymentMethod   = errors.New("the payment type is invalid")
+	errInvalidDate            = errors.New("the payment date is invalid")
+	errInvalidPaymentDetail   = errors.New("the payment details are invalid")
+	errInvalidAmount          = errors.New("the payment amount is invalid")
+	errInvalidCurrency        = errors.New("the payment currency is invalid")
+	errMissingDateAndSchedule = errors.New("either the payment date or schedule must be informed")
+	errDateAndSchedule        = errors.New("the payment date and schedule cannot be informed together")
+	errConsentAlreadyRejected = errors.New("the payment consent is already rejected")
+	errConsentNotAuthorized   = errors.New("the payment consent is not authorized")
+	errConsentTokenRequired   = errors.New("the payment consent was not authorized through an enrollment")
+	errDivergentPayment       = errors.New("the payment information diverges from the consent")
+	errInvalidEndToEndID      = errors.New("the end to end id is invalid")
+	errCancellationNotAllowed = errors.New("the payment does not allow cancellation")
+	errInvalidSchedule        = errors.New("the payment schedule is invalid")
+	errInvalidQRCode          = errors.New("the qr code is invalid")
+	errInvalidDebtorAccount   = errors.New("the debtor account does not belong to the user")
+
+	amountPattern     = regexp.MustCompile(`^\d{1,16}\.\d{2}$`)
+	endToEndIDPattern = regexp.MustCompile(`^E\d{8}(\d{12})[a-zA-Z0-9]{11}$`)
+)
+
+type Service struct {
+	storage Storage
+	// brcodeClient fetches the payloads of dynamic QR codes (QRDN).
+	brcodeClient brcode.Client
+	// dictService resolves the proxies of the creditors.
+	dictService dict.Service
+	// webhookService notifies the clients about status changes.
+	webhookService webhook.Service
+	// spiClient settles the payments.
+	spiClient spi.Client
+	// accountService debits the accounts of the users once their payments
+	// are settled.
+	accountService account.Service
+	// riskService evaluates the limits and fraud rules of the users before
+	// their payments are accepted.
+	riskService risk.Service
+}
+
+func NewService(
+	st Storage,
+	brcodeClient brcode.Client,
+	dictService dict.Service,
+	webhookService webhook.Service,
+	spiClient spi.Client,
+	accountService account.Service,
+	riskService risk.Service,
+) Service {
+	return Service{
+		storage:        st,
+		brcodeClient:   brcodeClient,
+		dictService:    dictService,
+		webhookService: webhookService,
+		spiClient:      spiClient,
+		accountService: accountService,
+		riskService:    riskService,
+	}
+}
+
+func (s Service) Authorize(ctx context.Context, c Consent) error {
+
+	slog.DebugContext(ctx, "trying to authorize payment consent", slog.String("consent_id", c.ID))
+
+	if !c.IsAwaitingAuthorization() {
+		slog.DebugContext(ctx, "cannot authorize a payment consent that is not awaiting authorization", slog.Any("status", c.Status))
+		return errors.New("invalid consent status")
+	}
+
+	slog.InfoContext(ctx, "authorizing payment consent", slog.String("consent_id", c.ID))
+	return s.updateConsentStatus(ctx, c, ConsentStatusAuthorized)
+}
+
+func (s Service) Consent(ctx context.Context, id string) (Consent, error) {
+	c, err := s.storage.consent(ctx, id)
+	if err != nil {
+		return Consent{}, err
+	}
+
+	if ctx.Value(api.CtxKeyClientID) != nil && ctx.Value(api.CtxKeyClientID) != c.ClientID {
+		return Consent{}, errAccessNotAllowed
+	}
+
+	if err := s.modifyConsent(ctx, &c); err != nil {
+		return Consent{}, err
+	}
+
+	return c, nil
+}
+
+// DebtorAccounts returns the accounts of the user which can be debited by
+// payments.
+func (s Service) DebtorAccounts(userCPF string) []account.Account {
+	var accs []account.Account
+	for _, acc := range s.accountService.Accounts(userCPF) {
+		if _, ok := debtorAccountTypes[acc.Type]; ok {
+			accs = append(accs, acc)
+		}
+	}
+	return accs
+}
+
+// IsDebtorAccount returns true if acc is one of the accounts of the user which
+// can be debited by payments.
+func (s Service) IsDebtorAccount(userCPF string, acc Account) bool {
+	for _, userAcc := range s.DebtorAccounts(userCPF) {
+		debtorAcc := NewDebtorAccount(userAcc)
+		if acc.ISPB == debtorAcc.ISPB && acc.Number == debtorAcc.Number && acc.Type == debtorAcc.Type &&
+			(acc.Issuer == "" || acc.Issuer == debtorAcc.Issuer) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDebtorAccount sets the account of the user identified by number as the
+// debtor account of the consent.
+func (s Service) SetDebtorAccount(c *Consent, number string) error {
+	for _, acc := range s.DebtorAccounts(c.UserCPF) {
+		if acc.Number == number {
+			debtorAcc := NewDebtorAccount(acc)
+			c.DebtorAccount = &debtorAcc
+			return nil
+		}
+	}
+	return errInvalidDebtorAccount
+}
+
+func (s Service) Reject(ctx context.Context, id string, reason RejectionReason) error {
+	c, err := s.Consent(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if c.Status == ConsentStatusRejected {
+		return errConsentAlreadyRejected
+	}
+
+	c.RejectionReason = &reason
+	return s.updateConsentStatus(ctx, c, ConsentStatusRejected)
+}
+
+func (s Service) createConsent(ctx context.Context, c Consent) error {
+	if err := validateConsent(c); err != nil {
+		return err
+	}
+
+	if err := s.validateQRCode(ctx, c); err != nil {
+		return err
+	}
+
+	if err := s.validateProxy(ctx, c.Payment.Details); err != nil {
+		return err
+	}
+
+	return s.saveConsent(ctx, c)
+}
+
+// modifyConsent will evaluate the consent information and modify it to be
+// compliant.
+func (s Service) modifyConsent(ctx context.Context, c *Consent) error {
+	consentWasModified := false
+
+	// Reject the consent if the time awaiting the user authorization has elapsed.
+	if c.HasAuthExpired() {
+		slog.DebugContext(ctx, "payment consent awaiting authorization for too long, moving to rejected")
+		c.Status = ConsentStatusRejected
+		c.RejectionReason = &RejectionReason{
+			Code:   RejectionReasonCodeAuthorizationTimeExpired,
+			Detail: "the consent was not authorized in time",
+		}
+		c.StatusUpdateDateTime = timex.DateTimeNow()
+		consentWasModified = true
+	}
+
+	// Reject the consent if it was authorized, but not used in time.
+	if c.HasConsumptionExpired() {
+		slog.DebugContext(ctx, "payment consent awaiting consumption for too long, moving to rejected")
+		c.Status = ConsentStatusRejected
+		c.RejectionReason = &RejectionReason{
+			Code:   RejectionReasonCodeConsumptionTimeExpired,
+			Detail: "the consent was not consumed in time",
+		}
+		c.StatusUpdateDateTime = timex.DateTimeNow()
+		consentWasModified = true
+	}
+
+	if consentWasModified {
+		slog.DebugContext(ctx, "the payment consent was modified")
+		if err := s.saveConsent(ctx, *c); err != nil {
+			return err
+		}
+		s.notifyConsent(ctx, *c)
+	}
+
+	return nil
+}
+
+func (s Service) updateConsentStatus(ctx context.Context, c Consent, status ConsentStatus) error {
+	c.Status = status
+	c.StatusUpdateDateTime = timex.DateTimeNow()
+	if err := s.saveConsent(ctx, c); err != nil {
+		return err
+	}
+
+	s.notifyConsent(ctx, c)
+	return nil
+}
+
+func (s Service) saveConsent(ctx context.Context, c Consent) error {
+	return s.storage.saveConsent(ctx, c)
+}
+
+// notifyConsent informs the client the status of the consent changed. Failing
+// to add the notification to the outbox doesn't prevent the change.
+func (s Service) notifyConsent(ctx context.Context, c Consent) {
+	if err := s.webhookService.Notify(ctx, c.ClientID, "/open-banking/payments/v4/consents/"+c.ID); err != nil {
+		slog.ErrorContext(ctx, "could not notify the payment consent status change", slog.String("consent_id", c.ID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// consume moves the consent to [ConsentStatusConsumed] since all the payments
+// it allows were initiated.
+func (s Service) consume(ctx context.Context, c Consent) error {
+	slog.InfoContext(ctx, "consuming payment consent", slog.String("consent_id", c.ID))
+	return s.updateConsentStatus(ctx, c, ConsentStatusConsumed)
+}
+
+// create initiates the payments authorized by the consent and consumes it.
+// withoutConsentToken informs the payments were requested with a client
+// credentials token, which is only allowed for consents authorized through an
+// enrollment, since no token is issued for them.
+func (s Service) create(ctx context.Context, consentID string, withoutConsentToken bool, ps []Payment) ([]Payment, error) {
+	c, err := s.Consent(ctx, consentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if withoutConsentToken && c.EnrollmentID == "" {
+		return nil, errConsentTokenRequired
+	}
+
+	if !c.IsAuthorized() {
+		return nil, errConsentNotAuthorized
+	}
+
+	if err := setPaymentDates(ps, c); err != nil {
+		return nil, err
+	}
+
+	for _, p := range ps {
+		if err := validatePayment(p, c); err != nil {
+			return nil, err
+		}
+	}
+
+	// The key may have been moved to another account since the consent was
+	// created.
+	if err := s.validateProxy(ctx, c.Payment.Details); err != nil {
+		return nil, err
+	}
+
+	for i := range ps {
+		ps[i].UserCPF = c.UserCPF
+		ps[i].BusinessCNPJ = c.BusinessCNPJ
+		ps[i].DebtorAccount = c.DebtorAccount
+		// Payments meant for future dates wait for the scheduler to be
+		// executed.
+		if ps[i].IsScheduled() {
+			ps[i].Status = StatusSCHD
+		}
+		if err := s.savePayment(ctx, ps[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.consume(ctx, c); err != nil {
+		return nil, err
+	}
+
+	return ps, nil
+}
+
+// executeScheduled moves the scheduled payments whose date has arrived, as well
+// as the payments already being executed, forward until they reach a final
+// status or must wait for the SPI. Failures are only logged, so a payment
+// doesn't hold back the others.
+func (s Service) executeScheduled(ctx context.Context) error {
+	var ps []Payment
+	for _, status := range []Status{StatusSCHD, StatusACCP, StatusACPD} {
+		psByStatus, err := s.storage.paymentsByStatus(ctx, status)
+		if err != nil {
+			return err
+		}
+		ps = append(ps, psByStatus...)
+	}
+
+	for _, p := range ps {
+		if p.Status == StatusSCHD && !p.IsDue() {
+			continue
+		}
+
+		slog.InfoContext(ctx, "executing payment", slog.String("payment_id", p.ID), slog.Any("status", p.Status))
+		if err := s.execute(ctx, &p); err != nil {
+			slog.ErrorContext(ctx, "could not execute payment", slog.String("payment_id", p.ID),
+				slog.String("error", err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// execute moves the payment forward in its life cycle until its status stops
+// changing.
+func (s Service) execute(ctx context.Context, p *Payment) error {
+	for {
+		status := p.Status
+		if err := s.modifyPayment(ctx, p); err != nil {
+			return err
+		}
+
+		if p.Status == status {
+			return nil
+		}
+	}
+}
+
+func (s Service) Payment(ctx context.Context, id string) (Payment, error) {
+	p, err := s.storage.payment(ctx, id)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	if ctx.Value(api.CtxKeyClientID) != nil && ctx.Value(api.CtxKeyClientID) != p.ClientID {
+		return Payment{}, errAccessNotAllowed
+	}
+
+	if err := s.modifyPayment(ctx, &p); err != nil {
+		return Payment{}, err
+	}
+
+	return p, nil
+}
+
+func (s Service) cancel(ctx context.Context, id, document string) (Payment, error) {
+	p, err := s.Payment(ctx, id)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	if !p.CanBeCancelled() {
+		return Payment{}, errCancellationNotAllowed
+	}
+
+	reason := CancellationReasonPending
+	if p.Status == StatusSCHD {
+		reason = CancellationReasonScheduled
+	}
+
+	previous := p.Status
+	p.Status = StatusCANC
+	p.StatusUpdateDateTime = timex.DateTimeNow()
+	p.Cancellation = &Cancellation{
+		Reason:     reason,
+		From:       CancelledFromInitiator,
+		At:         timex.DateTimeNow(),
+		ByDocument: document,
+	}
+	// The payment may have been moved forward since it was evaluated.
+	ok, err := s.updatePayment(ctx, &p, previous)
+	if err != nil {
+		return Payment{}, err
+	}
+	if !ok {
+		return Payment{}, errCancellationNotAllowed
+	}
+
+	s.notifyPayment(ctx, p)
+	return p, nil
+}
+
+// modifyPayment moves the payment one step forward in its life cycle every
+// time it is evaluated.
+// Payments are evaluated concurrently by the requests of the client and by the
+// scheduler, so a step is only applied if the payment was not moved forward in
+// the meantime, otherwise p is reloaded. The account is only debited or
+// refunded by the evaluation whose step was applied.
+func (s Service) modifyPayment(ctx context.Context, p *Payment) error {
+	previous := p.Status
+	status := p.Status
+	// transfer is sent to the SPI once the payment is moved to ACPD.
+	var transfer *spi.Transfer
+	refund := false
+	switch p.Status {
+	case StatusRCVD:
+		status = StatusACCP
+		if p.UserCPF == mock.CPFWithJointAccount {
+			status = StatusPDNG
+		}
+		if p.IsScheduled() {
+			status = StatusSCHD
+		}
+	case StatusPDNG:
+		if !mock.IsJointAccountPendingAuth(p.CreationDateTime) {
+			status = StatusACCP
+		}
+	case StatusSCHD:
+		if p.IsDue() {
+			status = StatusACCP
+		}
+	case StatusACCP:
+		if code, ok := mockRejections[p.Amount]; ok {
+			status = StatusRJCT
+			p.RejectionReason = &RejectionReason{
+				Code:   code,
+				Detail: "the payment was refused by the account holder",
+			}
+			break
+		}
+
+		t, err := s.newTransfer(ctx, *p)
+		if err != nil {
+			return err
+		}
+		status = StatusACPD
+		transfer = &t
+	case StatusACPD:
+		// The payment stays in ACPD until the SPI informs the outcome of the
+		// transfer. Payments without a settlement are still being sent.
+		if p.Settlement == nil || !p.Settlement.IsDue() {
+			break
+		}
+
+		if !p.Settlement.IsSettled() {
+			status = StatusRJCT
+			reason := SettlementRejectionReason(*p.Settlement)
+			p.RejectionReason = &reason
+			refund = true
+			break
+		}
+
+		status = StatusACSC
+	}
+
+	// The limits and fraud rules are evaluated right before the payment is
+	// accepted.
+	if status == StatusACCP {
+		if err := s.evaluateRisk(ctx, *p); err != nil {
+			reason, ok := RiskRejectionReason(err)
+			if !ok {
+				return err
+			}
+			status = StatusRJCT
+			p.RejectionReason = &reason
+		}
+	}
+
+	if status == p.Status {
+		return nil
+	}
+
+	slog.DebugContext(ctx, "updating payment status", slog.String("payment_id", p.ID),
+		slog.Any("from", p.Status), slog.Any("to", status))
+	p.Status = status
+	p.StatusUpdateDateTime = timex.DateTimeNow()
+	if ok, err := s.updatePayment(ctx, p, previous); err != nil || !ok {
+		// The amount counted towards the limits when the payment was
+		// evaluated is given back, since the payment was not accepted.
+		if status == StatusACCP {
+			s.releaseRisk(*p)
+		}
+		return err
+	}
+
+	// Payments rejected after being accepted no longer count towards the
+	// limits of the user.
+	if status == StatusRJCT && (previous == StatusACCP || previous == StatusACPD) {
+		s.releaseRisk(*p)
+	}
+
+	s.notifyPayment(ctx, *p)
+	if transfer != nil {
+		return s.send(ctx, p, *transfer)
+	}
+	if refund {
+		s.refund(ctx, *p)
+	}
+	return nil
+}
+
+// updatePayment saves the payment if its status is still previous. Otherwise,
+// p is reloaded with the current state of the payment and false is returned.
+func (s Service) updatePayment(ctx context.Context, p *Payment, previous Status) (bool, error) {
+	ok, err := s.storage.updatePayment(ctx, *p, previous)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	slog.DebugContext(ctx, "the payment was modified concurrently", slog.String("payment_id", p.ID))
+	current, err := s.storage.payment(ctx, p.ID)
+	if err != nil {
+		return false, err
+	}
+	*p = current
+	return false, nil
+}
+
+// evaluateRisk checks the payment against the limits and fraud rules of the
+// user.
+func (s Service) evaluateRisk(ctx context.Context, p Payment) error {
+	c, err := s.storage.consent(ctx, p.ConsentID)
+	if err != nil {
+		return err
+	}
+
+	var number string
+	if p.DebtorAccount != nil {
+		number = p.DebtorAccount.Number
+	}
+
+	return s.riskService.Evaluate(ctx, risk.Payment{
+		ID:               p.ID,
+		UserCPF:          p.UserCPF,
+		AccountNumber:    number,
+		Amount:           p.Amount,
+		CreditorDocument: c.Creditor.CPFCNPJ,
+		Proxy:            p.Proxy,
+	})
+}
+
+// releaseRisk gives back the amount of the payment to the limits of the user.
+func (s Service) releaseRisk(p Payment) {
+	s.riskService.Release(p.UserCPF, p.ID)
+}
+
+// newTransfer builds the transfer of the payment to the SPI.
+func (s Service) newTransfer(ctx context.Context, p Payment) (spi.Transfer, error) {
+	c, err := s.storage.consent(ctx, p.ConsentID)
+	if err != nil {
+		return spi.Transfer{}, err
+	}
+
+	debtorDocument := p.UserCPF
+	if p.BusinessCNPJ != "" {
+		debtorDocument = p.BusinessCNPJ
+	}
+
+	return spi.Transfer{
+		EndToEndID:            p.EndToEndID,
+		Amount:                p.Amount,
+		Currency:              p.Currency,
+		DebtorDocument:        debtorDocument,
+		DebtorAccount:         TransferDebtorAccount(p.DebtorAccount),
+		CreditorName:          c.Creditor.Name,
+		CreditorDocument:      c.Creditor.CPFCNPJ,
+		CreditorAccount:       TransferAccount(p.CreditorAccount),
+		Proxy:                 p.Proxy,
+		RemittanceInformation: p.RemittanceInformation,
+	}, nil
+}
+
+// send debits the account of the user and sends the transfer of the payment to
+// the SPI. Payments whose account cannot be debited are rejected without being
+// sent.
+func (s Service) send(ctx context.Context, p *Payment, t spi.Transfer) error {
+	if err := s.debit(ctx, *p); err != nil {
+		slog.InfoContext(ctx, "the account could not be debited, rejecting the payment", slog.String("payment_id", p.ID),
+			slog.String("error", err.Error()))
+		reason := DebitRejectionReason(err)
+		p.RejectionReason = &reason
+		p.Status = StatusRJCT
+		p.StatusUpdateDateTime = timex.DateTimeNow()
+		if ok, err := s.updatePayment(ctx, p, StatusACPD); err != nil || !ok {
+			return err
+		}
+		s.releaseRisk(*p)
+		s.notifyPayment(ctx, *p)
+		return nil
+	}
+
+	settlement := s.spiClient.Transfer(ctx, t)
+	p.Settlement = &settlement
+	_, err := s.updatePayment(ctx, p, StatusACPD)
+	return err
+}
+
+// debit withdraws the amount of the payment from the account of the user.
+func (s Service) debit(ctx context.Context, p Payment) error {
+	var number string
+	if p.DebtorAccount != nil {
+		number = p.DebtorAccount.Number
+	}
+
+	return s.accountService.Debit(ctx, p.UserCPF, number, p.Amount, account.TransactionTypePix, "PIX "+p.EndToEndID)
+}
+
+// refund gives the amount of a payment rejected by the SPI back to the user.
+// Failures are only logged, since the payment was already rejected.
+func (s Service) refund(ctx context.Context, p Payment) {
+	var number string
+	if p.DebtorAccount != nil {
+		number = p.DebtorAccount.Number
+	}
+
+	if err := s.accountService.Credit(ctx, p.UserCPF, number, p.Amount, account.TransactionTypePix,
+		"ESTORNO PIX "+p.EndToEndID); err != nil {
+		slog.ErrorContext(ctx, "could not refund the rejected payment", slog.String("payment_id", p.ID),
+			slog.String("error", err.Error()))
+	}
+}
+
+func (s Service) savePayment(ctx context.Context, p Payment) error {
+	return s.storage.savePayment(ctx, p)
+}
+
+// notifyPayment informs the client the status of the payment changed.
+func (s Service) notifyPayment(ctx context.Context, p Payment) {
+	if err := s.webhookService.Notify(ctx, p.ClientID, "/open-banking/payments/v4/pix/payments/"+p.ID); err != nil {
+		slog.ErrorContext(ctx, "could not notify the payment status change", slog.String("payment_id", p.ID),
+			slog.String("error", err.Error()))
+	}
+}
+
+func validateConsent(c Consent) error {
+	if c.Payment.Type != TypePix {
+		return errInvalidPaymentMethod
+	}
+
+	if err := validateAmount(c.Payment.Amount, c.Payment.Currency); err != nil {
+		return err
+	}
+
+	if c.Payment.Date == nil && c.Payment.Schedule == nil {
+		return errMissingDateAndSchedule
+	}
+
+	if c.Payment.Date != nil && c.Payment.Schedule != nil {
+		return errDateAndSchedule
+	}
+
+	// Payments without schedule must happen in the same day the consent is
+	// created.
+	if c.Payment.Date != nil && !c.Payment.Date.Equal(timex.DateNow().Time) {
+		return errInvalidDate
+	}
+
+	if c.Payment.Schedule != nil {
+		if err := validateSchedule(*c.Payment.Schedule); err != nil {
+			return err
+		}
+	}
+
+	return validateDetails(c.Payment.Details)
+}
+
+// validateQRCode checks the proxy, amount and creditor of consents with QR
+// codes against the information carried by the code. The payload of dynamic
+// codes is fetched from the PSP of the creditor.
+func (s Service) validateQRCode(ctx context.Context, c Consent) error {
+	details := c.Payment.Details
+	if details.LocalInstrument != LocalInstrumentQRES && details.LocalInstrument != LocalInstrumentQRDN {
+		return nil
+	}
+
+	code, err := brcode.Parse(details.QRCode)
+	if err != nil {
+		slog.DebugContext(ctx, "could not parse the qr code", slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %w", errInvalidQRCode, err)
+	}
+
+	if code.Dynamic != (details.LocalInstrument == LocalInstrumentQRDN) {
+		return fmt.Errorf("%w: the qr code type does not match the local instrument", errInvalidPaymentDetail)
+	}
+
+	if !code.MatchesName(c.Creditor.Name) {
+		return fmt.Errorf("%w: the creditor does not match the qr code", errInvalidPaymentDetail)
+	}
+
+	key, amount := code.Key, code.Amount
+	if code.Dynamic {
+		payload, err := s.brcodeClient.Payload(ctx, code.URL)
+		if err != nil {
+			slog.DebugContext(ctx, "could not fetch the qr code payload", slog.String("error", err.Error()))
+			return fmt.Errorf("%w: %w", errInvalidQRCode, err)
+		}
+
+		if !payload.IsActive() || payload.HasExpired() {
+			return fmt.Errorf("%w: the charge is no longer active", errInvalidQRCode)
+		}
+
+		key, amount = payload.Key, payload.Amount.Original
+	}
+
+	if key != details.Proxy {
+		return fmt.Errorf("%w: the proxy does not match the qr code", errInvalidPaymentDetail)
+	}
+
+	// Static codes may leave the amount to be informed by the payer.
+	if amount != "" && amount != c.Payment.Amount {
+		return fmt.Errorf("%w: the amount does not match the qr code", errInvalidPaymentDetail)
+	}
+
+	return nil
+}
+
+// validateProxy resolves the proxy in the DICT and checks it belongs to the
+// creditor account.
+func (s Service) validateProxy(ctx context.Context, details Details) error {
+	if details.Proxy == "" {
+		return nil
+	}
+
+	key, err := s.dictService.Key(ctx, details.Proxy)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInvalidPaymentDetail, err)
+	}
+
+	acc := details.CreditorAccount
+	if key.Account.Participant != acc.ISPB ||
+		key.Account.Number != acc.Number ||
+		string(key.Account.Type) != string(acc.Type) ||
+		(acc.Issuer != "" && acc.Issuer != key.Account.Branch) {
+		return fmt.Errorf("%w: the creditor account does not match the proxy", errInvalidPaymentDetail)
+	}
+
+	return nil
+}
+
+func validatePayment(p Payment, c Consent) error {
+	matches := endToEndIDPattern.FindStringSubmatch(p.EndToEndID)
+	if matches == nil {
+		return errInvalidEndToEndID
+	}
+
+	// The end to end id carries the date the payment is meant to be settled.
+	if !strings.HasPrefix(matches[1], strings.ReplaceAll(p.Date.String(), "-", "")) {
+		return errInvalidEndToEndID
+	}
+
+	if p.Amount != c.Payment.Amount || p.Currency != c.Payment.Currency {
+		return errDivergentPayment
+	}
+
+	if p.LocalInstrument != c.Payment.Details.LocalInstrument ||
+		p.Proxy != c.Payment.Details.Proxy ||
+		p.QRCode != c.Payment.Details.QRCode {
+		return errDivergentPayment
+	}
+
+	if p.CreditorAccount != c.Payment.Details.CreditorAccount {
+		return errDivergentPayment
+	}
+
+	return nil
+}
+
+func validateAmount(amount, currency string) error {
+	if currency != DefaultCurrency {
+		return errInvalidCurrency
+	}
+
+	if !amountPattern.MatchString(amount) || amount == "0.00" {
+		return errInvalidAmount
+	}
+
+	return nil
+}
+
+func validateSchedule(schedule Schedule) error {
+	informed := 0
+	for _, isInformed := range []bool{
+		schedule.Single != nil,
+		schedule.Daily != nil,
+		schedule.Weekly != nil,
+		schedule.Monthly != nil,
+		schedule.Custom != nil,
+	} {
+		if isInformed {
+			informed++
+		}
+	}
+	if informed != 1 {
+		return errInvalidSchedule
+	}
+
+	switch {
+	case schedule.Daily != nil:
+		if schedule.Daily.Quantity < minScheduleQuantity || schedule.Daily.Quantity > maxDailyScheduleQuantity {
+			return errInvalidSchedule
+		}
+	case schedule.Weekly != nil:
+		if !schedule.Weekly.DayOfWeek.isValid() {
+			return errInvalidSchedule
+		}
+		if schedule.Weekly.Quantity < minScheduleQuantity || schedule.Weekly.Quantity > maxWeeklyScheduleQuantity {
+			return errInvalidSchedule
+		}
+	case schedule.Monthly != nil:
+		if schedule.Monthly.DayOfMonth < 1 || schedule.Monthly.DayOfMonth > 31 {
+			return errInvalidSchedule
+		}
+		if schedule.Monthly.Quantity < minScheduleQuantity || schedule.Monthly.Quantity > maxMonthlyScheduleQuantity {
+			return errInvalidSchedule
+		}
+	case schedule.Custom != nil:
+		dates := schedule.Custom.Dates
+		if len(dates) < minScheduleQuantity || len(dates) > maxCustomScheduleQuantity {
+			return errInvalidSchedule
+		}
+		seen := map[string]bool{}
+		for _, d := range dates {
+			if seen[d.String()] {
+				return errInvalidSchedule
+			}
+			seen[d.String()] = true
+		}
+	}
+
+	// Scheduled payments must start at least in the next day (D+1) and cannot
+	// go beyond the max schedule period.
+	today := timex.DateNow()
+	limit := today.AddDate(0, 0, maxScheduleDays)
+	for _, d := range schedule.Dates() {
+		if !d.After(today.Time) || d.After(limit) {
+			return errInvalidDate
+		}
+	}
+
+	return nil
+}
+
+// setPaymentDates defines the date in which each payment will be executed
+// based on the consent.
+// For recurring schedules, the date is the one informed in the end to end id
+// and all the dates in the schedule must be covered by exactly one payment.
+func setPaymentDates(ps []Payment, c Consent) error {
+	if c.Payment.Date != nil {
+		for i := range ps {
+			ps[i].Date = *c.Payment.Date
+		}
+		return nil
+	}
+
+	dates := c.Payment.Schedule.Dates()
+	if len(ps) != len(dates) {
+		return errDivergentPayment
+	}
+
+	pending := map[string]bool{}
+	for _, d := range dates {
+		pending[d.String()] = true
+	}
+
+	for i := range ps {
+		matches := endToEndIDPattern.FindStringSubmatch(ps[i].EndToEndID)
+		if matches == nil {
+			return errInvalidEndToEndID
+		}
+
+		date, err := timex.ParseDate(matches[1][:4] + "-" + matches[1][4:6] + "-" + matches[1][6:8])
+		if err != nil {
+			return errInvalidEndToEndID
+		}
+
+		if !pending[date.String()] {
+			return errDivergentPayment
+		}
+		delete(pending, date.String())
+		ps[i].Date = date
+	}
+
+	return nil
+}
+
+func validateDetails(details Details) error {
+	switch details.LocalInstrument {
+	case LocalInstrumentMANU:
+		if details.Proxy != "" || details.QRCode != "" {
+			return errInvalidPaymentDetail
+		}
+	case LocalInstrumentDICT, LocalInstrumentINIC:
+		if details.Proxy == "" || details.QRCode != "" {
+			return errInvalidPaymentDetail
+		}
+	case LocalInstrumentQRES, LocalInstrumentQRDN:
+		if details.Proxy == "" || details.QRCode == "" {
+			return errInvalidPaymentDetail
+		}
+	default:
+		return errInvalidPaymentDetail
+	}
+
+	acc := details.CreditorAccount
+	if acc.Type != AccountTypeTRAN && acc.Issuer == "" {
+		return errInvalidPaymentDetail
+	}
+
+	return nil
+}
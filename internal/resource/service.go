@@ -37,5 +37,45 @@ func (s Service) resources(ctx context.Context, consentID string, pag page.Pagin
 		rs = append(rs, r)
 	}
 
+	for _, id := range c.LoanIDs {
+		rs = append(rs, Resource{
+			ID:     id,
+			Type:   TypeLoan,
+			Status: StatusAvailable,
+		})
+	}
+
+	for _, id := range c.FinancingIDs {
+		rs = append(rs, Resource{
+			ID:     id,
+			Type:   TypeFinancing,
+			Status: StatusAvailable,
+		})
+	}
+
+	for _, id := range c.UnarrangedOverdraftIDs {
+		rs = append(rs, Resource{
+			ID:     id,
+			Type:   TypeUnarrangedAccountOverdraft,
+			Status: StatusAvailable,
+		})
+	}
+
+	for _, id := range c.InvoiceFinancingIDs {
+		rs = append(rs, Resource{
+			ID:     id,
+			Type:   TypeInvoiceFinancing,
+			Status: StatusAvailable,
+		})
+	}
+
+	for _, id := range c.BankFixedIncomeIDs {
+		rs = append(rs, Resource{
+			ID:     id,
+			Type:   TypeBankFixedIncome,
+			Status: StatusAvailable,
+		})
+	}
+
 	return page.Paginate(rs, pag), nil
 }
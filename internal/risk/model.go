@@ -0,0 +1,75 @@
+// Package risk evaluates the limits and fraud rules configured for the users
+// before their payments are accepted.
+package risk
+
+import (
+	"time"
+)
+
+const (
+	// DefaultNightlyLimit is the default limit for Pix payments made by
+	// individuals at night, as defined by the Central Bank.
+	DefaultNightlyLimit = "1000.00"
+	// nightStartHour and nightEndHour delimit the night period defined by the
+	// Central Bank, which goes from 20h to 6h in local time.
+	nightStartHour = 20
+	nightEndHour   = 6
+)
+
+// Rules are the limits and restrictions applied to the payments of a user.
+// Limits left empty are not enforced.
+type Rules struct {
+	// TransactionLimit is the maximum amount of a single payment.
+	TransactionLimit string
+	// DailyLimit is the maximum amount the user can pay in a day.
+	DailyLimit string
+	// NightlyLimit is the maximum amount the user can pay during the night
+	// period.
+	NightlyLimit string
+	// CheckBalance indicates whether payments are refused when the account of
+	// the user doesn't have enough balance.
+	CheckBalance bool
+	// BlockedCreditors are the CPFs, CNPJs and Pix keys the user is not
+	// allowed to pay.
+	BlockedCreditors []string
+}
+
+// Payment is the information about a payment the rules are evaluated against.
+type Payment struct {
+	// ID identifies the payment, so its amount can be released if the payment
+	// is rejected after being evaluated.
+	ID      string
+	UserCPF string
+	// AccountNumber identifies the account to be debited. If empty, the first
+	// account of the user is considered.
+	AccountNumber    string
+	Amount           string
+	CreditorDocument string
+	Proxy            string
+}
+
+// usage is an amount the user paid, which counts towards the limits.
+type usage struct {
+	paymentID string
+	cents     int64
+	at        time.Time
+}
+
+// nightStart returns when the night period t is in started. If t is not in a
+// night period, false is returned.
+func nightStart(t time.Time) (time.Time, bool) {
+	year, month, day := t.Date()
+	switch {
+	case t.Hour() >= nightStartHour:
+		return time.Date(year, month, day, nightStartHour, 0, 0, 0, t.Location()), true
+	case t.Hour() < nightEndHour:
+		return time.Date(year, month, day-1, nightStartHour, 0, 0, 0, t.Location()), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func dayStart(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
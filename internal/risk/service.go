@@ -0,0 +1,130 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/luikyv/go-open-finance/internal/account"
+	"github.com/luikyv/go-open-finance/internal/money"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+var (
+	ErrTransactionLimitExceeded = errors.New("the payment amount exceeds the transaction limit")
+	ErrDailyLimitExceeded       = errors.New("the payment amount exceeds the daily limit")
+	ErrNightlyLimitExceeded     = errors.New("the payment amount exceeds the nightly limit")
+	ErrInsufficientBalance      = errors.New("the account does not have enough balance")
+	ErrBlockedCreditor          = errors.New("the creditor is blocked")
+)
+
+type Service struct {
+	storage        *Storage
+	accountService account.Service
+}
+
+func NewService(storage *Storage, accountService account.Service) Service {
+	return Service{
+		storage:        storage,
+		accountService: accountService,
+	}
+}
+
+// SetRules configures the rules applied to the payments of the user.
+func (s Service) SetRules(userCPF string, r Rules) {
+	s.storage.saveRules(userCPF, r)
+}
+
+// Evaluate checks the payment against the rules of the user. If the payment
+// is allowed, its amount counts towards the limits of the user from then on,
+// unless it is released. Otherwise, one of the errors of this package is
+// returned, or account.ErrAccountNotFound if the balance is checked against an
+// account the user doesn't have.
+func (s Service) Evaluate(ctx context.Context, p Payment) error {
+	cents, err := money.Parse(p.Amount)
+	if err != nil {
+		return err
+	}
+
+	rules := s.storage.rules(p.UserCPF)
+	if slices.Contains(rules.BlockedCreditors, p.CreditorDocument) ||
+		(p.Proxy != "" && slices.Contains(rules.BlockedCreditors, p.Proxy)) {
+		return ErrBlockedCreditor
+	}
+
+	if exceeds(cents, rules.TransactionLimit) {
+		return ErrTransactionLimitExceeded
+	}
+
+	now := timex.Now().In(timex.Location())
+	err = s.storage.record(p.UserCPF, usage{paymentID: p.ID, cents: cents, at: now}, func(usages []usage) error {
+		if exceeds(cents+usedSince(usages, dayStart(now)), rules.DailyLimit) {
+			return ErrDailyLimitExceeded
+		}
+
+		if start, ok := nightStart(now); ok && exceeds(cents+usedSince(usages, start), rules.NightlyLimit) {
+			return ErrNightlyLimitExceeded
+		}
+
+		if rules.CheckBalance {
+			return s.checkBalance(p, cents)
+		}
+
+		return nil
+	})
+	if err != nil {
+		slog.InfoContext(ctx, "payment refused by the risk rules", slog.String("error", err.Error()))
+		return err
+	}
+
+	return nil
+}
+
+// Release gives back the amount of a payment that was allowed by Evaluate but
+// ended up rejected, so it no longer counts towards the limits of the user.
+func (s Service) Release(userCPF, paymentID string) {
+	s.storage.release(userCPF, paymentID)
+}
+
+func (s Service) checkBalance(p Payment, cents int64) error {
+	acc, err := s.accountService.UserAccount(p.UserCPF, p.AccountNumber)
+	if err != nil {
+		return err
+	}
+
+	available, err := money.Parse(acc.Balance.AvailableAmount)
+	if err != nil {
+		return err
+	}
+
+	if cents > available {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
+
+// exceeds returns true if cents is above the limit. Empty limits are never
+// exceeded.
+func exceeds(cents int64, limit string) bool {
+	if limit == "" {
+		return false
+	}
+
+	limitCents, err := money.Parse(limit)
+	if err != nil {
+		return false
+	}
+	return cents > limitCents
+}
+
+func usedSince(usages []usage, start time.Time) int64 {
+	var cents int64
+	for _, u := range usages {
+		if !u.at.Before(start) {
+			cents += u.cents
+		}
+	}
+	return cents
+}
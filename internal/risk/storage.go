@@ -0,0 +1,65 @@
+package risk
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+type Storage struct {
+	mu        sync.Mutex
+	rulesMap  map[string]Rules
+	usagesMap map[string][]usage
+}
+
+func NewStorage() *Storage {
+	return &Storage{
+		rulesMap:  map[string]Rules{},
+		usagesMap: map[string][]usage{},
+	}
+}
+
+func (s *Storage) saveRules(userCPF string, r Rules) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rulesMap[userCPF] = r
+}
+
+func (s *Storage) rules(userCPF string) Rules {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rulesMap[userCPF]
+}
+
+// record evaluates fn against the previous usages of the user and records the
+// new usage if fn succeeds. It is atomic, so concurrent payments cannot exceed
+// the limits together.
+func (s *Storage) record(userCPF string, u usage, fn func(usages []usage) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := fn(s.usagesMap[userCPF]); err != nil {
+		return err
+	}
+
+	// Usages older than a day no longer count towards any limit.
+	var usages []usage
+	for _, old := range s.usagesMap[userCPF] {
+		if u.at.Sub(old.at) < 24*time.Hour {
+			usages = append(usages, old)
+		}
+	}
+	s.usagesMap[userCPF] = append(usages, u)
+	return nil
+}
+
+// release removes the usage of the payment, so its amount no longer counts
+// towards the limits of the user.
+func (s *Storage) release(userCPF, paymentID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.usagesMap[userCPF] = slices.DeleteFunc(s.usagesMap[userCPF], func(u usage) bool {
+		return u.paymentID == paymentID
+	})
+}
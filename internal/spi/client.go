@@ -0,0 +1,156 @@
+package spi
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/luikyv/go-open-finance/internal/mock"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+const (
+	contentTypeXML  = "application/xml"
+	maxMessageBytes = 1 << 20
+)
+
+// Client sends the transfers of MockBank to the receiving institution.
+type Client struct {
+	httpClient  *http.Client
+	receiverURL string
+	// settlementDelay is how long the outcome of a transfer takes to be
+	// known after it is sent.
+	settlementDelay time.Duration
+}
+
+func NewClient(httpClient *http.Client, receiverURL string, settlementDelay time.Duration) Client {
+	return Client{
+		httpClient:      httpClient,
+		receiverURL:     receiverURL,
+		settlementDelay: settlementDelay,
+	}
+}
+
+// Transfer sends the transfer to the receiving institution as a pacs.008 and
+// returns its outcome based on the pacs.002 received in reply.
+// Failures to reach the receiving institution or invalid replies result in
+// rejected settlements, the same way the SPI rejects transfers that the
+// creditor agent does not answer.
+func (c Client) Transfer(ctx context.Context, t Transfer) Settlement {
+	msg := newPacs008(t)
+	settlement := Settlement{
+		MessageID: msg.Transfer.GroupHeader.MessageID,
+		DateTime:  timex.NewDateTime(timex.Now().Add(c.settlementDelay)),
+	}
+
+	report, err := c.send(ctx, msg)
+	if err != nil {
+		slog.ErrorContext(ctx, "could not send the pacs.008 to the receiving institution",
+			slog.String("end_to_end_id", t.EndToEndID), slog.String("error", err.Error()))
+		settlement.Status = SettlementStatusRejected
+		settlement.ReasonCode = ReasonCodeTimeout
+		settlement.AdditionalInformation = "the receiving institution did not reply"
+		return settlement
+	}
+
+	info := report.Report.TransactionInfo
+	switch {
+	case info.OriginalEndToEndID != t.EndToEndID:
+		settlement.Status = SettlementStatusRejected
+		settlement.ReasonCode = ReasonCodeCreditorAgentError
+		settlement.AdditionalInformation = "the pacs.002 refers to another transfer"
+	case info.Status == txStatusAccepted:
+		settlement.Status = SettlementStatusSettled
+	case info.Status == txStatusRejected && info.StatusReason != nil:
+		settlement.Status = SettlementStatusRejected
+		settlement.ReasonCode = info.StatusReason.Code
+		settlement.AdditionalInformation = info.StatusReason.AdditionalInformation
+	default:
+		settlement.Status = SettlementStatusRejected
+		settlement.ReasonCode = ReasonCodeCreditorAgentError
+		settlement.AdditionalInformation = "the pacs.002 status is invalid"
+	}
+
+	slog.InfoContext(ctx, "transfer processed by the receiving institution", slog.String("end_to_end_id", t.EndToEndID),
+		slog.Any("status", settlement.Status), slog.Any("reason_code", settlement.ReasonCode))
+	return settlement
+}
+
+func (c Client) send(ctx context.Context, msg pacs008) (pacs002, error) {
+	body, err := xml.Marshal(msg)
+	if err != nil {
+		return pacs002{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.receiverURL, bytes.NewReader(append([]byte(xml.Header), body...)))
+	if err != nil {
+		return pacs002{}, err
+	}
+	req.Header.Set("Content-Type", contentTypeXML)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return pacs002{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return pacs002{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var report pacs002
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, maxMessageBytes)).Decode(&report); err != nil {
+		return pacs002{}, err
+	}
+
+	if report.Report.OriginalGroupInfo.MessageID != msg.Transfer.GroupHeader.MessageID {
+		return pacs002{}, fmt.Errorf("the pacs.002 does not refer to the message %s", msg.Transfer.GroupHeader.MessageID)
+	}
+
+	return report, nil
+}
+
+func newPacs008(t Transfer) pacs008 {
+	now := formatDateTime(timex.Now())
+	msg := pacs008{
+		Transfer: customerCreditTransfer{
+			GroupHeader: groupHeader{
+				MessageID:        messageID(mock.MockBankISPB),
+				CreationDateTime: now,
+				NumberOfTxs:      1,
+				SettlementInfo:   &settlementInfo{Method: settlementMethodClearing},
+				PaymentTypeInfo:  &paymentTypeInfo{InstructionPriority: instructionPriorityHigh},
+			},
+			Transaction: creditTransferTxInf{
+				PaymentID:          paymentID{EndToEndID: t.EndToEndID},
+				Amount:             activeAmount{Currency: t.Currency, Value: t.Amount},
+				AcceptanceDateTime: now,
+				ChargeBearer:       chargeBearerShared,
+				Debtor:             party{ID: newPartyID(t.DebtorDocument)},
+				DebtorAccount: cashAccount{
+					ID:   otherID{ID: t.DebtorAccount.Number, Issuer: t.DebtorAccount.Branch},
+					Type: t.DebtorAccount.Type,
+				},
+				DebtorAgent:   agent{ISPB: t.DebtorAccount.ISPB},
+				CreditorAgent: agent{ISPB: t.CreditorAccount.ISPB},
+				Creditor:      party{Name: t.CreditorName, ID: newPartyID(t.CreditorDocument)},
+				CreditorAccount: cashAccount{
+					ID:    otherID{ID: t.CreditorAccount.Number, Issuer: t.CreditorAccount.Branch},
+					Type:  t.CreditorAccount.Type,
+					Proxy: t.Proxy,
+				},
+			},
+		},
+	}
+
+	if t.RemittanceInformation != "" {
+		msg.Transfer.Transaction.RemittanceInformation = &remittanceInf{Unstructured: t.RemittanceInformation}
+	}
+
+	return msg
+}
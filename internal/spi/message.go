@@ -0,0 +1,168 @@
+package spi
+
+import (
+	"crypto/rand"
+	"encoding/xml"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	messageNamePacs008 = "pacs.008.spi.1.8"
+	// settlementMethodClearing is the settlement method of every transfer
+	// cleared by the SPI.
+	settlementMethodClearing = "CLRG"
+	chargeBearerShared       = "SLEV"
+	instructionPriorityHigh  = "HIGH"
+	messageIDCharset         = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	messageIDRandomLength    = 23
+	cpfLength                = 11
+)
+
+// pacs008 is the FIToFICustomerCreditTransfer message the debtor agent sends
+// to the SPI to transfer funds to the creditor agent.
+type pacs008 struct {
+	XMLName  xml.Name               `xml:"urn:iso:std:iso:20022:tech:xsd:pacs.008.001.08 Document"`
+	Transfer customerCreditTransfer `xml:"FIToFICstmrCdtTrf"`
+}
+
+type customerCreditTransfer struct {
+	GroupHeader groupHeader         `xml:"GrpHdr"`
+	Transaction creditTransferTxInf `xml:"CdtTrfTxInf"`
+}
+
+type groupHeader struct {
+	MessageID        string           `xml:"MsgId"`
+	CreationDateTime string           `xml:"CreDtTm"`
+	NumberOfTxs      int              `xml:"NbOfTxs,omitempty"`
+	SettlementInfo   *settlementInfo  `xml:"SttlmInf,omitempty"`
+	PaymentTypeInfo  *paymentTypeInfo `xml:"PmtTpInf,omitempty"`
+}
+
+type settlementInfo struct {
+	Method string `xml:"SttlmMtd"`
+}
+
+type paymentTypeInfo struct {
+	InstructionPriority string `xml:"InstrPty"`
+}
+
+type creditTransferTxInf struct {
+	PaymentID             paymentID      `xml:"PmtId"`
+	Amount                activeAmount   `xml:"IntrBkSttlmAmt"`
+	AcceptanceDateTime    string         `xml:"AccptncDtTm"`
+	ChargeBearer          string         `xml:"ChrgBr"`
+	Debtor                party          `xml:"Dbtr"`
+	DebtorAccount         cashAccount    `xml:"DbtrAcct"`
+	DebtorAgent           agent          `xml:"DbtrAgt"`
+	CreditorAgent         agent          `xml:"CdtrAgt"`
+	Creditor              party          `xml:"Cdtr"`
+	CreditorAccount       cashAccount    `xml:"CdtrAcct"`
+	RemittanceInformation *remittanceInf `xml:"RmtInf,omitempty"`
+}
+
+type paymentID struct {
+	EndToEndID string `xml:"EndToEndId"`
+}
+
+type activeAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type party struct {
+	Name string   `xml:"Nm,omitempty"`
+	ID   *partyID `xml:"Id,omitempty"`
+}
+
+// partyID identifies a person by their CPF or an organisation by its CNPJ.
+type partyID struct {
+	Organisation *otherID `xml:"OrgId>Othr,omitempty"`
+	Private      *otherID `xml:"PrvtId>Othr,omitempty"`
+}
+
+type otherID struct {
+	ID     string `xml:"Id"`
+	Issuer string `xml:"Issr,omitempty"`
+}
+
+type cashAccount struct {
+	ID    otherID `xml:"Id>Othr"`
+	Type  string  `xml:"Tp>Cd,omitempty"`
+	Proxy string  `xml:"Prxy>Id,omitempty"`
+}
+
+type agent struct {
+	ISPB string `xml:"FinInstnId>ClrSysMmbId>MmbId"`
+}
+
+type remittanceInf struct {
+	Unstructured string `xml:"Ustrd"`
+}
+
+// pacs002 is the FIToFIPaymentStatusReport message the creditor agent replies
+// to a pacs.008 with, either accepting or rejecting the transfer.
+type pacs002 struct {
+	XMLName xml.Name            `xml:"urn:iso:std:iso:20022:tech:xsd:pacs.002.001.10 Document"`
+	Report  paymentStatusReport `xml:"FIToFIPmtStsRpt"`
+}
+
+type paymentStatusReport struct {
+	GroupHeader       groupHeader       `xml:"GrpHdr"`
+	OriginalGroupInfo originalGroupInfo `xml:"OrgnlGrpInfAndSts"`
+	TransactionInfo   transactionInfo   `xml:"TxInfAndSts"`
+}
+
+type originalGroupInfo struct {
+	MessageID   string `xml:"OrgnlMsgId"`
+	MessageName string `xml:"OrgnlMsgNmId"`
+}
+
+type transactionInfo struct {
+	OriginalEndToEndID string        `xml:"OrgnlEndToEndId"`
+	Status             txStatus      `xml:"TxSts"`
+	StatusReason       *statusReason `xml:"StsRsnInf,omitempty"`
+}
+
+type statusReason struct {
+	Code                  ReasonCode `xml:"Rsn>Cd"`
+	AdditionalInformation string     `xml:"AddtlInf,omitempty"`
+}
+
+type txStatus string
+
+const (
+	// txStatusAccepted means the creditor agent accepted the transfer and
+	// credited the creditor account.
+	txStatusAccepted txStatus = "ACSP"
+	txStatusRejected txStatus = "RJCT"
+)
+
+// messageID generates the ID of a message sent by the participant identified
+// by ispb, formatted as the SPI expects, e.g. "M58540569aBc...".
+func messageID(ispb string) string {
+	var sb strings.Builder
+	sb.WriteString("M" + ispb)
+	charsetLen := big.NewInt(int64(len(messageIDCharset)))
+	for range messageIDRandomLength {
+		n, _ := rand.Int(rand.Reader, charsetLen)
+		sb.WriteByte(messageIDCharset[n.Int64()])
+	}
+	return sb.String()
+}
+
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func newPartyID(document string) *partyID {
+	if document == "" {
+		return nil
+	}
+
+	if len(document) == cpfLength {
+		return &partyID{Private: &otherID{ID: document}}
+	}
+	return &partyID{Organisation: &otherID{ID: document}}
+}
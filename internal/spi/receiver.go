@@ -0,0 +1,111 @@
+package spi
+
+import (
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/luikyv/go-open-finance/internal/money"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+const (
+	// PathReceiver is where the fake receiving institution accepts pacs.008
+	// messages.
+	PathReceiver = "/spi/receiver/pacs.008"
+	// receiverISPB identifies the fake receiving institution in the messages
+	// it sends.
+	receiverISPB = "99999004"
+)
+
+// DefaultRejections are the transfers the fake receiving institution rejects
+// by default, keyed by amount.
+var DefaultRejections = map[string]ReasonCode{
+	"10444.00": ReasonCodeRejectedByCreditor,
+	"10466.00": ReasonCodeTimeout,
+}
+
+// ReceiverRouter is a fake receiving institution. It stands in for every
+// participant other than MockBank and replies to pacs.008 messages with
+// pacs.002 messages, accepting the transfers unless their amount is
+// configured to be rejected.
+type ReceiverRouter struct {
+	rejections map[string]ReasonCode
+}
+
+func NewReceiverRouter(rejections map[string]ReasonCode) ReceiverRouter {
+	return ReceiverRouter{
+		rejections: rejections,
+	}
+}
+
+func (router ReceiverRouter) Register(mux *http.ServeMux) {
+	mux.Handle("POST "+PathReceiver, router.transferHandler())
+}
+
+func (router ReceiverRouter) transferHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg pacs008
+		if err := xml.NewDecoder(io.LimitReader(r.Body, maxMessageBytes)).Decode(&msg); err != nil {
+			slog.InfoContext(r.Context(), "could not parse the pacs.008", slog.String("error", err.Error()))
+			http.Error(w, "invalid pacs.008", http.StatusBadRequest)
+			return
+		}
+
+		report := router.process(msg)
+		body, err := xml.Marshal(report)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentTypeXML)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(append([]byte(xml.Header), body...))
+	})
+}
+
+// process evaluates the transfer and builds the pacs.002 replying to it.
+func (router ReceiverRouter) process(msg pacs008) pacs002 {
+	tx := msg.Transfer.Transaction
+	info := transactionInfo{
+		OriginalEndToEndID: tx.PaymentID.EndToEndID,
+		Status:             txStatusAccepted,
+	}
+
+	if code, reason, ok := router.rejection(tx); ok {
+		info.Status = txStatusRejected
+		info.StatusReason = &statusReason{Code: code, AdditionalInformation: reason}
+	}
+
+	return pacs002{
+		Report: paymentStatusReport{
+			GroupHeader: groupHeader{
+				MessageID:        messageID(receiverISPB),
+				CreationDateTime: formatDateTime(timex.Now()),
+			},
+			OriginalGroupInfo: originalGroupInfo{
+				MessageID:   msg.Transfer.GroupHeader.MessageID,
+				MessageName: messageNamePacs008,
+			},
+			TransactionInfo: info,
+		},
+	}
+}
+
+func (router ReceiverRouter) rejection(tx creditTransferTxInf) (ReasonCode, string, bool) {
+	if cents, err := money.Parse(tx.Amount.Value); err != nil || cents <= 0 {
+		return ReasonCodeInvalidAmount, "the amount is invalid", true
+	}
+
+	if tx.CreditorAccount.ID.ID == "" {
+		return ReasonCodeInvalidCreditorAccount, "the creditor account is not informed", true
+	}
+
+	if code, ok := router.rejections[tx.Amount.Value]; ok {
+		return code, "the transfer was rejected by the receiving institution", true
+	}
+
+	return "", "", false
+}
@@ -0,0 +1,84 @@
+// Package spi simulates the Instant Payment System (Sistema de Pagamentos
+// Instantâneos) which settles the Pix payments initiated at MockBank.
+// Transfers are sent as ISO 20022 pacs.008 messages to a fake receiving
+// institution, which accepts or rejects them with a pacs.002 message.
+package spi
+
+import (
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+// Transfer is a Pix credit transfer from an account at MockBank to an account
+// at the receiving institution.
+type Transfer struct {
+	EndToEndID            string
+	Amount                string
+	Currency              string
+	DebtorDocument        string
+	DebtorAccount         Account
+	CreditorName          string
+	CreditorDocument      string
+	CreditorAccount       Account
+	Proxy                 string
+	RemittanceInformation string
+}
+
+type Account struct {
+	ISPB   string
+	Branch string
+	Number string
+	Type   string
+}
+
+// Settlement is the outcome of a transfer.
+type Settlement struct {
+	// MessageID identifies the pacs.008 sent for the transfer.
+	MessageID             string           `bson:"message_id"`
+	Status                SettlementStatus `bson:"status"`
+	ReasonCode            ReasonCode       `bson:"reason_code,omitempty"`
+	AdditionalInformation string           `bson:"additional_information,omitempty"`
+	// DateTime is when the outcome of the transfer is known by the debtor
+	// agent.
+	DateTime timex.DateTime `bson:"date_time"`
+}
+
+// IsDue returns true if the outcome of the transfer can be applied.
+func (s Settlement) IsDue() bool {
+	return !timex.Now().Before(s.DateTime.Time)
+}
+
+func (s Settlement) IsSettled() bool {
+	return s.Status == SettlementStatusSettled
+}
+
+type SettlementStatus string
+
+const (
+	SettlementStatusSettled  SettlementStatus = "ACSC"
+	SettlementStatusRejected SettlementStatus = "RJCT"
+)
+
+// ReasonCode is the ISO 20022 code informing why a transfer was rejected.
+type ReasonCode string
+
+const (
+	// ReasonCodeTimeout means the receiving institution did not reply in time.
+	ReasonCodeTimeout ReasonCode = "AB03"
+	// ReasonCodeCreditorAgentError means the receiving institution replied
+	// with an invalid message.
+	ReasonCodeCreditorAgentError     ReasonCode = "AB09"
+	ReasonCodeInvalidCreditorAccount ReasonCode = "AC03"
+	ReasonCodeBlockedAccount         ReasonCode = "AC06"
+	ReasonCodeTransactionForbidden   ReasonCode = "AG03"
+	ReasonCodeInvalidAmount          ReasonCode = "AM09"
+	ReasonCodeInconsistentCreditor   ReasonCode = "BE01"
+	ReasonCodeRejectedByCreditor     ReasonCode = "DS04"
+	ReasonCodeSettlementFailed       ReasonCode = "ED05"
+	ReasonCodeRegulatoryReason       ReasonCode = "RR04"
+)
+
+// IsInfrastructureFailure returns true if the transfer was rejected due to a
+// failure of the SPI or of the receiving institution rather than refused.
+func (c ReasonCode) IsInfrastructureFailure() bool {
+	return c == ReasonCodeTimeout || c == ReasonCodeCreditorAgentError || c == ReasonCodeSettlementFailed
+}
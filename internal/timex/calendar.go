@@ -0,0 +1,105 @@
+package timex
+
+import (
+	"time"
+)
+
+// extraHolidays are the holidays configured in addition to the national ones,
+// e.g. state or municipal holidays. It is keyed by the date formatted as
+// "2006-01-02".
+var extraHolidays = map[string]bool{}
+
+// AddHolidays configures dates as holidays in addition to the national ones.
+// It is not safe for concurrent use and must be called during start up.
+func AddHolidays(dates ...Date) {
+	for _, d := range dates {
+		extraHolidays[d.String()] = true
+	}
+}
+
+// IsHoliday returns true if d is a Brazilian national holiday or one of the
+// holidays configured with [AddHolidays].
+func IsHoliday(d Date) bool {
+	if extraHolidays[d.String()] {
+		return true
+	}
+
+	month, day := d.Month(), d.Day()
+	switch {
+	case month == time.January && day == 1, // Confraternização Universal.
+		month == time.April && day == 21,    // Tiradentes.
+		month == time.May && day == 1,       // Dia do Trabalho.
+		month == time.September && day == 7, // Independência do Brasil.
+		month == time.October && day == 12,  // Nossa Senhora Aparecida.
+		month == time.November && day == 2,  // Finados.
+		month == time.November && day == 15, // Proclamação da República.
+		month == time.December && day == 25: // Natal.
+		return true
+	case month == time.November && day == 20:
+		// Dia Nacional de Zumbi e da Consciência Negra became a national
+		// holiday in 2024.
+		return d.Year() >= 2024
+	}
+
+	// Moving holidays are defined relative to Easter.
+	switch daysFromEaster := int(d.Sub(easter(d.Year())).Hours() / 24); daysFromEaster {
+	case -48, -47: // Carnaval.
+		return true
+	case -2: // Sexta-feira Santa.
+		return true
+	case 60: // Corpus Christi.
+		return true
+	default:
+		return false
+	}
+}
+
+// IsBusinessDay returns true if d is neither a weekend day nor a holiday.
+func IsBusinessDay(d Date) bool {
+	if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		return false
+	}
+	return !IsHoliday(d)
+}
+
+// NextBusinessDay returns the first business day after d.
+func NextBusinessDay(d Date) Date {
+	return AddBusinessDays(d, 1)
+}
+
+// AddBusinessDays returns the date n business days after d. If n is negative,
+// the date is n business days before d. If n is zero, d is returned as is.
+func AddBusinessDays(d Date, n int) Date {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+
+	for n > 0 {
+		d = NewDate(d.AddDate(0, 0, step))
+		if IsBusinessDay(d) {
+			n--
+		}
+	}
+	return d
+}
+
+// easter returns the date of Easter Sunday in the Gregorian calendar using
+// the Meeus/Jones/Butcher algorithm.
+func easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,186 @@
+package timex
+
+import (
+	"testing"
+)
+
+func TestEaster(t *testing.T) {
+	testCases := []struct {
+		year int
+		want string
+	}{
+		{year: 2023, want: "2023-04-09"},
+		{year: 2024, want: "2024-03-31"},
+		{year: 2025, want: "2025-04-20"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			if got := NewDate(easter(tc.year)).String(); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsHoliday(t *testing.T) {
+	testCases := []struct {
+		name string
+		date string
+		want bool
+	}{
+		{name: "new year", date: "2025-01-01", want: true},
+		{name: "first day of carnaval", date: "2025-03-03", want: true},
+		{name: "second day of carnaval", date: "2025-03-04", want: true},
+		{name: "ash wednesday", date: "2025-03-05", want: false},
+		{name: "good friday", date: "2025-04-18", want: true},
+		{name: "tiradentes", date: "2025-04-21", want: true},
+		{name: "labour day", date: "2025-05-01", want: true},
+		{name: "corpus christi", date: "2025-06-19", want: true},
+		{name: "corpus christi of 2024", date: "2024-05-30", want: true},
+		{name: "independence day", date: "2025-09-07", want: true},
+		{name: "nossa senhora aparecida", date: "2025-10-12", want: true},
+		{name: "finados", date: "2025-11-02", want: true},
+		{name: "proclamation of the republic", date: "2025-11-15", want: true},
+		{name: "consciencia negra", date: "2024-11-20", want: true},
+		{name: "consciencia negra before 2024", date: "2023-11-20", want: false},
+		{name: "christmas", date: "2025-12-25", want: true},
+		{name: "regular day", date: "2025-06-18", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsHoliday(date(t, tc.date)); got != tc.want {
+				t.Errorf("got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsBusinessDay(t *testing.T) {
+	testCases := []struct {
+		name string
+		date string
+		want bool
+	}{
+		{name: "weekday", date: "2025-06-18", want: true},
+		{name: "saturday", date: "2025-06-21", want: false},
+		{name: "sunday", date: "2025-06-22", want: false},
+		{name: "holiday on a weekday", date: "2025-06-19", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsBusinessDay(date(t, tc.date)); got != tc.want {
+				t.Errorf("got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddBusinessDays(t *testing.T) {
+	testCases := []struct {
+		name string
+		date string
+		n    int
+		want string
+	}{
+		{
+			name: "next day",
+			date: "2025-06-17",
+			n:    1,
+			want: "2025-06-18",
+		},
+		{
+			// 2025-06-19 is Corpus Christi and is followed by the weekend.
+			name: "over a holiday and a weekend",
+			date: "2025-06-18",
+			n:    1,
+			want: "2025-06-20",
+		},
+		{
+			name: "over carnaval",
+			date: "2025-02-28",
+			n:    1,
+			want: "2025-03-05",
+		},
+		{
+			name: "several days",
+			date: "2025-04-17",
+			n:    3,
+			want: "2025-04-24",
+		},
+		{
+			name: "previous day over a weekend",
+			date: "2025-06-23",
+			n:    -1,
+			want: "2025-06-20",
+		},
+		{
+			name: "previous day over a holiday",
+			date: "2025-04-22",
+			n:    -1,
+			want: "2025-04-17",
+		},
+		{
+			// The date is returned as is even if it is not a business day.
+			name: "zero",
+			date: "2025-06-21",
+			n:    0,
+			want: "2025-06-21",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := AddBusinessDays(date(t, tc.date), tc.n).String(); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNextBusinessDay(t *testing.T) {
+	testCases := []struct {
+		name string
+		date string
+		want string
+	}{
+		{name: "business day", date: "2025-06-17", want: "2025-06-18"},
+		{name: "friday", date: "2025-06-13", want: "2025-06-16"},
+		{name: "christmas eve", date: "2025-12-24", want: "2025-12-26"},
+		{name: "new year's eve", date: "2024-12-31", want: "2025-01-02"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := NextBusinessDay(date(t, tc.date)).String(); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddHolidays(t *testing.T) {
+	// São Paulo's anniversary is a municipal holiday.
+	d := date(t, "2025-01-25")
+	t.Cleanup(func() { delete(extraHolidays, d.String()) })
+
+	if IsHoliday(d) {
+		t.Fatalf("%s should not be a national holiday", d)
+	}
+
+	AddHolidays(d)
+	if !IsHoliday(d) {
+		t.Errorf("expected %s to be a holiday once added", d)
+	}
+}
+
+func date(t *testing.T, s string) Date {
+	t.Helper()
+	d, err := ParseDate(s)
+	if err != nil {
+		t.Fatalf("invalid date %s: %v", s, err)
+	}
+	return d
+}
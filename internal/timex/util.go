@@ -3,6 +3,9 @@ package timex
 import (
 	"encoding/json"
 	"time"
+	// Embed the time zone database, so locations can be loaded regardless of
+	// the system the server runs on.
+	_ "time/tzdata"
 )
 
 var Second = time.Second
@@ -10,8 +13,40 @@ var Second = time.Second
 const (
 	dateTimeFormat = "2006-01-02T15:04:05Z"
 	dateFormat     = "2006-01-02"
+	// DefaultLocation is the time zone in which dates are booked unless
+	// configured otherwise with [SetLocation].
+	DefaultLocation = "America/Sao_Paulo"
 )
 
+// location is the time zone used to determine the date of an instant, e.g.
+// the booking date of a transaction.
+var location = mustLoadLocation(DefaultLocation)
+
+// SetLocation configures the time zone used to determine dates.
+// It is not safe for concurrent use and must be called during start up.
+func SetLocation(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return err
+	}
+
+	location = loc
+	return nil
+}
+
+// Location returns the time zone used to determine dates.
+func Location() *time.Location {
+	return location
+}
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
 type DateTime struct {
 	time.Time
 }
@@ -36,12 +71,15 @@ func (d *DateTime) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// String formats the date time in UTC regardless of its location.
 func (d DateTime) String() string {
-	return d.Time.Format(dateTimeFormat)
+	return d.Time.UTC().Format(dateTimeFormat)
 }
 
+// ToDate returns the date of d in the configured location, e.g. a transaction
+// made at 2025-01-02T01:00:00Z is booked on 2025-01-01 in São Paulo.
 func (d DateTime) ToDate() Date {
-	return NewDate(d.Time.Truncate(24 * time.Hour))
+	return NewDate(d.Time.In(location))
 }
 
 func NewDateTime(t time.Time) DateTime {
@@ -82,14 +120,19 @@ func (d Date) String() string {
 	return d.Time.Format(dateFormat)
 }
 
+// NewDate returns the calendar date of t as seen in the location of t.
+// Dates are always represented as midnight UTC, so they can be compared
+// regardless of where they came from.
 func NewDate(t time.Time) Date {
+	year, month, day := t.Date()
 	return Date{
-		Time: t.Truncate(24 * time.Hour),
+		Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC),
 	}
 }
 
+// DateNow returns the current date in the configured location.
 func DateNow() Date {
-	return NewDate(Now())
+	return NewDate(Now().In(location))
 }
 
 func ParseDate(s string) (Date, error) {
@@ -0,0 +1,136 @@
+package timex
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestToDate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		location string
+		dateTime time.Time
+		want     string
+	}{
+		{
+			// São Paulo is three hours behind UTC, so it is still the
+			// evening of the previous day.
+			name:     "before midnight in sao paulo",
+			location: DefaultLocation,
+			dateTime: time.Date(2025, time.January, 1, 1, 0, 0, 0, time.UTC),
+			want:     "2024-12-31",
+		},
+		{
+			name:     "after midnight in sao paulo",
+			location: DefaultLocation,
+			dateTime: time.Date(2025, time.January, 1, 3, 0, 0, 0, time.UTC),
+			want:     "2025-01-01",
+		},
+		{
+			name:     "utc",
+			location: "UTC",
+			dateTime: time.Date(2025, time.January, 1, 1, 0, 0, 0, time.UTC),
+			want:     "2025-01-01",
+		},
+		{
+			// Tokyo is nine hours ahead of UTC.
+			name:     "ahead of utc",
+			location: "Asia/Tokyo",
+			dateTime: time.Date(2024, time.December, 31, 16, 0, 0, 0, time.UTC),
+			want:     "2025-01-01",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setLocation(t, tc.location)
+			if got := NewDateTime(tc.dateTime).ToDate().String(); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewDate(t *testing.T) {
+	saoPaulo, err := time.LoadLocation(DefaultLocation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name string
+		t    time.Time
+		want string
+	}{
+		{
+			name: "utc",
+			t:    time.Date(2025, time.January, 1, 1, 0, 0, 0, time.UTC),
+			want: "2025-01-01",
+		},
+		{
+			// The date is taken in the location of the time.
+			name: "sao paulo",
+			t:    time.Date(2025, time.January, 1, 1, 0, 0, 0, time.UTC).In(saoPaulo),
+			want: "2024-12-31",
+		},
+		{
+			name: "last instant of the day",
+			t:    time.Date(2024, time.December, 31, 23, 59, 59, 999999999, saoPaulo),
+			want: "2024-12-31",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDate(tc.t)
+			if got := d.String(); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+
+			// Dates are always represented as midnight UTC.
+			if d.Location() != time.UTC || d.Hour() != 0 || d.Minute() != 0 || d.Second() != 0 || d.Nanosecond() != 0 {
+				t.Errorf("got %v, want midnight in UTC", d.Time)
+			}
+		})
+	}
+}
+
+func TestDateTime_MarshalJSON(t *testing.T) {
+	setLocation(t, DefaultLocation)
+
+	// The date time is informed in UTC regardless of the configured location.
+	saoPaulo := Location()
+	dateTime := NewDateTime(time.Date(2024, time.December, 31, 22, 0, 0, 0, saoPaulo))
+	got, err := json.Marshal(dateTime)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := `"2025-01-01T01:00:00Z"`; string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestSetLocation_Invalid(t *testing.T) {
+	setLocation(t, DefaultLocation)
+
+	if err := SetLocation("Nowhere/Invalid"); err == nil {
+		t.Fatal("expected an error for an invalid location")
+	}
+
+	if got := Location().String(); got != DefaultLocation {
+		t.Errorf("got location %s, want %s", got, DefaultLocation)
+	}
+}
+
+// setLocation configures the location for the duration of the test.
+func setLocation(t *testing.T, name string) {
+	t.Helper()
+	previous := location
+	t.Cleanup(func() { location = previous })
+
+	if err := SetLocation(name); err != nil {
+		t.Fatalf("invalid location %s: %v", name, err)
+	}
+}
@@ -5,13 +5,20 @@ import (
 )
 
 type User struct {
-	UserName        string
-	Email           string
-	CPF             string
-	Name            string
-	AccountID       string
-	CreditAccountID string
-	CompanyCNPJs    []string
+	UserName               string
+	Email                  string
+	CPF                    string
+	Name                   string
+	AccountID              string
+	CreditAccountID        string
+	LoanIDs                []string
+	FinancingIDs           []string
+	UnarrangedOverdraftIDs []string
+	BankFixedIncomeIDs     []string
+	CompanyCNPJs           []string
+	// InvoiceFinancingIDs are the invoice financing contracts of the companies
+	// of the user indexed by CNPJ.
+	InvoiceFinancingIDs map[string][]string
 }
 
 func (u User) OwnsCompany(cnpj string) bool {
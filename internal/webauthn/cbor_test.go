@@ -0,0 +1,107 @@
+package webauthn
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	// Examples from RFC 8949, Appendix A.
+	testCases := []struct {
+		hex  string
+		want any
+	}{
+		{"00", int64(0)},
+		{"01", int64(1)},
+		{"0a", int64(10)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"1903e8", int64(1000)},
+		{"1a000f4240", int64(1000000)},
+		{"1b000000e8d4a51000", int64(1000000000000)},
+		{"20", int64(-1)},
+		{"29", int64(-10)},
+		{"3863", int64(-100)},
+		{"3903e7", int64(-1000)},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"fa47c35000", float64(100000.0)},
+		{"fb3ff199999999999a", 1.1},
+		{"c11a514b67b0", int64(1363896240)},
+		{"40", []byte{}},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"60", ""},
+		{"6161", "a"},
+		{"6449455446", "IETF"},
+		{"62c3bc", "ü"},
+		{"80", []any{}},
+		{"83010203", []any{int64(1), int64(2), int64(3)}},
+		{"8301820203820405", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{"a0", map[any]any{}},
+		{"a201020304", map[any]any{int64(1): int64(2), int64(3): int64(4)}},
+		{"a26161016162820203", map[any]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.hex, func(t *testing.T) {
+			b, _ := hex.DecodeString(tc.hex)
+			got, n, err := decodeCBOR(b)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if n != len(b) {
+				t.Errorf("got %d bytes read, want %d", n, len(b))
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCBOR_TrailingBytes(t *testing.T) {
+	// Only the first item is decoded, which is how the credential public key
+	// is separated from the extensions in the authenticator data.
+	b, _ := hex.DecodeString("a20102030401")
+	_, n, err := decodeCBOR(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n != 5 {
+		t.Errorf("got %d bytes read, want 5", n)
+	}
+}
+
+func TestDecodeCBOR_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		hex  string
+	}{
+		{"empty", ""},
+		{"truncated integer", "1a0000"},
+		{"truncated byte string", "44010203"},
+		{"indefinite length byte string", "5f42010243030405ff"},
+		{"indefinite length array", "9f0102ff"},
+		{"array longer than the data", "9a0000ffff01"},
+		{"map with a boolean key", "a1f4f5"},
+		{"map missing a value", "a101"},
+		{"unsigned integer overflowing int64", "1bffffffffffffffff"},
+		{"unassigned simple value", "f0"},
+		{"too deep", strings.Repeat("81", cborMaxDepth+1) + "01"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(tc.hex)
+			if _, _, err := decodeCBOR(b); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
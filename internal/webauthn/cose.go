@@ -0,0 +1,142 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// COSE algorithms supported for credentials.
+// See https://www.iana.org/assignments/cose/cose.xhtml#algorithms.
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgPS256 int64 = -37
+	AlgRS256 int64 = -257
+)
+
+// SupportedAlgs are the COSE algorithms accepted for credentials in order of
+// preference.
+var SupportedAlgs = []int64{AlgES256, AlgPS256, AlgRS256, AlgEdDSA}
+
+const (
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+
+	coseLabelKeyType = 1
+	coseLabelAlg     = 3
+	// EC2 and OKP keys.
+	coseLabelCurve = -1
+	coseLabelX     = -2
+	coseLabelY     = -3
+	// RSA keys.
+	coseLabelN = -1
+	coseLabelE = -2
+)
+
+var (
+	ErrUnsupportedKey   = errors.New("the credential public key is not supported")
+	ErrInvalidSignature = errors.New("the signature is invalid")
+)
+
+// publicKey is a credential public key decoded from its COSE representation.
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parsePublicKey decodes a COSE_Key.
+func parsePublicKey(coseKey []byte) (publicKey, error) {
+	v, _, err := decodeCBOR(coseKey)
+	if err != nil {
+		return publicKey{}, ErrUnsupportedKey
+	}
+
+	m, ok := v.(map[any]any)
+	if !ok {
+		return publicKey{}, ErrUnsupportedKey
+	}
+
+	kty, _ := m[int64(coseLabelKeyType)].(int64)
+	alg, _ := m[int64(coseLabelAlg)].(int64)
+	switch {
+	case kty == coseKeyTypeEC2 && alg == AlgES256:
+		crv, _ := m[int64(coseLabelCurve)].(int64)
+		x, _ := m[int64(coseLabelX)].([]byte)
+		y, _ := m[int64(coseLabelY)].([]byte)
+		if crv != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return publicKey{}, ErrUnsupportedKey
+		}
+
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return publicKey{}, ErrUnsupportedKey
+		}
+		return publicKey{alg: alg, key: key}, nil
+	case kty == coseKeyTypeRSA && (alg == AlgRS256 || alg == AlgPS256):
+		n, _ := m[int64(coseLabelN)].([]byte)
+		e, _ := m[int64(coseLabelE)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return publicKey{}, ErrUnsupportedKey
+		}
+
+		key := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		return publicKey{alg: alg, key: key}, nil
+	case kty == coseKeyTypeOKP && alg == AlgEdDSA:
+		crv, _ := m[int64(coseLabelCurve)].(int64)
+		x, _ := m[int64(coseLabelX)].([]byte)
+		if crv != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return publicKey{}, ErrUnsupportedKey
+		}
+		return publicKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+	default:
+		return publicKey{}, ErrUnsupportedKey
+	}
+}
+
+// verifySignature verifies sig was generated over data with the private key
+// corresponding to key using alg.
+func verifySignature(alg int64, key crypto.PublicKey, data, sig []byte) error {
+	digest := sha256.Sum256(data)
+	switch alg {
+	case AlgES256:
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok || !ecdsa.VerifyASN1(k, digest[:], sig) {
+			return ErrInvalidSignature
+		}
+	case AlgRS256:
+		k, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) != nil {
+			return ErrInvalidSignature
+		}
+	case AlgPS256:
+		k, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPSS(k, crypto.SHA256, digest[:], sig, nil) != nil {
+			return ErrInvalidSignature
+		}
+	case AlgEdDSA:
+		k, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(k, data, sig) {
+			return ErrInvalidSignature
+		}
+	default:
+		return ErrUnsupportedKey
+	}
+	return nil
+}
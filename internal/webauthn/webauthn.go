@@ -0,0 +1,319 @@
+// Package webauthn verifies the registration and authentication ceremonies
+// of FIDO2 authenticators as described in https://www.w3.org/TR/webauthn-2/.
+// Attestations are accepted in the "none" and "packed" formats. Certificate
+// chains are not validated, so software authenticators can be used.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+const (
+	challengeLength = 32
+
+	clientDataTypeCreate = "webauthn.create"
+	clientDataTypeGet    = "webauthn.get"
+
+	attestationFormatNone   = "none"
+	attestationFormatPacked = "packed"
+
+	flagUserPresent        = 0x01
+	flagUserVerified       = 0x04
+	flagAttestedCredential = 0x40
+	// authDataMinLength is the length of the rp id hash, flags and sign count.
+	authDataMinLength = 37
+	aaguidLength      = 16
+)
+
+var (
+	ErrInvalidClientData   = errors.New("the client data is invalid")
+	ErrInvalidChallenge    = errors.New("the challenge does not match")
+	ErrInvalidRelyingParty = errors.New("the relying party does not match")
+	ErrInvalidOrigin       = errors.New("the origin does not belong to the relying party")
+	ErrUserNotPresent      = errors.New("the user was not present")
+	ErrUserNotVerified     = errors.New("the user was not verified")
+	ErrInvalidAuthData     = errors.New("the authenticator data is invalid")
+	ErrInvalidAttestation  = errors.New("the attestation is invalid")
+	ErrInvalidSignCount    = errors.New("the sign count did not increase")
+)
+
+// Credential is a public key credential registered by an authenticator.
+type Credential struct {
+	// ID is the base64url encoded credential ID.
+	ID string `bson:"id"`
+	// PublicKey is the COSE encoded public key of the credential.
+	PublicKey []byte `bson:"public_key"`
+	SignCount uint32 `bson:"sign_count"`
+}
+
+// AttestationResponse is the response of an authenticator to the creation of
+// a credential.
+type AttestationResponse struct {
+	ClientDataJSON    []byte
+	AttestationObject []byte
+}
+
+// AssertionResponse is the response of an authenticator to a request for
+// signing a challenge.
+type AssertionResponse struct {
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+}
+
+// NewChallenge generates a random base64url encoded challenge.
+func NewChallenge() string {
+	b := make([]byte, challengeLength)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeBase64URL decodes values sent by WebAuthn clients, which may or may
+// not be padded.
+func DecodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// VerifyRegistration validates the attestation generated by an authenticator
+// for challenge and returns the credential created.
+func VerifyRegistration(rpID, challenge string, resp AttestationResponse) (Credential, error) {
+	if err := verifyClientData(resp.ClientDataJSON, clientDataTypeCreate, rpID, challenge); err != nil {
+		return Credential{}, err
+	}
+
+	v, _, err := decodeCBOR(resp.AttestationObject)
+	if err != nil {
+		return Credential{}, ErrInvalidAttestation
+	}
+	attObj, ok := v.(map[any]any)
+	if !ok {
+		return Credential{}, ErrInvalidAttestation
+	}
+	format, _ := attObj["fmt"].(string)
+	attStmt, _ := attObj["attStmt"].(map[any]any)
+	rawAuthData, _ := attObj["authData"].([]byte)
+	if attStmt == nil {
+		return Credential{}, ErrInvalidAttestation
+	}
+
+	authData, err := parseAuthData(rawAuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	if err := authData.verify(rpID); err != nil {
+		return Credential{}, err
+	}
+
+	if authData.flags&flagAttestedCredential == 0 {
+		return Credential{}, ErrInvalidAuthData
+	}
+
+	key, err := parsePublicKey(authData.publicKey)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	clientDataHash := sha256.Sum256(resp.ClientDataJSON)
+	signed := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+	if err := verifyAttestationStatement(format, attStmt, key, signed); err != nil {
+		return Credential{}, err
+	}
+
+	return Credential{
+		ID:        base64.RawURLEncoding.EncodeToString(authData.credentialID),
+		PublicKey: authData.publicKey,
+		SignCount: authData.signCount,
+	}, nil
+}
+
+// VerifyAssertion validates the signature generated by the authenticator of
+// cred over challenge and returns the new sign count of the credential.
+func VerifyAssertion(rpID, challenge string, cred Credential, resp AssertionResponse) (uint32, error) {
+	if err := verifyClientData(resp.ClientDataJSON, clientDataTypeGet, rpID, challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := parseAuthData(resp.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := authData.verify(rpID); err != nil {
+		return 0, err
+	}
+
+	key, err := parsePublicKey(cred.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(resp.ClientDataJSON)
+	signed := append(append([]byte(nil), resp.AuthenticatorData...), clientDataHash[:]...)
+	if err := verifySignature(key.alg, key.key, signed, resp.Signature); err != nil {
+		return 0, err
+	}
+
+	// Authenticators that don't implement a counter always send zero.
+	if (authData.signCount != 0 || cred.SignCount != 0) && authData.signCount <= cred.SignCount {
+		return 0, ErrInvalidSignCount
+	}
+
+	return authData.signCount, nil
+}
+
+func verifyClientData(clientDataJSON []byte, ceremonyType, rpID, challenge string) error {
+	var clientData struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Origin    string `json:"origin"`
+	}
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return ErrInvalidClientData
+	}
+
+	if clientData.Type != ceremonyType {
+		return ErrInvalidClientData
+	}
+
+	if strings.TrimRight(clientData.Challenge, "=") != strings.TrimRight(challenge, "=") {
+		return ErrInvalidChallenge
+	}
+
+	return verifyOrigin(clientData.Origin, rpID)
+}
+
+// verifyOrigin checks the origin is the relying party or one of its
+// subdomains. Only https origins are accepted, except for localhost.
+func verifyOrigin(origin, rpID string) error {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return ErrInvalidOrigin
+	}
+
+	host := u.Hostname()
+	if u.Scheme != "https" && !(u.Scheme == "http" && host == "localhost") {
+		return ErrInvalidOrigin
+	}
+
+	if host != rpID && !strings.HasSuffix(host, "."+rpID) {
+		return ErrInvalidOrigin
+	}
+
+	return nil
+}
+
+func verifyAttestationStatement(format string, attStmt map[any]any, credKey publicKey, signed []byte) error {
+	switch format {
+	case attestationFormatNone:
+		if len(attStmt) != 0 {
+			return ErrInvalidAttestation
+		}
+		return nil
+	case attestationFormatPacked:
+		alg, _ := attStmt["alg"].(int64)
+		sig, _ := attStmt["sig"].([]byte)
+		if sig == nil {
+			return ErrInvalidAttestation
+		}
+
+		x5c, ok := attStmt["x5c"].([]any)
+		// Self attestation, the statement is signed with the credential key.
+		if !ok {
+			if alg != credKey.alg {
+				return ErrInvalidAttestation
+			}
+			return verifySignature(alg, credKey.key, signed, sig)
+		}
+
+		// Only the attestation certificate is used, its chain is not
+		// validated.
+		if len(x5c) == 0 {
+			return ErrInvalidAttestation
+		}
+		der, _ := x5c[0].([]byte)
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return ErrInvalidAttestation
+		}
+		return verifySignature(alg, cert.PublicKey, signed, sig)
+	default:
+		return ErrInvalidAttestation
+	}
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	// publicKey is the COSE encoded key of the credential. It is only present
+	// during registration.
+	publicKey []byte
+}
+
+func parseAuthData(b []byte) (authenticatorData, error) {
+	if len(b) < authDataMinLength {
+		return authenticatorData{}, ErrInvalidAuthData
+	}
+
+	authData := authenticatorData{
+		rpIDHash:  b[:32],
+		flags:     b[32],
+		signCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+
+	if authData.flags&flagAttestedCredential == 0 {
+		return authData, nil
+	}
+
+	rest := b[authDataMinLength:]
+	if len(rest) < aaguidLength+2 {
+		return authenticatorData{}, ErrInvalidAuthData
+	}
+	rest = rest[aaguidLength:]
+
+	idLength := int(binary.BigEndian.Uint16(rest[:2]))
+	rest = rest[2:]
+	if idLength == 0 || len(rest) < idLength {
+		return authenticatorData{}, ErrInvalidAuthData
+	}
+	authData.credentialID = rest[:idLength]
+	rest = rest[idLength:]
+
+	// The public key is followed by the extensions, if any, so its length is
+	// only known after decoding it.
+	_, n, err := decodeCBOR(rest)
+	if err != nil {
+		return authenticatorData{}, ErrInvalidAuthData
+	}
+	authData.publicKey = rest[:n]
+
+	return authData, nil
+}
+
+func (a authenticatorData) verify(rpID string) error {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	if !bytes.Equal(a.rpIDHash, rpIDHash[:]) {
+		return ErrInvalidRelyingParty
+	}
+
+	if a.flags&flagUserPresent == 0 {
+		return ErrUserNotPresent
+	}
+
+	if a.flags&flagUserVerified == 0 {
+		return ErrUserNotVerified
+	}
+
+	return nil
+}
@@ -0,0 +1,511 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+)
+
+const (
+	testRPID      = "tpp.example.com"
+	testOrigin    = "https://tpp.example.com"
+	testChallenge = "Y2hhbGxlbmdlLWlzc3VlZC1ieS10aGUtc2VydmVy"
+
+	flagsValid = flagUserPresent | flagUserVerified
+)
+
+const (
+	formatNone       = "none"
+	formatPackedSelf = "packed"
+	formatPackedX5C  = "packed-x5c"
+)
+
+func TestVerifyRegistration(t *testing.T) {
+	testCases := []struct {
+		name    string
+		format  string
+		modify  func(*ceremony)
+		wantErr error
+	}{
+		{
+			name:   "none attestation",
+			format: formatNone,
+		},
+		{
+			name:   "packed self attestation",
+			format: formatPackedSelf,
+		},
+		{
+			name:   "packed attestation with certificate",
+			format: formatPackedX5C,
+		},
+		{
+			name:   "origin is a subdomain of the relying party",
+			format: formatNone,
+			modify: func(c *ceremony) { c.origin = "https://app.tpp.example.com" },
+		},
+		{
+			name:   "localhost over http",
+			format: formatNone,
+			modify: func(c *ceremony) {
+				c.rpID = "localhost"
+				c.authDataRPID = "localhost"
+				c.origin = "http://localhost:8080"
+			},
+		},
+		{
+			name:    "rp id hash of another relying party",
+			format:  formatPackedSelf,
+			modify:  func(c *ceremony) { c.authDataRPID = "evil.example.com" },
+			wantErr: ErrInvalidRelyingParty,
+		},
+		{
+			name:    "wrong challenge",
+			format:  formatPackedSelf,
+			modify:  func(c *ceremony) { c.challenge = NewChallenge() },
+			wantErr: ErrInvalidChallenge,
+		},
+		{
+			name:    "assertion client data",
+			format:  formatNone,
+			modify:  func(c *ceremony) { c.clientDataType = clientDataTypeGet },
+			wantErr: ErrInvalidClientData,
+		},
+		{
+			name:    "origin of another site",
+			format:  formatNone,
+			modify:  func(c *ceremony) { c.origin = "https://evil.example.com" },
+			wantErr: ErrInvalidOrigin,
+		},
+		{
+			name:    "origin sharing the suffix of the relying party",
+			format:  formatNone,
+			modify:  func(c *ceremony) { c.origin = "https://eviltpp.example.com" },
+			wantErr: ErrInvalidOrigin,
+		},
+		{
+			name:    "origin over http",
+			format:  formatNone,
+			modify:  func(c *ceremony) { c.origin = "http://tpp.example.com" },
+			wantErr: ErrInvalidOrigin,
+		},
+		{
+			name:    "missing origin",
+			format:  formatNone,
+			modify:  func(c *ceremony) { c.origin = "" },
+			wantErr: ErrInvalidOrigin,
+		},
+		{
+			name:    "user not present",
+			format:  formatNone,
+			modify:  func(c *ceremony) { c.flags = flagUserVerified },
+			wantErr: ErrUserNotPresent,
+		},
+		{
+			name:    "user not verified",
+			format:  formatNone,
+			modify:  func(c *ceremony) { c.flags = flagUserPresent },
+			wantErr: ErrUserNotVerified,
+		},
+		{
+			name:    "tampered packed signature",
+			format:  formatPackedSelf,
+			modify:  func(c *ceremony) { c.tamperSignature = true },
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "tampered certificate signature",
+			format:  formatPackedX5C,
+			modify:  func(c *ceremony) { c.tamperSignature = true },
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "none attestation with a statement",
+			format:  formatNone,
+			modify:  func(c *ceremony) { c.noneStatement = true },
+			wantErr: ErrInvalidAttestation,
+		},
+		{
+			name:    "unsupported format",
+			format:  "fido-u2f",
+			wantErr: ErrInvalidAttestation,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newTestAuthenticator(t)
+			c := newCeremony(clientDataTypeCreate)
+			if tc.modify != nil {
+				tc.modify(&c)
+			}
+
+			cred, err := VerifyRegistration(c.rpID, testChallenge, a.attest(t, c, tc.format))
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if cred.ID != base64.RawURLEncoding.EncodeToString(a.credentialID) {
+				t.Errorf("got credential id %s", cred.ID)
+			}
+
+			if !bytes.Equal(cred.PublicKey, a.coseKey()) {
+				t.Error("the credential public key does not match the authenticator key")
+			}
+		})
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	testCases := []struct {
+		name            string
+		storedSignCount uint32
+		modify          func(*ceremony)
+		wantErr         error
+		wantSignCount   uint32
+	}{
+		{
+			name:            "valid assertion",
+			storedSignCount: 5,
+			modify:          func(c *ceremony) { c.signCount = 6 },
+			wantSignCount:   6,
+		},
+		{
+			name:            "authenticator without counter",
+			storedSignCount: 0,
+			modify:          func(c *ceremony) { c.signCount = 0 },
+			wantSignCount:   0,
+		},
+		{
+			name:            "sign count not increased",
+			storedSignCount: 5,
+			modify:          func(c *ceremony) { c.signCount = 5 },
+			wantErr:         ErrInvalidSignCount,
+		},
+		{
+			name:            "sign count regression",
+			storedSignCount: 5,
+			modify:          func(c *ceremony) { c.signCount = 3 },
+			wantErr:         ErrInvalidSignCount,
+		},
+		{
+			name:            "counter reset to zero",
+			storedSignCount: 5,
+			modify:          func(c *ceremony) { c.signCount = 0 },
+			wantErr:         ErrInvalidSignCount,
+		},
+		{
+			name:    "rp id hash of another relying party",
+			modify:  func(c *ceremony) { c.authDataRPID = "evil.example.com" },
+			wantErr: ErrInvalidRelyingParty,
+		},
+		{
+			name:    "wrong challenge",
+			modify:  func(c *ceremony) { c.challenge = NewChallenge() },
+			wantErr: ErrInvalidChallenge,
+		},
+		{
+			name:    "registration client data",
+			modify:  func(c *ceremony) { c.clientDataType = clientDataTypeCreate },
+			wantErr: ErrInvalidClientData,
+		},
+		{
+			name:    "origin of another site",
+			modify:  func(c *ceremony) { c.origin = "https://evil.example.com" },
+			wantErr: ErrInvalidOrigin,
+		},
+		{
+			name:    "user not verified",
+			modify:  func(c *ceremony) { c.flags = flagUserPresent },
+			wantErr: ErrUserNotVerified,
+		},
+		{
+			name:    "tampered signature",
+			modify:  func(c *ceremony) { c.tamperSignature = true },
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "signed by another credential",
+			modify:  func(c *ceremony) { c.signer = newTestAuthenticator(t) },
+			wantErr: ErrInvalidSignature,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newTestAuthenticator(t)
+			cred := Credential{
+				ID:        base64.RawURLEncoding.EncodeToString(a.credentialID),
+				PublicKey: a.coseKey(),
+				SignCount: tc.storedSignCount,
+			}
+			c := newCeremony(clientDataTypeGet)
+			if tc.modify != nil {
+				tc.modify(&c)
+			}
+
+			signCount, err := VerifyAssertion(c.rpID, testChallenge, cred, a.assert(c))
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if signCount != tc.wantSignCount {
+				t.Errorf("got sign count %d, want %d", signCount, tc.wantSignCount)
+			}
+		})
+	}
+}
+
+// ceremony describes what an authenticator and the client signed, so each
+// test case can tamper with one of its parts.
+type ceremony struct {
+	rpID           string
+	authDataRPID   string
+	clientDataType string
+	challenge      string
+	origin         string
+	flags          byte
+	signCount      uint32
+	// signer is the authenticator signing the assertion, if not the one
+	// which owns the credential.
+	signer          *testAuthenticator
+	tamperSignature bool
+	noneStatement   bool
+}
+
+func newCeremony(clientDataType string) ceremony {
+	return ceremony{
+		rpID:           testRPID,
+		authDataRPID:   testRPID,
+		clientDataType: clientDataType,
+		challenge:      testChallenge,
+		origin:         testOrigin,
+		flags:          flagsValid,
+	}
+}
+
+func (c ceremony) clientDataJSON() []byte {
+	b, _ := json.Marshal(map[string]string{
+		"type":      c.clientDataType,
+		"challenge": c.challenge,
+		"origin":    c.origin,
+	})
+	return b
+}
+
+// testAuthenticator is a software authenticator with an ES256 credential.
+type testAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return &testAuthenticator{key: key, credentialID: id}
+}
+
+func (a *testAuthenticator) coseKey() []byte {
+	return encodeCBOR(cborMap{
+		{int64(coseLabelKeyType), int64(coseKeyTypeEC2)},
+		{int64(coseLabelAlg), AlgES256},
+		{int64(coseLabelCurve), int64(coseCurveP256)},
+		{int64(coseLabelX), a.key.X.FillBytes(make([]byte, 32))},
+		{int64(coseLabelY), a.key.Y.FillBytes(make([]byte, 32))},
+	})
+}
+
+func (a *testAuthenticator) authData(c ceremony, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(c.authDataRPID))
+	flags := c.flags
+	if attested {
+		flags |= flagAttestedCredential
+	}
+
+	b := append(rpIDHash[:], flags)
+	b = binary.BigEndian.AppendUint32(b, c.signCount)
+	if !attested {
+		return b
+	}
+
+	b = append(b, make([]byte, aaguidLength)...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(a.credentialID)))
+	b = append(b, a.credentialID...)
+	return append(b, a.coseKey()...)
+}
+
+// attest creates the attestation of the credential in the format informed.
+func (a *testAuthenticator) attest(t *testing.T, c ceremony, format string) AttestationResponse {
+	t.Helper()
+	clientDataJSON := c.clientDataJSON()
+	authData := a.authData(c, true)
+	signed := signedData(authData, clientDataJSON)
+
+	var attStmt cborMap
+	switch format {
+	case formatNone:
+		if c.noneStatement {
+			attStmt = cborMap{{"alg", AlgES256}}
+		}
+	case formatPackedSelf:
+		attStmt = cborMap{
+			{"alg", AlgES256},
+			{"sig", sign(a.key, signed, c.tamperSignature)},
+		}
+	case formatPackedX5C:
+		attKey, der := newAttestationCertificate(t)
+		attStmt = cborMap{
+			{"alg", AlgES256},
+			{"sig", sign(attKey, signed, c.tamperSignature)},
+			{"x5c", []any{der}},
+		}
+		format = attestationFormatPacked
+	}
+
+	return AttestationResponse{
+		ClientDataJSON: clientDataJSON,
+		AttestationObject: encodeCBOR(cborMap{
+			{"fmt", format},
+			{"attStmt", attStmt},
+			{"authData", authData},
+		}),
+	}
+}
+
+func (a *testAuthenticator) assert(c ceremony) AssertionResponse {
+	signer := a
+	if c.signer != nil {
+		signer = c.signer
+	}
+
+	clientDataJSON := c.clientDataJSON()
+	authData := a.authData(c, false)
+	return AssertionResponse{
+		ClientDataJSON:    clientDataJSON,
+		AuthenticatorData: authData,
+		Signature:         sign(signer.key, signedData(authData, clientDataJSON), c.tamperSignature),
+	}
+}
+
+func signedData(authData, clientDataJSON []byte) []byte {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	return append(append([]byte(nil), authData...), clientDataHash[:]...)
+}
+
+func sign(key *ecdsa.PrivateKey, data []byte, tamper bool) []byte {
+	digest := sha256.Sum256(data)
+	sig, _ := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if tamper {
+		sig[len(sig)-1] ^= 0xff
+	}
+	return sig
+}
+
+// newAttestationCertificate generates the key and the self signed certificate
+// of an authenticator model.
+func newAttestationCertificate(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			Organization:       []string{"Test Authenticator"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			CommonName:         "Test Authenticator Attestation",
+		},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, der
+}
+
+type cborPair struct {
+	key   any
+	value any
+}
+
+// cborMap is a CBOR map whose keys are encoded in order.
+type cborMap []cborPair
+
+// encodeCBOR encodes the subset of CBOR used in the tests.
+func encodeCBOR(v any) []byte {
+	switch v := v.(type) {
+	case int64:
+		if v >= 0 {
+			return cborHead(cborTypeUnsigned, uint64(v))
+		}
+		return cborHead(cborTypeNegative, uint64(-1-v))
+	case []byte:
+		return append(cborHead(cborTypeBytes, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(cborTypeText, uint64(len(v))), v...)
+	case []any:
+		b := cborHead(cborTypeArray, uint64(len(v)))
+		for _, item := range v {
+			b = append(b, encodeCBOR(item)...)
+		}
+		return b
+	case cborMap:
+		b := cborHead(cborTypeMap, uint64(len(v)))
+		for _, pair := range v {
+			b = append(b, encodeCBOR(pair.key)...)
+			b = append(b, encodeCBOR(pair.value)...)
+		}
+		return b
+	default:
+		panic("unsupported cbor value")
+	}
+}
+
+func cborHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= math.MaxUint8:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	default:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	}
+}
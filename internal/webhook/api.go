@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/luikyv/go-oidc/pkg/provider"
+	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/api/middleware"
+	"github.com/luikyv/go-open-finance/internal/page"
+)
+
+// PathPrefixWebhooks is where the notifications sent to the clients can be
+// inspected.
+const PathPrefixWebhooks = "/webhooks"
+
+// APIRouter serves the webhook notifications of the outbox, so the webhook
+// receivers of the clients can be tested end to end.
+// Clients authenticate with any of their tokens and only see the notifications
+// sent to them.
+type APIRouter struct {
+	service Service
+	op      *provider.Provider
+}
+
+func NewAPIRouter(service Service, op *provider.Provider) APIRouter {
+	return APIRouter{
+		service: service,
+		op:      op,
+	}
+}
+
+func (router APIRouter) Register(mux *http.ServeMux) {
+	handler := router.notificationsHandler()
+	handler = middleware.AuthScopes(handler, router.op)
+	mux.Handle("GET "+PathPrefixWebhooks+"/notifications", handler)
+
+	handler = router.notificationHandler()
+	handler = middleware.AuthScopes(handler, router.op)
+	mux.Handle("GET "+PathPrefixWebhooks+"/notifications/{id}", handler)
+}
+
+func (router APIRouter) notificationsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID := r.Context().Value(api.CtxKeyClientID).(string)
+		pag, err := api.NewPagination(r)
+		if err != nil {
+			api.WriteError(w, api.NewError("INVALID_REQUEST", http.StatusBadRequest, err.Error()))
+			return
+		}
+
+		ns, err := router.service.notifications(r.Context(), clientID, pag)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		api.WriteJSON(w, toNotificationsResponse(ns), http.StatusOK)
+	})
+}
+
+func (router APIRouter) notificationHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID := r.Context().Value(api.CtxKeyClientID).(string)
+		n, err := router.service.notification(r.Context(), r.PathValue("id"), clientID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		api.WriteJSON(w, notificationResponse{
+			Data: toNotificationData(n),
+			Meta: api.NewSingleRecordMeta(),
+		}, http.StatusOK)
+	})
+}
+
+type notificationsResponse struct {
+	Data []notificationData `json:"data"`
+	Meta api.Meta           `json:"meta"`
+}
+
+type notificationResponse struct {
+	Data notificationData `json:"data"`
+	Meta api.Meta         `json:"meta"`
+}
+
+type notificationData struct {
+	ID                   string        `json:"id"`
+	ClientID             string        `json:"clientId"`
+	URL                  string        `json:"url"`
+	Status               Status        `json:"status"`
+	Attempts             []attemptData `json:"attempts"`
+	NextAttemptDateTime  string        `json:"nextAttemptDateTime,omitempty"`
+	CreationDateTime     string        `json:"creationDateTime"`
+	StatusUpdateDateTime string        `json:"statusUpdateDateTime"`
+}
+
+type attemptData struct {
+	InteractionID string `json:"interactionId"`
+	StatusCode    int    `json:"statusCode,omitempty"`
+	Error         string `json:"error,omitempty"`
+	DateTime      string `json:"dateTime"`
+}
+
+func toNotificationsResponse(ns page.Page[Notification]) notificationsResponse {
+	resp := notificationsResponse{
+		Data: []notificationData{},
+		Meta: api.NewPaginatedMeta(ns),
+	}
+	for _, n := range ns.Records {
+		resp.Data = append(resp.Data, toNotificationData(n))
+	}
+	return resp
+}
+
+func toNotificationData(n Notification) notificationData {
+	data := notificationData{
+		ID:                   n.ID,
+		ClientID:             n.ClientID,
+		URL:                  n.URL,
+		Status:               n.Status,
+		Attempts:             []attemptData{},
+		CreationDateTime:     n.CreationDateTime.String(),
+		StatusUpdateDateTime: n.StatusUpdateDateTime.String(),
+	}
+
+	if n.Status == StatusPending {
+		data.NextAttemptDateTime = n.NextAttemptDateTime.String()
+	}
+
+	for _, a := range n.Attempts {
+		data.Attempts = append(data.Attempts, attemptData{
+			InteractionID: a.InteractionID,
+			StatusCode:    a.StatusCode,
+			Error:         a.Error,
+			DateTime:      a.At.String(),
+		})
+	}
+
+	return data
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errNotificationNotFound) {
+		api.WriteError(w, api.NewError("NOT_FOUND", http.StatusNotFound, errNotificationNotFound.Error()))
+		return
+	}
+
+	api.WriteError(w, err)
+}
@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const dispatcherInterval = 5 * time.Second
+
+// Dispatcher periodically delivers the notifications in the outbox.
+type Dispatcher struct {
+	service Service
+}
+
+func NewDispatcher(service Service) Dispatcher {
+	return Dispatcher{
+		service: service,
+	}
+}
+
+// Run delivers the pending notifications until ctx is done.
+func (d Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatcherInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.service.deliverPending(ctx); err != nil {
+			slog.ErrorContext(ctx, "could not deliver webhook notifications", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
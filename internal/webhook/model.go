@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+const (
+	// maxAttempts is how many times a notification is sent before giving up.
+	maxAttempts = 6
+	// baseBackoff is how long to wait before the first retry. The wait doubles
+	// after every failed attempt.
+	baseBackoff = 10 * time.Second
+)
+
+// Notification is a message in the outbox of webhook notifications. It
+// informs the client the resource at URL changed.
+type Notification struct {
+	ID       string `bson:"_id"`
+	ClientID string `bson:"client_id"`
+	URL      string `bson:"url"`
+	Status   Status `bson:"status"`
+	// Attempts holds the result of every delivery attempt, so deliveries can
+	// be inspected.
+	Attempts            []Attempt      `bson:"attempts"`
+	NextAttemptDateTime timex.DateTime `bson:"next_attempt_at"`
+
+	CreationDateTime     timex.DateTime `bson:"created_at"`
+	StatusUpdateDateTime timex.DateTime `bson:"status_updated_at"`
+}
+
+// IsDue returns true if the notification is waiting to be sent and the time
+// for the next attempt has arrived.
+func (n Notification) IsDue() bool {
+	return n.Status == StatusPending && !timex.Now().Before(n.NextAttemptDateTime.Time)
+}
+
+// backoff returns how long to wait before retrying the notification.
+func (n Notification) backoff() time.Duration {
+	return baseBackoff << (len(n.Attempts) - 1)
+}
+
+type Status string
+
+const (
+	StatusPending   Status = "PENDING"
+	StatusDelivered Status = "DELIVERED"
+	// StatusFailed is the status of notifications that could not be delivered
+	// after the max number of attempts.
+	StatusFailed Status = "FAILED"
+)
+
+type Attempt struct {
+	// InteractionID is the value of the header "x-webhook-interaction-id".
+	InteractionID string         `bson:"interaction_id"`
+	StatusCode    int            `bson:"status_code,omitempty"`
+	Error         string         `bson:"error,omitempty"`
+	At            timex.DateTime `bson:"at"`
+}
+
+func notificationID() string {
+	return fmt.Sprintf("urn:mockbank:%s", uuid.NewString())
+}
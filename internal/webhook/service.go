@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/luikyv/go-oidc/pkg/goidc"
+	"github.com/luikyv/go-open-finance/internal/api"
+	"github.com/luikyv/go-open-finance/internal/page"
+	"github.com/luikyv/go-open-finance/internal/timex"
+)
+
+const headerInteractionID = "x-webhook-interaction-id"
+
+type Service struct {
+	storage       Storage
+	clientManager goidc.ClientManager
+	signer        api.JWTSigner
+	// httpClient authenticates with the transport certificate of MockBank
+	// when delivering notifications.
+	httpClient *http.Client
+}
+
+func NewService(st Storage, clientManager goidc.ClientManager, signer api.JWTSigner, httpClient *http.Client) Service {
+	return Service{
+		storage:       st,
+		clientManager: clientManager,
+		signer:        signer,
+		httpClient:    httpClient,
+	}
+}
+
+// Notify adds a notification to the outbox for each webhook URI registered by
+// the client. path identifies the resource which changed and is appended to
+// the webhook URIs.
+// Clients which did not register webhook URIs are not notified.
+func (s Service) Notify(ctx context.Context, clientID, path string) error {
+	client, err := s.clientManager.Client(ctx, clientID)
+	if err != nil {
+		slog.DebugContext(ctx, "could not load the client to notify, skipping webhook", slog.String("error", err.Error()))
+		return nil
+	}
+
+	now := timex.DateTimeNow()
+	for _, uri := range URIs(client.CustomAttributes) {
+		n := Notification{
+			ID:                   notificationID(),
+			ClientID:             clientID,
+			URL:                  strings.TrimRight(uri, "/") + path,
+			Status:               StatusPending,
+			NextAttemptDateTime:  now,
+			CreationDateTime:     now,
+			StatusUpdateDateTime: now,
+		}
+		slog.DebugContext(ctx, "adding webhook notification to the outbox", slog.String("notification_id", n.ID), slog.String("url", n.URL))
+		if err := s.storage.save(ctx, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deliverPending sends the notifications in the outbox whose time for the next
+// attempt has arrived.
+func (s Service) deliverPending(ctx context.Context) error {
+	ns, err := s.storage.notificationsByStatus(ctx, StatusPending)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range ns {
+		if !n.IsDue() {
+			continue
+		}
+
+		if err := s.deliver(ctx, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deliver sends the notification and schedules a retry with exponential
+// backoff if it fails.
+func (s Service) deliver(ctx context.Context, n Notification) error {
+	attempt := s.send(ctx, n)
+	n.Attempts = append(n.Attempts, attempt)
+
+	switch {
+	case attempt.Error == "":
+		slog.InfoContext(ctx, "webhook notification delivered", slog.String("notification_id", n.ID))
+		n.Status = StatusDelivered
+		n.StatusUpdateDateTime = timex.DateTimeNow()
+	case len(n.Attempts) >= maxAttempts:
+		slog.InfoContext(ctx, "webhook notification could not be delivered, giving up", slog.String("notification_id", n.ID))
+		n.Status = StatusFailed
+		n.StatusUpdateDateTime = timex.DateTimeNow()
+	default:
+		slog.DebugContext(ctx, "webhook notification failed, retrying later", slog.String("notification_id", n.ID),
+			slog.String("error", attempt.Error))
+		n.NextAttemptDateTime = timex.NewDateTime(timex.Now().Add(n.backoff()))
+	}
+
+	return s.storage.save(ctx, n)
+}
+
+// send posts a JWT signed by MockBank to the URL of the notification. Any 2xx
+// response is considered a successful delivery.
+func (s Service) send(ctx context.Context, n Notification) Attempt {
+	attempt := Attempt{
+		InteractionID: uuid.NewString(),
+		At:            timex.DateTimeNow(),
+	}
+
+	client, err := s.clientManager.Client(ctx, n.ClientID)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	jwt, err := s.signer.Sign(map[string]any{
+		"data": map[string]any{
+			"timestamp": n.CreationDateTime.String(),
+		},
+	}, api.ClientOrgID(client))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewBufferString(jwt))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", api.ContentTypeJWT)
+	req.Header.Set(headerInteractionID, attempt.InteractionID)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// notification returns the notification if it was sent to the client.
+// Notifications of other clients are treated as not found.
+func (s Service) notification(ctx context.Context, id, clientID string) (Notification, error) {
+	n, err := s.storage.notification(ctx, id)
+	if err != nil {
+		return Notification{}, err
+	}
+
+	if n.ClientID != clientID {
+		return Notification{}, errNotificationNotFound
+	}
+
+	return n, nil
+}
+
+// notifications returns the notifications sent to the client, the most recent
+// first.
+func (s Service) notifications(ctx context.Context, clientID string, pag page.Pagination) (page.Page[Notification], error) {
+	ns, err := s.storage.notificationsByClient(ctx, clientID)
+	if err != nil {
+		return page.Page[Notification]{}, err
+	}
+
+	slices.SortFunc(ns, func(a, b Notification) int {
+		return b.CreationDateTime.Compare(a.CreationDateTime.Time)
+	})
+	return page.Paginate(ns, pag), nil
+}
@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errNotificationNotFound = errors.New("webhook notification not found")
+
+type Storage struct {
+	collection *mongo.Collection
+}
+
+func NewStorage(db *mongo.Database) Storage {
+	return Storage{
+		collection: db.Collection("webhook_notifications"),
+	}
+}
+
+func (st Storage) save(ctx context.Context, n Notification) error {
+	shouldUpsert := true
+	filter := bson.D{{Key: "_id", Value: n.ID}}
+	if _, err := st.collection.ReplaceOne(ctx, filter, n, &options.ReplaceOptions{
+		Upsert: &shouldUpsert,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (st Storage) notification(ctx context.Context, id string) (Notification, error) {
+	filter := bson.D{{Key: "_id", Value: id}}
+	result := st.collection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return Notification{}, errNotificationNotFound
+		}
+		return Notification{}, result.Err()
+	}
+
+	var n Notification
+	if err := result.Decode(&n); err != nil {
+		return Notification{}, err
+	}
+
+	return n, nil
+}
+
+func (st Storage) notificationsByStatus(ctx context.Context, status Status) ([]Notification, error) {
+	return st.notifications(ctx, bson.D{{Key: "status", Value: status}})
+}
+
+func (st Storage) notificationsByClient(ctx context.Context, clientID string) ([]Notification, error) {
+	return st.notifications(ctx, bson.D{{Key: "client_id", Value: clientID}})
+}
+
+func (st Storage) notifications(ctx context.Context, filter bson.D) ([]Notification, error) {
+	cursor, err := st.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ns []Notification
+	if err := cursor.All(ctx, &ns); err != nil {
+		return nil, err
+	}
+
+	return ns, nil
+}
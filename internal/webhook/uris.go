@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// AttributeURIs is the client attribute holding the webhook URIs
+	// registered during DCR.
+	AttributeURIs               = "webhook_uris"
+	attributeSoftwareStatement  = "software_statement"
+	claimSoftwareAPIWebhookURIs = "software_api_webhook_uris"
+)
+
+var ErrInvalidURIs = errors.New("the webhook uris are invalid")
+
+// URIs returns the webhook URIs in the attributes of a client.
+func URIs(attributes map[string]any) []string {
+	var values []any
+	// The attribute is decoded as a slice of any when informed as JSON and as
+	// a BSON array when loaded from the database.
+	switch v := attributes[AttributeURIs].(type) {
+	case []string:
+		return v
+	case []any:
+		values = v
+	case primitive.A:
+		values = v
+	}
+
+	var uris []string
+	for _, v := range values {
+		if uri, ok := v.(string); ok {
+			uris = append(uris, uri)
+		}
+	}
+	return uris
+}
+
+// ValidateURIs checks the webhook URIs requested by a client during DCR are
+// HTTPS URLs allowed by its software statement. If the client was not
+// registered with a software statement, any HTTPS URL is allowed.
+func ValidateURIs(attributes map[string]any) error {
+	uris := URIs(attributes)
+	allowedURIs, hasSoftwareStatement := softwareStatementURIs(attributes)
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return ErrInvalidURIs
+		}
+
+		if hasSoftwareStatement && !slices.Contains(allowedURIs, uri) {
+			return ErrInvalidURIs
+		}
+	}
+	return nil
+}
+
+// softwareStatementURIs extracts the webhook URIs from the software statement
+// informed during DCR. The signature of the statement is not verified.
+func softwareStatementURIs(attributes map[string]any) ([]string, bool) {
+	ssa, ok := attributes[attributeSoftwareStatement].(string)
+	if !ok || ssa == "" {
+		return nil, false
+	}
+
+	parts := strings.Split(ssa, ".")
+	if len(parts) != 3 {
+		return nil, true
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, true
+	}
+
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, true
+	}
+
+	return URIs(map[string]any{AttributeURIs: claims[claimSoftwareAPIWebhookURIs]}), true
+}